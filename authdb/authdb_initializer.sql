@@ -11,6 +11,10 @@ BEGIN
     CALL sp_insert_permission('AddPost', 'Insert a post');
     CALL sp_insert_permission('UpdatePost', 'Edit a post');
     CALL sp_insert_permission('DeletePost', 'Delete a post');
+    CALL sp_insert_permission('ManageKeys', 'List and rotate token signing keys');
+    CALL sp_insert_permission('ManageRoles', 'Administrate roles and permissions');
+    CALL sp_insert_permission('ManageUsers', 'Administrate users and their roles');
+    CALL sp_insert_permission('ManageClients', 'Administrate OAuth clients');
 END ;;
 DELIMITER ;
 
@@ -23,7 +27,7 @@ DROP PROCEDURE IF EXISTS `temp_role_sp` ;
 
 
 DELIMITER ;;
-CREATE PROCEDURE `temp_role_sp`(IN name varchar(64), IN description varchar(512), IN permission varchar(32))
+CREATE PROCEDURE `temp_role_sp`(IN name varchar(64), IN description varchar(512), IN permission varchar(128))
 BEGIN
     DECLARE CONTINUE HANDLER FOR SQLSTATE '45000' Select 'Duplicate role permission';
 	CALL sp_insert_role(name, description);
@@ -33,23 +37,43 @@ BEGIN
 END ;;
 DELIMITER ;
 
-# Role Admin and its permissions
-CALL `temp_role_sp`('Admin', 'Administrative user', 'GetPost');
-CALL `temp_role_sp`('Admin', 'Administrative user', 'AddPost');
-CALL `temp_role_sp`('Admin', 'Administrative user', 'UpdatePost');
-CALL `temp_role_sp`('Admin', 'Administrative user', 'DeletePost');
+# Role Reader and its permissions
+CALL `temp_role_sp`('Reader', 'Only read access', 'GetPost');
 
-# Role Author and its permissions
-CALL `temp_role_sp`('Author', 'Only read, create and update access', 'GetPost');
+# Role Author and its permissions, the permissions of Reader are inherited
 CALL `temp_role_sp`('Author', 'Only read, create and update access', 'AddPost');
 CALL `temp_role_sp`('Author', 'Only read, create and update access', 'UpdatePost');
 
-# Role Reader and its permissions
-CALL `temp_role_sp`('Reader', 'Only read access', 'GetPost');
+# Role Admin and its permissions, the permissions of Author are inherited
+CALL `temp_role_sp`('Admin', 'Administrative user', 'DeletePost');
+CALL `temp_role_sp`('Admin', 'Administrative user', 'ManageKeys');
+CALL `temp_role_sp`('Admin', 'Administrative user', 'ManageRoles');
+CALL `temp_role_sp`('Admin', 'Administrative user', 'ManageUsers');
+CALL `temp_role_sp`('Admin', 'Administrative user', 'ManageClients');
 
 DROP PROCEDURE IF EXISTS `temp_role_sp` ;
 
+-- TEMPORARY SP to insert parent roles
+DROP PROCEDURE IF EXISTS `temp_role_parent_sp` ;
+
+DELIMITER ;;
+CREATE PROCEDURE `temp_role_parent_sp`(IN name varchar(64), IN parent varchar(64))
+BEGIN
+    DECLARE CONTINUE HANDLER FOR SQLSTATE '45000' Select 'Duplicate parent role';
+    SET @roleid = (SELECT R.id from Role AS R where R.name=name);
+    SET @parentid = (SELECT R.id from Role AS R where R.name=parent);
+    CALL sp_insert_role_parent(@roleid, @parentid);
+END ;;
+DELIMITER ;
+
+# Role hierarchy Admin -> Author -> Reader
+CALL `temp_role_parent_sp`('Author', 'Reader');
+CALL `temp_role_parent_sp`('Admin', 'Author');
+
+DROP PROCEDURE IF EXISTS `temp_role_parent_sp` ;
+
 -- TEMPORARY SP to insert users and its roles
+-- Seeded passwords are MD5 hashed, authsvc rehashes them on the first login
 DROP PROCEDURE IF EXISTS `temp_user_sp` ;
 
 DELIMITER ;;
@@ -71,3 +95,23 @@ CALL `temp_user_sp`('Author', 'User1', 'author.user1@testmail.com', '_GOllum#!',
 CALL `temp_user_sp`('Reader', 'User1', 'reader.user1@testmail.com', 'bUfo_MelanOst!ktus', 'reader');
 
 DROP PROCEDURE IF EXISTS `temp_user_sp` ;
+
+-- TEMPORARY SP to insert OAuth clients
+-- Seeded secrets are MD5 hashed, authsvc rehashes them on the first authentication
+DROP PROCEDURE IF EXISTS `temp_oauth_client_sp` ;
+
+DELIMITER ;;
+CREATE PROCEDURE `temp_oauth_client_sp`(IN clientid varchar(64), IN secret varchar(64), IN name varchar(128),
+    IN grants varchar(255), IN scopes text, IN redirecturis text, IN audience text)
+BEGIN
+    DECLARE CONTINUE HANDLER FOR SQLSTATE '45000' Select 'Duplicate client';
+    CALL sp_insert_oauth_client(clientid, IF(secret = '', NULL, MD5(secret)), name, grants, scopes, redirecturis, audience);
+END ;;
+DELIMITER ;
+
+# Confidential client of the API gateway, public clients of the posts web app and the posts CLI
+CALL `temp_oauth_client_sp`('api-gateway', 'g4teW@y_s3cret', 'API Gateway', 'client_credentials urn:ietf:params:oauth:grant-type:token-exchange', 'GetPost', '', 'posts-api');
+CALL `temp_oauth_client_sp`('posts-spa', '', 'Posts Web App', 'authorization_code refresh_token', 'openid profile email GetPost AddPost UpdatePost', 'http://localhost:3000/callback', 'posts-api');
+CALL `temp_oauth_client_sp`('posts-cli', '', 'Posts CLI', 'urn:ietf:params:oauth:grant-type:device_code refresh_token', 'openid profile GetPost AddPost UpdatePost', '', 'posts-api');
+
+DROP PROCEDURE IF EXISTS `temp_oauth_client_sp` ;
@@ -30,12 +30,16 @@ CREATE TABLE `User` (
   `firstname` varchar(64) NOT NULL,
   `lastname` varchar(64) NOT NULL,
   `login` varchar(64) NOT NULL,
-  `password` varchar(64) NOT NULL,
+  `password` varchar(255) NOT NULL,
   `created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   `verified` tinyint NOT NULL DEFAULT '0',
   `rowguid` varchar(36) NOT NULL DEFAULT (uuid()),
   `verification_code` varchar(64) NOT NULL,
+  `disabled` tinyint NOT NULL DEFAULT '0',
+  `totp_secret` varchar(255) DEFAULT NULL,
+  `totp_enabled` tinyint NOT NULL DEFAULT '0',
+  `totp_counter` bigint NOT NULL DEFAULT '0',
   PRIMARY KEY (`id`),
   UNIQUE KEY `login` (`login`),
   UNIQUE KEY `rowguid` (`rowguid`)
@@ -115,7 +119,7 @@ DROP TABLE IF EXISTS `Permission`;
 /*!50503 SET character_set_client = utf8mb4 */;
 CREATE TABLE `Permission` (
   `id` int NOT NULL AUTO_INCREMENT,
-  `name` varchar(32) NOT NULL,
+  `name` varchar(128) NOT NULL,
   `description` varchar(512) NOT NULL,
   PRIMARY KEY (`id`),
   UNIQUE KEY `name` (`name`)
@@ -132,6 +136,25 @@ CREATE TABLE `Permission` (
 /*!40111 SET SQL_NOTES=@OLD_SQL_NOTES */;
 
 
+--
+-- Table structure for table `RoleParent`
+--
+
+DROP TABLE IF EXISTS `RoleParent`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `RoleParent` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `roleid` int NOT NULL,
+  `parentid` int NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_roleid_parentid` (`roleid`,`parentid`),
+  KEY `fk_RoleParent_Parent` (`parentid`),
+  CONSTRAINT `fk_RoleParent_Parent` FOREIGN KEY (`parentid`) REFERENCES `Role` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `fk_RoleParent_Role` FOREIGN KEY (`roleid`) REFERENCES `Role` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `RolePermission`
 --
@@ -160,10 +183,96 @@ CREATE TABLE `RolePermission` (
 /*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION */;
 /*!40111 SET SQL_NOTES=@OLD_SQL_NOTES */;
 
+--
+-- Table structure for table `UserPasswordHistory`
+--
+
+DROP TABLE IF EXISTS `UserPasswordHistory`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `UserPasswordHistory` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `userid` int NOT NULL,
+  `password` varchar(255) NOT NULL,
+  `created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `fk_UserPasswordHistory_User` (`userid`),
+  CONSTRAINT `fk_UserPasswordHistory_User` FOREIGN KEY (`userid`) REFERENCES `User` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `OAuthClient`
+--
+
+DROP TABLE IF EXISTS `OAuthClient`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `OAuthClient` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `clientid` varchar(64) NOT NULL,
+  `secret` varchar(255) DEFAULT NULL,
+  `name` varchar(128) NOT NULL,
+  `grants` varchar(255) NOT NULL DEFAULT '',
+  `scopes` text NOT NULL,
+  `redirecturis` text NOT NULL,
+  `audience` text NOT NULL,
+  `created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `clientid` (`clientid`)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `UserRecoveryCode`
+--
+
+DROP TABLE IF EXISTS `UserRecoveryCode`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `UserRecoveryCode` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `userid` int NOT NULL,
+  `code` varchar(64) NOT NULL,
+  `created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_UserRecoveryCode_code` (`userid`,`code`),
+  CONSTRAINT `fk_UserRecoveryCode_User` FOREIGN KEY (`userid`) REFERENCES `User` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `UserCredential`
+--
+
+DROP TABLE IF EXISTS `UserCredential`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `UserCredential` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `userid` int NOT NULL,
+  `credential_id` varchar(1400) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+  `name` varchar(128) NOT NULL,
+  `public_key` varbinary(2048) NOT NULL,
+  `sign_count` int unsigned NOT NULL DEFAULT '0',
+  `aaguid` varchar(36) NOT NULL,
+  `attestation_format` varchar(32) NOT NULL,
+  `transports` varchar(255) NOT NULL DEFAULT '',
+  `created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_used` timestamp NULL DEFAULT NULL,
+  `disabled` tinyint NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_UserCredential_credential_id` (`credential_id`),
+  KEY `fk_UserCredential_User` (`userid`),
+  CONSTRAINT `fk_UserCredential_User` FOREIGN KEY (`userid`) REFERENCES `User` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Dumping routines for database 'AuthDB'
 --
-/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_permission` */;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_count_users` */;
 /*!50003 SET @saved_cs_client      = @@character_set_client */ ;
 /*!50003 SET @saved_cs_results     = @@character_set_results */ ;
 /*!50003 SET @saved_col_connection = @@collation_connection */ ;
@@ -172,17 +281,105 @@ CREATE TABLE `RolePermission` (
 /*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
 /*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
 /*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
-
-DROP PROCEDURE IF EXISTS `sp_insert_permission`;
-
 DELIMITER ;;
-CREATE PROCEDURE `sp_insert_permission`(IN name varchar(32), IN description varchar(512))
+CREATE PROCEDURE `sp_count_users`(IN search VARCHAR(64))
 BEGIN
-    IF NOT EXISTS(SELECT 1 from Permission AS P where P.name=name) THEN
-        INSERT INTO Permission(name, description) VALUES(name, description);
-        SELECT LAST_INSERT_ID() AS id;
+    SELECT COUNT(*) AS total
+    FROM User AS u
+    WHERE search = ''
+       OR u.login LIKE CONCAT('%', search, '%')
+       OR u.firstname LIKE CONCAT('%', search, '%')
+       OR u.lastname LIKE CONCAT('%', search, '%');
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_credential_disabled_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_credential_disabled_assignment`(IN credential_id VARCHAR(1400))
+BEGIN
+    UPDATE UserCredential AS C
+       SET C.disabled = 1
+       WHERE C.credential_id = credential_id;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_credential_sign_count_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_credential_sign_count_assignment`(IN credential_id VARCHAR(1400), IN sign_count INT UNSIGNED)
+BEGIN
+    UPDATE UserCredential AS C
+       SET C.sign_count = sign_count, C.last_used = CURRENT_TIMESTAMP
+       WHERE C.credential_id = credential_id
+         AND (C.sign_count < sign_count OR (C.sign_count = 0 AND sign_count = 0));
+    SELECT ROW_COUNT();
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_delete_credential` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_delete_credential`(IN login VARCHAR(64), IN credential_id VARCHAR(1400))
+BEGIN
+    DELETE C FROM UserCredential AS C
+    INNER JOIN User AS U ON U.id = C.userid
+    WHERE U.login = login AND C.credential_id = credential_id;
+    IF ROW_COUNT() = 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no credential is found';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_delete_oauth_client` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_delete_oauth_client`(IN clientid varchar(64))
+BEGIN
+    IF EXISTS(SELECT 1 FROM OAuthClient AS C WHERE C.clientid = clientid) THEN
+        DELETE FROM OAuthClient WHERE OAuthClient.clientid = clientid;
     ELSE
-        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'permission already exists';
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no client is found';
     END IF;
 END ;;
 DELIMITER ;
@@ -190,7 +387,7 @@ DELIMITER ;
 /*!50003 SET character_set_client  = @saved_cs_client */ ;
 /*!50003 SET character_set_results = @saved_cs_results */ ;
 /*!50003 SET collation_connection  = @saved_col_connection */ ;
-/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_role` */;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_delete_permission` */;
 /*!50003 SET @saved_cs_client      = @@character_set_client */ ;
 /*!50003 SET @saved_cs_results     = @@character_set_results */ ;
 /*!50003 SET @saved_col_connection = @@collation_connection */ ;
@@ -199,17 +396,13 @@ DELIMITER ;
 /*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
 /*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
 /*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
-DROP PROCEDURE IF EXISTS `sp_insert_role`;
-
 DELIMITER ;;
-CREATE PROCEDURE `sp_insert_role`(IN name varchar(64), IN description varchar(512))
+CREATE PROCEDURE `sp_delete_permission`(IN id int)
 BEGIN
-    IF NOT EXISTS(SELECT 1 FROM Role AS R WHERE R.name=name) THEN
-        INSERT INTO Role(name, description) VALUES(name, description);
-        SELECT LAST_INSERT_ID() as id;
-
+    IF EXISTS(SELECT 1 FROM Permission AS P WHERE P.id = id) THEN
+        DELETE FROM Permission WHERE Permission.id = id;
     ELSE
-        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'role already exists';
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no permission is found';
     END IF;
 END ;;
 DELIMITER ;
@@ -217,7 +410,7 @@ DELIMITER ;
 /*!50003 SET character_set_client  = @saved_cs_client */ ;
 /*!50003 SET character_set_results = @saved_cs_results */ ;
 /*!50003 SET collation_connection  = @saved_col_connection */ ;
-/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_role_permission` */;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_delete_role` */;
 /*!50003 SET @saved_cs_client      = @@character_set_client */ ;
 /*!50003 SET @saved_cs_results     = @@character_set_results */ ;
 /*!50003 SET @saved_col_connection = @@collation_connection */ ;
@@ -226,26 +419,21 @@ DELIMITER ;
 /*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
 /*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
 /*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
-
-DROP PROCEDURE IF EXISTS `sp_insert_role_permission`;
-
 DELIMITER ;;
-CREATE PROCEDURE `sp_insert_role_permission`(IN roleid int, IN permissionid int)
+CREATE PROCEDURE `sp_delete_role`(IN id int)
 BEGIN
-    IF NOT EXISTS(SELECT 1 FROM RolePermission AS RP WHERE RP.roleid=roleid AND RP.permissionid=permissionid) THEN
-        INSERT INTO RolePermission(roleid, permissionid) VALUES(roleid,permissionid);
-        SELECT LAST_INSERT_ID() as id;
+    IF EXISTS(SELECT 1 FROM Role AS R WHERE R.id = id) THEN
+        DELETE FROM Role WHERE Role.id = id;
     ELSE
-        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The group already has the permission';
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no role is found';
     END IF;
 END ;;
 DELIMITER ;
-
 /*!50003 SET sql_mode              = @saved_sql_mode */ ;
 /*!50003 SET character_set_client  = @saved_cs_client */ ;
 /*!50003 SET character_set_results = @saved_cs_results */ ;
 /*!50003 SET collation_connection  = @saved_col_connection */ ;
-/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_user` */;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_delete_role_parent` */;
 /*!50003 SET @saved_cs_client      = @@character_set_client */ ;
 /*!50003 SET @saved_cs_results     = @@character_set_results */ ;
 /*!50003 SET @saved_col_connection = @@collation_connection */ ;
@@ -254,20 +442,13 @@ DELIMITER ;
 /*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
 /*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
 /*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
-
-
-DROP PROCEDURE IF EXISTS `sp_insert_user`;
-
 DELIMITER ;;
-CREATE PROCEDURE `sp_insert_user`(IN firstname varchar(64), IN lastname varchar(64), IN login varchar(64),
-                                                IN password varchar(64), IN verification_code varchar(64))
+CREATE PROCEDURE `sp_delete_role_parent`(IN roleid int, IN parentid int)
 BEGIN
-    IF NOT EXISTS(SELECT 1 FROM User AS U WHERE U.login=login) THEN
-        INSERT INTO User(firstname, lastname, login, password, verification_code) VALUES(firstname, lastname, login, password, verification_code);
-        SELECT LAST_INSERT_ID() as id;
-
+    IF EXISTS(SELECT 1 FROM RoleParent AS RP WHERE RP.roleid = roleid AND RP.parentid = parentid) THEN
+        DELETE FROM RoleParent WHERE RoleParent.roleid = roleid AND RoleParent.parentid = parentid;
     ELSE
-        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'user already exists';
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The role does not have the parent role';
     END IF;
 END ;;
 DELIMITER ;
@@ -275,7 +456,7 @@ DELIMITER ;
 /*!50003 SET character_set_client  = @saved_cs_client */ ;
 /*!50003 SET character_set_results = @saved_cs_results */ ;
 /*!50003 SET collation_connection  = @saved_col_connection */ ;
-/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_user_role` */;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_delete_role_permission` */;
 /*!50003 SET @saved_cs_client      = @@character_set_client */ ;
 /*!50003 SET @saved_cs_results     = @@character_set_results */ ;
 /*!50003 SET @saved_col_connection = @@collation_connection */ ;
@@ -284,18 +465,13 @@ DELIMITER ;
 /*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
 /*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
 /*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
-
-DROP PROCEDURE IF EXISTS `sp_insert_user_role`;
-
 DELIMITER ;;
-CREATE PROCEDURE `sp_insert_user_role`(IN userid int, IN roleid int)
+CREATE PROCEDURE `sp_delete_role_permission`(IN roleid int, IN permissionid int)
 BEGIN
-    IF NOT EXISTS(SELECT 1 FROM UserRole AS UR WHERE UR.userid=userid AND UR.roleid=roleid) THEN
-        INSERT INTO UserRole(userid, roleid) VALUES(userid, roleid);
-        SELECT LAST_INSERT_ID() as id;
-
+    IF EXISTS(SELECT 1 FROM RolePermission AS RP WHERE RP.roleid = roleid AND RP.permissionid = permissionid) THEN
+        DELETE FROM RolePermission WHERE RolePermission.roleid = roleid AND RolePermission.permissionid = permissionid;
     ELSE
-        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The user has already the role';
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The group does not have the permission';
     END IF;
 END ;;
 DELIMITER ;
@@ -303,7 +479,7 @@ DELIMITER ;
 /*!50003 SET character_set_client  = @saved_cs_client */ ;
 /*!50003 SET character_set_results = @saved_cs_results */ ;
 /*!50003 SET collation_connection  = @saved_col_connection */ ;
-/*!50003 DROP PROCEDURE IF EXISTS `sp_read_user_permission` */;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_delete_user` */;
 /*!50003 SET @saved_cs_client      = @@character_set_client */ ;
 /*!50003 SET @saved_cs_results     = @@character_set_results */ ;
 /*!50003 SET @saved_col_connection = @@collation_connection */ ;
@@ -312,24 +488,21 @@ DELIMITER ;
 /*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
 /*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
 /*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
-DROP PROCEDURE IF EXISTS `sp_read_user_permission`;
-
 DELIMITER ;;
-CREATE PROCEDURE `sp_read_user_permission`(IN login varchar(64))
+CREATE PROCEDURE `sp_delete_user`(IN login VARCHAR(64))
 BEGIN
-    SELECT DISTINCT(PERM.id), PERM.name, PERM.description
-    FROM Permission PERM
-    INNER JOIN RolePermission RP ON PERM.id = RP.permissionid
-    INNER JOIN UserRole UR ON UR.roleid = RP.roleid
-    INNER JOIN User U ON U.id = UR.userid
-WHERE U.login = login;
+    IF EXISTS(SELECT 1 FROM User AS U where U.login = login) THEN
+        DELETE FROM User WHERE User.login = login;
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no user is found';
+    END IF;
 END ;;
 DELIMITER ;
 /*!50003 SET sql_mode              = @saved_sql_mode */ ;
 /*!50003 SET character_set_client  = @saved_cs_client */ ;
 /*!50003 SET character_set_results = @saved_cs_results */ ;
 /*!50003 SET collation_connection  = @saved_col_connection */ ;
-/*!50003 DROP PROCEDURE IF EXISTS `sp_read_user_role` */;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_delete_user_role` */;
 /*!50003 SET @saved_cs_client      = @@character_set_client */ ;
 /*!50003 SET @saved_cs_results     = @@character_set_results */ ;
 /*!50003 SET @saved_col_connection = @@collation_connection */ ;
@@ -338,23 +511,74 @@ DELIMITER ;
 /*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
 /*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
 /*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
-
-DROP PROCEDURE IF EXISTS`sp_read_user_role`;
-
 DELIMITER ;;
-CREATE PROCEDURE `sp_read_user_role`(IN login varchar(64))
+CREATE PROCEDURE `sp_delete_user_role`(IN userid int, IN roleid int)
 BEGIN
-    SELECT R.* FROM Role R
-    INNER JOIN UserRole UR ON UR.roleid = R.id
-    INNER JOIN User U ON U.id = UR.userid
-WHERE U.login = login;
+    IF EXISTS(SELECT 1 FROM UserRole AS UR WHERE UR.userid=userid AND UR.roleid=roleid) THEN
+        DELETE FROM UserRole WHERE UserRole.userid = userid AND UserRole.roleid = roleid;
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The user does not have the role';
+    END IF;
 END ;;
 DELIMITER ;
 /*!50003 SET sql_mode              = @saved_sql_mode */ ;
 /*!50003 SET character_set_client  = @saved_cs_client */ ;
 /*!50003 SET character_set_results = @saved_cs_results */ ;
 /*!50003 SET collation_connection  = @saved_col_connection */ ;
-/*!50003 DROP PROCEDURE IF EXISTS `sp_user_get_by_login` */;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_credential` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_insert_credential`(IN login VARCHAR(64), IN credential_id VARCHAR(1400), IN name VARCHAR(128), IN public_key VARBINARY(2048), IN sign_count INT UNSIGNED, IN aaguid VARCHAR(36), IN attestation_format VARCHAR(32), IN transports VARCHAR(255))
+BEGIN
+    DECLARE userid INT;
+    SET userid = (SELECT U.id FROM User AS U WHERE U.login = login);
+    IF userid IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no user is found';
+    END IF;
+    IF EXISTS(SELECT 1 FROM UserCredential AS C WHERE C.credential_id = credential_id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'credential already exists';
+    END IF;
+    INSERT INTO UserCredential(userid, credential_id, name, public_key, sign_count, aaguid, attestation_format, transports)
+    VALUES (userid, credential_id, name, public_key, sign_count, aaguid, attestation_format, transports);
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_oauth_client` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_insert_oauth_client`(IN clientid varchar(64), IN secret varchar(255), IN name varchar(128),
+    IN grants varchar(255), IN scopes text, IN redirecturis text, IN audience text)
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM OAuthClient AS C WHERE C.clientid = clientid) THEN
+        INSERT INTO OAuthClient(clientid, secret, name, grants, scopes, redirecturis, audience)
+            VALUES(clientid, secret, name, grants, scopes, redirecturis, audience);
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'client already exists';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_permission` */;
 /*!50003 SET @saved_cs_client      = @@character_set_client */ ;
 /*!50003 SET @saved_cs_results     = @@character_set_results */ ;
 /*!50003 SET @saved_col_connection = @@collation_connection */ ;
@@ -364,19 +588,931 @@ DELIMITER ;
 /*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
 /*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
 
-DROP PROCEDURE IF EXISTS `sp_user_get_by_login`;
+DROP PROCEDURE IF EXISTS `sp_insert_permission`;
 
 DELIMITER ;;
-CREATE PROCEDURE `sp_user_get_by_login`(IN login VARCHAR(64))
+CREATE PROCEDURE `sp_insert_permission`(IN name varchar(128), IN description varchar(512))
 BEGIN
-    SELECT
-        u.firstname,
-        u.lastname,
-        u.login,
-        u.password,
-        u.rowguid,
-        u.verified,
-        u.verification_code
+    IF NOT EXISTS(SELECT 1 from Permission AS P where P.name=name) THEN
+        INSERT INTO Permission(name, description) VALUES(name, description);
+        SELECT LAST_INSERT_ID() AS id;
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'permission already exists';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_role` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DROP PROCEDURE IF EXISTS `sp_insert_role`;
+
+DELIMITER ;;
+CREATE PROCEDURE `sp_insert_role`(IN name varchar(64), IN description varchar(512))
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM Role AS R WHERE R.name=name) THEN
+        INSERT INTO Role(name, description) VALUES(name, description);
+        SELECT LAST_INSERT_ID() as id;
+
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'role already exists';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_role_parent` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_insert_role_parent`(IN roleid int, IN parentid int)
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM Role AS R WHERE R.id=roleid) OR NOT EXISTS(SELECT 1 FROM Role AS R WHERE R.id=parentid) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no role is found';
+    ELSEIF EXISTS(SELECT 1 FROM RoleParent AS RP WHERE RP.roleid=roleid AND RP.parentid=parentid) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The role already has the parent role';
+    ELSEIF roleid = parentid OR roleid IN (
+        WITH RECURSIVE Ancestor(id) AS (
+            SELECT RP.parentid FROM RoleParent AS RP WHERE RP.roleid = parentid
+            UNION
+            SELECT RP.parentid FROM RoleParent AS RP INNER JOIN Ancestor AS A ON RP.roleid = A.id
+        )
+        SELECT A.id FROM Ancestor AS A
+    ) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The parent role would create a cycle';
+    ELSE
+        INSERT INTO RoleParent(roleid, parentid) VALUES(roleid, parentid);
+        SELECT LAST_INSERT_ID() as id;
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_role_permission` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+
+DROP PROCEDURE IF EXISTS `sp_insert_role_permission`;
+
+DELIMITER ;;
+CREATE PROCEDURE `sp_insert_role_permission`(IN roleid int, IN permissionid int)
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM Role AS R WHERE R.id=roleid) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no role is found';
+    ELSEIF NOT EXISTS(SELECT 1 FROM Permission AS P WHERE P.id=permissionid) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no permission is found';
+    ELSEIF NOT EXISTS(SELECT 1 FROM RolePermission AS RP WHERE RP.roleid=roleid AND RP.permissionid=permissionid) THEN
+        INSERT INTO RolePermission(roleid, permissionid) VALUES(roleid,permissionid);
+        SELECT LAST_INSERT_ID() as id;
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The group already has the permission';
+    END IF;
+END ;;
+DELIMITER ;
+
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_user` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+
+
+DROP PROCEDURE IF EXISTS `sp_insert_user`;
+
+DELIMITER ;;
+CREATE PROCEDURE `sp_insert_user`(IN firstname varchar(64), IN lastname varchar(64), IN login varchar(64),
+                                                IN password varchar(255), IN verification_code varchar(64))
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM User AS U WHERE U.login=login) THEN
+        INSERT INTO User(firstname, lastname, login, password, verification_code) VALUES(firstname, lastname, login, password, verification_code);
+        SELECT LAST_INSERT_ID() as id;
+
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'user already exists';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_insert_user_role` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+
+DROP PROCEDURE IF EXISTS `sp_insert_user_role`;
+
+DELIMITER ;;
+CREATE PROCEDURE `sp_insert_user_role`(IN userid int, IN roleid int)
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM User AS U WHERE U.id=userid) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no user is found';
+    ELSEIF NOT EXISTS(SELECT 1 FROM Role AS R WHERE R.id=roleid) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no role is found';
+    ELSEIF NOT EXISTS(SELECT 1 FROM UserRole AS UR WHERE UR.userid=userid AND UR.roleid=roleid) THEN
+        INSERT INTO UserRole(userid, roleid) VALUES(userid, roleid);
+        SELECT LAST_INSERT_ID() as id;
+
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The user has already the role';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_oauth_client_secret_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_oauth_client_secret_assignment`(IN clientid varchar(64), IN secret varchar(255))
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM OAuthClient AS C WHERE C.clientid = clientid) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no client is found';
+    ELSE
+        UPDATE OAuthClient AS C SET C.secret = secret WHERE C.clientid = clientid;
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_credential` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_credential`(IN credential_id VARCHAR(1400))
+BEGIN
+    SELECT C.credential_id, U.login, U.rowguid, C.name, C.public_key, C.sign_count, C.aaguid, C.attestation_format, C.transports, C.created, C.last_used, C.disabled
+    FROM UserCredential AS C
+    INNER JOIN User AS U ON U.id = C.userid
+    WHERE C.credential_id = credential_id;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_oauth_client` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_oauth_client`(IN clientid varchar(64))
+BEGIN
+    SELECT C.clientid, C.secret, C.name, C.grants, C.scopes, C.redirecturis, C.audience
+      FROM OAuthClient AS C
+      WHERE C.clientid = clientid;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_oauth_clients` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_oauth_clients`()
+BEGIN
+    SELECT C.clientid, C.secret, C.name, C.grants, C.scopes, C.redirecturis, C.audience
+      FROM OAuthClient AS C
+      ORDER BY C.name;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_permission` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_permission`(IN id int)
+BEGIN
+    SELECT P.id, P.name, P.description FROM Permission AS P WHERE P.id = id;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_permissions` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_permissions`()
+BEGIN
+    SELECT P.id, P.name, P.description FROM Permission AS P ORDER BY P.name;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_role` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_role`(IN id int)
+BEGIN
+    SELECT R.id, R.name, R.description FROM Role AS R WHERE R.id = id;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_role_parent` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_role_parent`(IN roleid int)
+BEGIN
+    SELECT R.id, R.name, R.description
+    FROM Role R
+    INNER JOIN RoleParent RP ON RP.parentid = R.id
+    WHERE RP.roleid = roleid;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_role_permission` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_role_permission`(IN roleid int)
+BEGIN
+    SELECT PERM.id, PERM.name, PERM.description
+    FROM Permission PERM
+    INNER JOIN RolePermission RP ON PERM.id = RP.permissionid
+    WHERE RP.roleid = roleid
+    ORDER BY PERM.name;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_roles` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_roles`()
+BEGIN
+    SELECT R.id, R.name, R.description FROM Role AS R ORDER BY R.name;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_user_credentials` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_user_credentials`(IN login VARCHAR(64))
+BEGIN
+    SELECT C.credential_id, U.login, U.rowguid, C.name, C.public_key, C.sign_count, C.aaguid, C.attestation_format, C.transports, C.created, C.last_used, C.disabled
+    FROM UserCredential AS C
+    INNER JOIN User AS U ON U.id = C.userid
+    WHERE U.login = login
+    ORDER BY C.id;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_user_effective_role` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_user_effective_role`(IN login varchar(64))
+BEGIN
+    WITH RECURSIVE EffectiveRole(id) AS (
+        SELECT UR.roleid FROM UserRole UR
+        INNER JOIN User U ON U.id = UR.userid
+        WHERE U.login = login
+        UNION
+        SELECT RP.parentid FROM RoleParent RP
+        INNER JOIN EffectiveRole ER ON RP.roleid = ER.id
+    )
+    SELECT R.id, R.name, R.description
+    FROM Role R
+    INNER JOIN EffectiveRole ER ON ER.id = R.id;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_user_permission` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DROP PROCEDURE IF EXISTS `sp_read_user_permission`;
+
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_user_permission`(IN login varchar(64))
+BEGIN
+    WITH RECURSIVE EffectiveRole(id) AS (
+        SELECT UR.roleid FROM UserRole UR
+        INNER JOIN User U ON U.id = UR.userid
+        WHERE U.login = login
+        UNION
+        SELECT RP.parentid FROM RoleParent RP
+        INNER JOIN EffectiveRole ER ON RP.roleid = ER.id
+    )
+    SELECT DISTINCT(PERM.id), PERM.name, PERM.description
+    FROM Permission PERM
+    INNER JOIN RolePermission RP ON PERM.id = RP.permissionid
+    INNER JOIN EffectiveRole ER ON ER.id = RP.roleid;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_user_role` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+
+DROP PROCEDURE IF EXISTS`sp_read_user_role`;
+
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_user_role`(IN login varchar(64))
+BEGIN
+    SELECT R.* FROM Role R
+    INNER JOIN UserRole UR ON UR.roleid = R.id
+    INNER JOIN User U ON U.id = UR.userid
+WHERE U.login = login;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_read_users` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_read_users`(IN search VARCHAR(64), IN skip INT, IN size INT)
+BEGIN
+    SELECT
+        u.id,
+        u.firstname,
+        u.lastname,
+        u.login,
+        u.password,
+        u.rowguid,
+        u.verified,
+        u.verification_code,
+        u.disabled,
+        u.totp_enabled
+    FROM User AS u
+    WHERE search = ''
+       OR u.login LIKE CONCAT('%', search, '%')
+       OR u.firstname LIKE CONCAT('%', search, '%')
+       OR u.lastname LIKE CONCAT('%', search, '%')
+    ORDER BY u.id
+    LIMIT skip, size;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_update_oauth_client` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_update_oauth_client`(IN clientid varchar(64), IN name varchar(128),
+    IN grants varchar(255), IN scopes text, IN redirecturis text, IN audience text)
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM OAuthClient AS C WHERE C.clientid = clientid) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no client is found';
+    ELSE
+        UPDATE OAuthClient AS C
+           SET C.name = name, C.grants = grants, C.scopes = scopes,
+               C.redirecturis = redirecturis, C.audience = audience
+           WHERE C.clientid = clientid;
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_update_permission` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_update_permission`(IN id int, IN name varchar(128), IN description varchar(512))
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM Permission AS P WHERE P.id = id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no permission is found';
+    ELSEIF EXISTS(SELECT 1 FROM Permission AS P WHERE P.name = name AND P.id <> id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'permission already exists';
+    ELSE
+        UPDATE Permission AS P
+           SET P.name = name, P.description = description
+           WHERE P.id = id;
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_update_role` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_update_role`(IN id int, IN name varchar(64), IN description varchar(512))
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM Role AS R WHERE R.id = id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no role is found';
+    ELSEIF EXISTS(SELECT 1 FROM Role AS R WHERE R.name = name AND R.id <> id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'role already exists';
+    ELSE
+        UPDATE Role AS R
+           SET R.name = name, R.description = description
+           WHERE R.id = id;
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_disabled_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_disabled_assignment`(IN login VARCHAR(64), IN isDisabled TINYINT(1))
+BEGIN
+    IF EXISTS(SELECT 1 FROM User AS U where U.login = login) THEN
+        UPDATE User AS U
+           SET U.disabled = isDisabled
+           WHERE U.login = login;
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no user is found';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_get_by_guid` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_get_by_guid`(IN rowguid VARCHAR(36))
+BEGIN
+    SELECT
+        u.id,
+        u.firstname,
+        u.lastname,
+        u.login,
+        u.password,
+        u.rowguid,
+        u.verified,
+        u.verification_code,
+        u.disabled,
+        u.totp_enabled
+    FROM User AS u
+    WHERE u.rowguid = rowguid;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_get_by_login` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+
+DROP PROCEDURE IF EXISTS `sp_user_get_by_login`;
+
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_get_by_login`(IN login VARCHAR(64))
+BEGIN
+    SELECT
+        u.id,
+        u.firstname,
+        u.lastname,
+        u.login,
+        u.password,
+        u.rowguid,
+        u.verified,
+        u.verification_code,
+        u.disabled,
+        u.totp_enabled
+    FROM User AS u
+    WHERE u.login = login;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_password_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+
+DROP PROCEDURE  IF EXISTS `sp_user_password_assignment`;
+
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_password_assignment`(IN login VARCHAR(64), IN password VARCHAR(255))
+BEGIN
+    IF EXISTS(SELECT 1 FROM User AS U where U.login = login) THEN
+        UPDATE User AS U
+           SET U.password = password
+           WHERE U.login = login;
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no user is found';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_password_change` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_password_change`(IN login VARCHAR(64), IN password VARCHAR(255), IN history_size INT)
+BEGIN
+    DECLARE userid INT;
+    SET userid = (SELECT U.id FROM User AS U WHERE U.login = login);
+    IF userid IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no user is found';
+    END IF;
+    INSERT INTO UserPasswordHistory(userid, password)
+        SELECT U.id, U.password FROM User AS U WHERE U.id = userid;
+    UPDATE User AS U
+       SET U.password = password
+       WHERE U.id = userid;
+    DELETE FROM UserPasswordHistory
+    WHERE UserPasswordHistory.userid = userid
+      AND UserPasswordHistory.id NOT IN (
+        SELECT H.id FROM (
+            SELECT UPH.id FROM UserPasswordHistory AS UPH
+            WHERE UPH.userid = userid
+            ORDER BY UPH.id DESC
+            LIMIT history_size
+        ) AS H
+    );
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_password_history_get` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_password_history_get`(IN login VARCHAR(64), IN size INT)
+BEGIN
+    SELECT H.password FROM UserPasswordHistory AS H
+    INNER JOIN User AS U ON U.id = H.userid
+    WHERE U.login = login
+    ORDER BY H.id DESC
+    LIMIT size;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_recovery_code_consumption` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_recovery_code_consumption`(IN login VARCHAR(64), IN code VARCHAR(64))
+BEGIN
+    DECLARE userid INT;
+    DECLARE consumed INT;
+    SET userid = (SELECT U.id FROM User AS U WHERE U.login = login);
+    DELETE FROM UserRecoveryCode
+    WHERE UserRecoveryCode.userid = userid AND UserRecoveryCode.code = code;
+    SET consumed = ROW_COUNT();
+    SELECT consumed, COUNT(*) FROM UserRecoveryCode AS R WHERE R.userid = userid;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_recovery_codes_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_recovery_codes_assignment`(IN login VARCHAR(64), IN codes JSON)
+BEGIN
+    DECLARE userid INT;
+    SET userid = (SELECT U.id FROM User AS U WHERE U.login = login);
+    IF userid IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no user is found';
+    END IF;
+    DELETE FROM UserRecoveryCode WHERE UserRecoveryCode.userid = userid;
+    INSERT INTO UserRecoveryCode(userid, code)
+        SELECT userid, C.code FROM JSON_TABLE(codes, '$[*]' COLUMNS (code VARCHAR(64) PATH '$')) AS C;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_totp_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_totp_assignment`(IN login VARCHAR(64), IN secret VARCHAR(255))
+BEGIN
+    IF EXISTS(SELECT 1 FROM User AS U where U.login = login) THEN
+        UPDATE User AS U
+           SET U.totp_secret = secret,
+               U.totp_enabled = 0,
+               U.totp_counter = 0
+           WHERE U.login = login;
+    ELSE
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'no user is found';
+    END IF;
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_totp_counter_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_totp_counter_assignment`(IN login VARCHAR(64), IN counter BIGINT)
+BEGIN
+    UPDATE User AS U
+       SET U.totp_counter = counter
+       WHERE U.login = login AND U.totp_enabled = 1 AND U.totp_counter < counter;
+    SELECT ROW_COUNT();
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_totp_enabled_assignment` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_totp_enabled_assignment`(IN login VARCHAR(64), IN counter BIGINT)
+BEGIN
+    UPDATE User AS U
+       SET U.totp_enabled = 1,
+           U.totp_counter = counter
+       WHERE U.login = login AND U.totp_secret IS NOT NULL AND U.totp_counter < counter;
+    SELECT ROW_COUNT();
+END ;;
+DELIMITER ;
+/*!50003 SET sql_mode              = @saved_sql_mode */ ;
+/*!50003 SET character_set_client  = @saved_cs_client */ ;
+/*!50003 SET character_set_results = @saved_cs_results */ ;
+/*!50003 SET collation_connection  = @saved_col_connection */ ;
+/*!50003 DROP PROCEDURE IF EXISTS `sp_user_totp_get` */;
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 SET @saved_cs_results     = @@character_set_results */ ;
+/*!50003 SET @saved_col_connection = @@collation_connection */ ;
+/*!50003 SET character_set_client  = utf8mb4 */ ;
+/*!50003 SET character_set_results = utf8mb4 */ ;
+/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */ ;
+/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;
+/*!50003 SET sql_mode              = 'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION' */ ;
+DELIMITER ;;
+CREATE PROCEDURE `sp_user_totp_get`(IN login VARCHAR(64))
+BEGIN
+    SELECT
+        u.totp_secret,
+        u.totp_enabled,
+        u.totp_counter
     FROM User AS u
     WHERE u.login = login;
 END ;;
@@ -8,19 +8,27 @@ import (
 	"github.com/parthoshuvo/authsvc/db"
 	"github.com/parthoshuvo/authsvc/email"
 	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/mfa"
+	"github.com/parthoshuvo/authsvc/passwd"
 	"github.com/parthoshuvo/authsvc/render"
 	"github.com/parthoshuvo/authsvc/resource"
 	"github.com/parthoshuvo/authsvc/route"
+	clntTable "github.com/parthoshuvo/authsvc/table/client"
+	credTable "github.com/parthoshuvo/authsvc/table/credential"
 	permTable "github.com/parthoshuvo/authsvc/table/permission"
 	roleTable "github.com/parthoshuvo/authsvc/table/role"
 	usrTable "github.com/parthoshuvo/authsvc/table/user"
 	toknSvc "github.com/parthoshuvo/authsvc/token"
 	"github.com/parthoshuvo/authsvc/uc/adm"
+	"github.com/parthoshuvo/authsvc/uc/client"
+	ucmfa "github.com/parthoshuvo/authsvc/uc/mfa"
 	"github.com/parthoshuvo/authsvc/uc/permission"
 	"github.com/parthoshuvo/authsvc/uc/role"
 	"github.com/parthoshuvo/authsvc/uc/token"
 	"github.com/parthoshuvo/authsvc/uc/user"
+	ucwebauthn "github.com/parthoshuvo/authsvc/uc/webauthn"
 	"github.com/parthoshuvo/authsvc/validator"
+	"github.com/parthoshuvo/authsvc/webauthn"
 )
 
 func main() {
@@ -37,24 +45,124 @@ func main() {
 	validate := validator.New()
 	rndr := render.NewJSONRenderer(config.Indent())
 
-	rb := route.NewRouteBuilder(config.AllowCORS(), new(resource.DefaultProtector), config.AppName(), config.IsLogDebug())
-	rb.Add("Home", http.MethodGet, "/", resource.HomeHandler(config.HomePage()))
-
 	usrHndlr := user.NewHandler(usrTable.NewTable(audb))
 	toknHndlr := token.NewHandler(toknSvc.NewService(config.JWTDef(), tdb))
+	notifier := resource.NewSecurityNotifier(emailClient, config.SecurityEventDef().MailUser)
+	toknHndlr.OnSecurityEvent(notifier.Notify)
 	roleHndlr := role.NewHandler(roleTable.NewTable(audb))
 	permHndlr := permission.NewHandler(permTable.NewTable(audb))
+	admHndlr := adm.NewHandler(usrHndlr, roleHndlr, permHndlr)
+	toknHndlr.UseGrants(admHndlr.UserGrants)
+
+	protector := resource.NewPermissionProtector(toknHndlr, permHndlr, config.ActionPermissions())
+	rb := route.NewRouteBuilder(config.AllowCORS(), config.Server().TrustedProxies, protector, config.AppName(), config.IsLogDebug())
+	rb.Add("Home", http.MethodGet, "/", resource.HomeHandler(config.HomePage()))
+	pwdHasher := passwd.NewPasswordHasher(config.PasswordHashDef())
+	clntHndlr := client.NewHandler(clntTable.NewTable(audb), pwdHasher)
+	mfaHndlr := ucmfa.NewHandler(usrTable.NewTable(audb), mfa.NewTOTP(config.TOTPDef()))
+	mfaHndlr.OnRecoveryCodeUsed(notifier.NotifyRecoveryCodeUsed)
+	webauthnHndlr := ucwebauthn.NewHandler(credTable.NewTable(audb), webauthn.NewRelyingParty(config.WebAuthnDef()), toknHndlr)
 
 	aurb := rb.SubrouteBuilder("/auth")
-	aurs := resource.NewAuthResource(usrHndlr, toknHndlr, rndr, validate, emailClient)
+	aurs := resource.NewAuthResource(usrHndlr, toknHndlr, mfaHndlr, rndr, validate, emailClient, pwdHasher)
 	aurb.Add("LoginUser", http.MethodPost, "/login", aurs.UserLogin())
+	aurb.Add("LoginUserMFA", http.MethodPost, "/login/mfa", aurs.UserMFALogin())
+	aurb.Add("LogoutUser", http.MethodPost, "/logout", aurs.UserLogout())
 	aurb.Add("RegisterUser", http.MethodPost, "/register", aurs.UserRegistration())
 	aurb.Add("VerifyEmail", http.MethodGet, "/email_verification", aurs.EmailVerifier())
+	azrs := resource.NewAuthorizationResource(toknHndlr, permHndlr, rndr)
+	aurb.Add("Authorize", http.MethodPost, "/authorize", azrs.Authorizer())
+
+	oarb := rb.SubrouteBuilder("/oauth")
+	oars := resource.NewOAuthResource(usrHndlr, admHndlr, toknHndlr, clntHndlr, mfaHndlr, pwdHasher, rndr)
+	oarb.Add("OAuthAuthorize", http.MethodGet, "/authorize", oars.Authorizer())
+	oarb.Add("OAuthGrantAuthorization", http.MethodPost, "/authorize", oars.AuthorizationGranter())
+	oarb.Add("OAuthToken", http.MethodPost, "/token", oars.TokenIssuer())
+	oarb.Add("OAuthDeviceAuthorization", http.MethodPost, "/device_authorization", oars.DeviceAuthorizer())
+	oarb.Add("OAuthDevice", http.MethodGet, "/device", oars.DeviceVerifier())
+	oarb.Add("OAuthGrantDevice", http.MethodPost, "/device", oars.DeviceGranter())
+	oarb.Add("OAuthUserInfo", http.MethodGet, "/userinfo", oars.UserInfoProvider())
+	oarb.Add("OAuthPostUserInfo", http.MethodPost, "/userinfo", oars.UserInfoProvider())
+
+	pwrb := aurb.SubrouteBuilder("/password")
+	pwrs := resource.NewPasswordResource(usrHndlr, toknHndlr, validate, pwdHasher, emailClient, config.PasswordResetLink(), config.PasswordHistory())
+	pwrb.Add("ForgotPassword", http.MethodPost, "/forgot", pwrs.PasswordForgotten())
+	pwrb.Add("ResetPassword", http.MethodPost, "/reset", pwrs.PasswordReset())
+	pwrb.Add("ChangePassword", http.MethodPost, "/change", pwrs.PasswordChanger())
+
+	srs := resource.NewSessionResource(toknHndlr, rndr)
+	aurb.Add("ListSessions", http.MethodGet, "/sessions", srs.SessionLister())
+	aurb.Add("RevokeOtherSessions", http.MethodDelete, "/sessions", srs.OtherSessionsRevoker())
+	aurb.Add("RevokeSession", http.MethodDelete, "/sessions/{id}", srs.SessionRevoker())
+
+	mfrb := aurb.SubrouteBuilder("/mfa")
+	mfrs := resource.NewMFAResource(usrHndlr, toknHndlr, mfaHndlr, pwdHasher, rndr)
+	mfrb.Add("EnrollTOTP", http.MethodPost, "/totp", mfrs.TOTPEnroller())
+	mfrb.Add("GetTOTPQRCode", http.MethodGet, "/totp/qr", mfrs.TOTPQRCodeProvider())
+	mfrb.Add("ConfirmTOTP", http.MethodPost, "/totp/confirm", mfrs.TOTPConfirmer())
+	mfrb.Add("DisableTOTP", http.MethodDelete, "/totp", mfrs.TOTPDisabler())
+	mfrb.Add("RegenerateRecoveryCodes", http.MethodPost, "/recovery_codes", mfrs.RecoveryCodesRegenerator())
+
+	warb := aurb.SubrouteBuilder("/webauthn")
+	wars := resource.NewWebAuthnResource(usrHndlr, toknHndlr, webauthnHndlr, rndr)
+	warb.Add("BeginWebAuthnRegistration", http.MethodPost, "/register/begin", wars.RegistrationBeginner())
+	warb.Add("FinishWebAuthnRegistration", http.MethodPost, "/register/finish", wars.RegistrationFinisher())
+	warb.Add("ListWebAuthnCredentials", http.MethodGet, "/credentials", wars.CredentialLister())
+	warb.Add("DeleteWebAuthnCredential", http.MethodDelete, "/credentials/{id}", wars.CredentialDeleter())
+	warb.Add("BeginWebAuthnLogin", http.MethodPost, "/login/begin", wars.LoginBeginner())
+	warb.Add("FinishWebAuthnLogin", http.MethodPost, "/login/finish", wars.LoginFinisher())
 
 	trb := aurb.SubrouteBuilder("/token")
-	trs := resource.NewTokenResource(toknHndlr, adm.NewHandler(usrHndlr, roleHndlr, permHndlr), usrHndlr, rndr)
+	trs := resource.NewTokenResource(toknHndlr, admHndlr, usrHndlr, clntHndlr, rndr)
 	trb.Add("VerifyAccessToken", http.MethodPost, "/verify", trs.AccessTokenVerifier())
 	trb.Add("GenerateTokenPair", http.MethodPost, "/refresh", trs.TokenPairGenerator())
+	trb.Add("IntrospectToken", http.MethodPost, "/introspect", trs.TokenIntrospector())
+	trb.Add("RevokeToken", http.MethodPost, "/revoke", trs.TokenRevoker())
+
+	wkrs := resource.NewWellKnownResource(toknHndlr, rndr)
+	rb.Add("JWKS", http.MethodGet, "/.well-known/jwks.json", wkrs.JWKSPublisher())
+	rb.Add("OpenIDConfiguration", http.MethodGet, "/.well-known/openid-configuration", wkrs.OpenIDConfigurationPublisher())
+
+	adrb := rb.SubrouteBuilder("/admin")
+	adrs := resource.NewAdminResource(toknHndlr, rndr)
+	adrb.AddSafe("ListSigningKeys", http.MethodGet, "/keys", adrs.SigningKeyLister())
+	adrb.AddSafe("RotateSigningKeys", http.MethodPost, "/keys/rotate", adrs.SigningKeyRotator())
+
+	rlrs := resource.NewRoleResource(roleHndlr, rndr, validate)
+	adrb.AddSafe("ListRoles", http.MethodGet, "/roles", rlrs.RoleLister())
+	adrb.AddSafe("CreateRole", http.MethodPost, "/roles", rlrs.RoleCreator())
+	adrb.AddSafe("GetRole", http.MethodGet, "/roles/{id}", rlrs.RoleGetter())
+	adrb.AddSafe("UpdateRole", http.MethodPut, "/roles/{id}", rlrs.RoleUpdater())
+	adrb.AddSafe("DeleteRole", http.MethodDelete, "/roles/{id}", rlrs.RoleDeleter())
+	adrb.AddSafe("AttachRolePermission", http.MethodPut, "/roles/{id}/permissions/{permission_id}", rlrs.PermissionAttacher())
+	adrb.AddSafe("DetachRolePermission", http.MethodDelete, "/roles/{id}/permissions/{permission_id}", rlrs.PermissionDetacher())
+	adrb.AddSafe("AddRoleParent", http.MethodPut, "/roles/{id}/parents/{parent_id}", rlrs.ParentAdder())
+	adrb.AddSafe("RemoveRoleParent", http.MethodDelete, "/roles/{id}/parents/{parent_id}", rlrs.ParentRemover())
+
+	pmrs := resource.NewPermissionResource(permHndlr, rndr, validate)
+	adrb.AddSafe("ListPermissions", http.MethodGet, "/permissions", pmrs.PermissionLister())
+	adrb.AddSafe("CreatePermission", http.MethodPost, "/permissions", pmrs.PermissionCreator())
+	adrb.AddSafe("GetPermission", http.MethodGet, "/permissions/{id}", pmrs.PermissionGetter())
+	adrb.AddSafe("UpdatePermission", http.MethodPut, "/permissions/{id}", pmrs.PermissionUpdater())
+	adrb.AddSafe("DeletePermission", http.MethodDelete, "/permissions/{id}", pmrs.PermissionDeleter())
+
+	usrs := resource.NewUserResource(admHndlr, toknHndlr, rndr)
+	adrb.AddSafe("ListUsers", http.MethodGet, "/users", usrs.UserLister())
+	adrb.AddSafe("GetUser", http.MethodGet, "/users/{id}", usrs.UserGetter())
+	adrb.AddSafe("DeleteUser", http.MethodDelete, "/users/{id}", usrs.UserDeleter())
+	adrb.AddSafe("VerifyUser", http.MethodPost, "/users/{id}/verify", usrs.UserVerifier())
+	adrb.AddSafe("DisableUser", http.MethodPost, "/users/{id}/disable", usrs.UserDisabler())
+	adrb.AddSafe("EnableUser", http.MethodPost, "/users/{id}/enable", usrs.UserEnabler())
+	adrb.AddSafe("AssignUserRole", http.MethodPut, "/users/{id}/roles/{role_id}", usrs.RoleAssigner())
+	adrb.AddSafe("RemoveUserRole", http.MethodDelete, "/users/{id}/roles/{role_id}", usrs.RoleRemover())
+
+	clrs := resource.NewClientResource(clntHndlr, rndr, validate)
+	adrb.AddSafe("ListClients", http.MethodGet, "/clients", clrs.ClientLister())
+	adrb.AddSafe("CreateClient", http.MethodPost, "/clients", clrs.ClientCreator())
+	adrb.AddSafe("GetClient", http.MethodGet, "/clients/{id}", clrs.ClientGetter())
+	adrb.AddSafe("UpdateClient", http.MethodPut, "/clients/{id}", clrs.ClientUpdater())
+	adrb.AddSafe("DeleteClient", http.MethodDelete, "/clients/{id}", clrs.ClientDeleter())
+	adrb.AddSafe("RotateClientSecret", http.MethodPost, "/clients/{id}/secret", clrs.ClientSecretRotator())
 
 	log.Infof("Starting %s on %s\n", config.AppName(), config.Server())
 	log.Fatal(http.ListenAndServe(config.Server().String(), rb.Router()))
@@ -0,0 +1,133 @@
+// Package authz matches structured permissions of the form
+// resource:action[:scope], e.g. post:edit:project-42.
+//
+// A segment of a granted permission equal to the wildcard * matches any value
+// of the segment, e.g. post:* grants all actions on posts in all scopes. A
+// granted permission without scope grants the action in all scopes. Flat
+// permission names without a colon, e.g. GetPost, only match themselves.
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Wildcard matches any value of a permission segment.
+	Wildcard = "*"
+
+	separator   = ":"
+	maxSegments = 3
+)
+
+// Permission is a parsed permission.
+type Permission struct {
+	segments []string
+}
+
+// Parse parses a permission of the form resource:action[:scope]. Segments
+// must not be empty nor contain white space.
+func Parse(s string) (*Permission, error) {
+	segments := strings.Split(s, separator)
+	if len(segments) > maxSegments {
+		return nil, fmt.Errorf("permission %q has more than %d segments", s, maxSegments)
+	}
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("permission %q has an empty segment", s)
+		}
+		if strings.ContainsAny(segment, " \t\r\n") {
+			return nil, fmt.Errorf("permission %q contains white space", s)
+		}
+	}
+	return &Permission{segments}, nil
+}
+
+// New creates the permission to perform an action on a resource, the scope
+// is optional.
+func New(resource, action, scope string) (*Permission, error) {
+	s := resource + separator + action
+	if scope != "" {
+		s += separator + scope
+	}
+	return Parse(s)
+}
+
+// Resource provides the resource segment.
+func (p *Permission) Resource() string {
+	return p.segment(0)
+}
+
+// Action provides the action segment, empty for flat permissions.
+func (p *Permission) Action() string {
+	return p.segment(1)
+}
+
+// Scope provides the scope segment, empty if the permission isn't scoped.
+func (p *Permission) Scope() string {
+	return p.segment(2)
+}
+
+func (p *Permission) segment(i int) string {
+	if i < len(p.segments) {
+		return p.segments[i]
+	}
+	return ""
+}
+
+func (p *Permission) String() string {
+	return strings.Join(p.segments, separator)
+}
+
+// Implies checks whether holding the permission grants the required one.
+func (p *Permission) Implies(required *Permission) bool {
+	if len(p.segments) == 1 || len(required.segments) == 1 {
+		return len(p.segments) == len(required.segments) && p.segments[0] == required.segments[0]
+	}
+	if len(p.segments) > len(required.segments) && p.segments[len(p.segments)-1] != Wildcard {
+		return false
+	}
+	for i, segment := range p.segments {
+		if i < len(required.segments) && segment != Wildcard && segment != required.segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Set is a set of granted permissions.
+type Set []*Permission
+
+// NewSet parses granted permissions, names which can't be parsed grant
+// nothing and are skipped.
+func NewSet(names []string) Set {
+	set := make(Set, 0, len(names))
+	for _, name := range names {
+		if perm, err := Parse(name); err == nil {
+			set = append(set, perm)
+		}
+	}
+	return set
+}
+
+// Implies checks whether any permission of the set grants the required one.
+func (s Set) Implies(required *Permission) bool {
+	for _, perm := range s {
+		if perm.Implies(required) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImpliesAll checks whether the set grants all required permissions. Required
+// permissions which can't be parsed are never granted.
+func (s Set) ImpliesAll(required []string) bool {
+	for _, name := range required {
+		perm, err := Parse(name)
+		if err != nil || !s.Implies(perm) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,17 +1,533 @@
 package cache
 
 import (
+	"encoding/json"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
 	"github.com/parthoshuvo/authsvc/token"
 )
 
-func (td *TokenDB) SetRefreshToken(authToken *token.AuthToken) error {
-	return td.rdb.Set(td.ctx, authToken.UserID(), authToken.UUID(), authToken.Expires()).Err()
+const (
+	sessionPrefix           = "session:"
+	userSessionsPrefix      = "sessions:"
+	deniedAccessTokenPrefix = "denied:"
+	passwordResetPrefix     = "reset:"
+	userResetsPrefix        = "resets:"
+	authorizationCodePrefix = "code:"
+	deviceGrantPrefix       = "device:"
+	userCodePrefix          = "usercode:"
+	devicePollPrefix        = "devicepoll:"
+	userCodeFailuresPrefix  = "usercodefail:"
+	mfaChallengePrefix      = "mfa:"
+	mfaFailuresPrefix       = "mfafail:"
+	webAuthnChallengePrefix = "webauthn:"
+)
+
+// sessionRecord is the stored form of a session.
+type sessionRecord struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"user_id"`
+	TokenID   string   `json:"token_id"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Audience  []string `json:"audience,omitempty"`
+	UserAgent string   `json:"user_agent"`
+	IP        string   `json:"ip"`
+	Created   int64    `json:"created"`
+	LastUsed  int64    `json:"last_used"`
+}
+
+// authorizationCodeRecord is the stored form of the grant of an authorization code.
+type authorizationCodeRecord struct {
+	ClientID      string   `json:"client_id"`
+	RedirectURI   string   `json:"redirect_uri"`
+	Scope         string   `json:"scope,omitempty"`
+	Audience      []string `json:"audience,omitempty"`
+	CodeChallenge string   `json:"code_challenge"`
+	Nonce         string   `json:"nonce,omitempty"`
+	Login         string   `json:"login"`
+	AuthTime      int64    `json:"auth_time"`
+}
+
+// deviceGrantRecord is the stored form of a device grant.
+type deviceGrantRecord struct {
+	ClientID string   `json:"client_id"`
+	Scope    string   `json:"scope,omitempty"`
+	Audience []string `json:"audience,omitempty"`
+	UserCode string   `json:"user_code"`
+	Status   string   `json:"status"`
+	Login    string   `json:"login,omitempty"`
+	AuthTime int64    `json:"auth_time,omitempty"`
+	Expires  int64    `json:"expires"`
+}
+
+// SetSession stores a session until exp and indexes it by its user. The index
+// lives as long as the most recently stored session of the user.
+func (td *TokenDB) SetSession(sess *token.Session, exp time.Duration) error {
+	data, err := json.Marshal(toSessionRecord(sess))
+	if err != nil {
+		return err
+	}
+	userKey := userSessionsPrefix + sess.UserID
+	_, err = td.rdb.TxPipelined(td.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(td.ctx, sessionPrefix+sess.ID, data, exp)
+		pipe.SAdd(td.ctx, userKey, sess.ID)
+		pipe.Expire(td.ctx, userKey, exp)
+		return nil
+	})
+	return err
+}
+
+// rotateSessionScript replaces a session only if it still exists and its
+// current refresh token is the rotated one, the session is indexed by its
+// user like SetSession does.
+var rotateSessionScript = redis.NewScript(`
+local data = redis.call("GET", KEYS[1])
+if not data or cjson.decode(data)["token_id"] ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+redis.call("SADD", KEYS[2], ARGV[4])
+redis.call("PEXPIRE", KEYS[2], ARGV[3])
+return 1
+`)
+
+// RotateSession stores a session until exp like SetSession, provided the
+// stored session still exists with the refresh token rotatedTokenID. It
+// reports whether the session is stored.
+func (td *TokenDB) RotateSession(sess *token.Session, rotatedTokenID string, exp time.Duration) (bool, error) {
+	data, err := json.Marshal(toSessionRecord(sess))
+	if err != nil {
+		return false, err
+	}
+	keys := []string{sessionPrefix + sess.ID, userSessionsPrefix + sess.UserID}
+	rotated, err := rotateSessionScript.Run(td.ctx, td.rdb, keys, rotatedTokenID, data, exp.Milliseconds(), sess.ID).Int()
+	return rotated == 1, err
+}
+
+// GetSession fetches a session by session ID, nil if it doesn't exist.
+func (td *TokenDB) GetSession(sessionID string) (*token.Session, error) {
+	data, err := td.rdb.Get(td.ctx, sessionPrefix+sessionID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toSession(data)
+}
+
+// GetUserSessions fetches the sessions of a user. Expired sessions are
+// removed from the user's sessions.
+func (td *TokenDB) GetUserSessions(userID string) ([]*token.Session, error) {
+	userKey := userSessionsPrefix + userID
+	ids, err := td.rdb.SMembers(td.ctx, userKey).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, sessionPrefix+id)
+	}
+	values, err := td.rdb.MGet(td.ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*token.Session, 0, len(ids))
+	expired := make([]interface{}, 0)
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		sess, err := toSession([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	if len(expired) > 0 {
+		if err := td.rdb.SRem(td.ctx, userKey, expired...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession deletes a session and removes it from the user's sessions.
+func (td *TokenDB) RevokeSession(sess *token.Session) error {
+	_, err := td.rdb.TxPipelined(td.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(td.ctx, sessionPrefix+sess.ID)
+		pipe.SRem(td.ctx, userSessionsPrefix+sess.UserID, sess.ID)
+		return nil
+	})
+	return err
+}
+
+func toSessionRecord(sess *token.Session) *sessionRecord {
+	return &sessionRecord{
+		ID:        sess.ID,
+		UserID:    sess.UserID,
+		TokenID:   sess.TokenID,
+		ClientID:  sess.ClientID,
+		Scope:     sess.Scope,
+		Audience:  sess.Audience,
+		UserAgent: sess.UserAgent,
+		IP:        sess.IP,
+		Created:   sess.Created,
+		LastUsed:  sess.LastUsed,
+	}
+}
+
+func toSession(data []byte) (*token.Session, error) {
+	var rec sessionRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return &token.Session{
+		ID:        rec.ID,
+		UserID:    rec.UserID,
+		TokenID:   rec.TokenID,
+		ClientID:  rec.ClientID,
+		Scope:     rec.Scope,
+		Audience:  rec.Audience,
+		UserAgent: rec.UserAgent,
+		IP:        rec.IP,
+		Created:   rec.Created,
+		LastUsed:  rec.LastUsed,
+	}, nil
+}
+
+// DenyAccessToken adds the access token ID to the denylist until the token expires.
+func (td *TokenDB) DenyAccessToken(tokenID string, exp time.Duration) error {
+	if exp <= 0 {
+		return nil
+	}
+	return td.rdb.Set(td.ctx, deniedAccessTokenPrefix+tokenID, 1, exp).Err()
+}
+
+// IsAccessTokenDenied checks whether the access token ID is in the denylist.
+func (td *TokenDB) IsAccessTokenDenied(tokenID string) (bool, error) {
+	n, err := td.rdb.Exists(td.ctx, deniedAccessTokenPrefix+tokenID).Result()
+	return n > 0, err
+}
+
+// SetPasswordResetToken stores the ID of an unused password reset token until
+// it expires and indexes it by its user. The index lives as long as the most
+// recently stored token of the user.
+func (td *TokenDB) SetPasswordResetToken(userID, tokenID string, exp time.Duration) error {
+	userKey := userResetsPrefix + userID
+	_, err := td.rdb.TxPipelined(td.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(td.ctx, passwordResetPrefix+tokenID, 1, exp)
+		pipe.SAdd(td.ctx, userKey, tokenID)
+		pipe.Expire(td.ctx, userKey, exp)
+		return nil
+	})
+	return err
+}
+
+// RevokePasswordResetTokens deletes the IDs of the unused password reset
+// tokens of a user.
+func (td *TokenDB) RevokePasswordResetTokens(userID string) error {
+	userKey := userResetsPrefix + userID
+	ids, err := td.rdb.SMembers(td.ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, passwordResetPrefix+id)
+	}
+	return td.rdb.Del(td.ctx, append(keys, userKey)...).Err()
+}
+
+// ConsumePasswordResetToken deletes the ID of a password reset token. It reports
+// whether the token was unused, only one of concurrent consumers succeeds.
+func (td *TokenDB) ConsumePasswordResetToken(tokenID string) (bool, error) {
+	n, err := td.rdb.Del(td.ctx, passwordResetPrefix+tokenID).Result()
+	return n == 1, err
+}
+
+// SetAuthorizationCode stores the grant of an authorization code by the hash
+// of the code until it expires.
+func (td *TokenDB) SetAuthorizationCode(codeHash string, grant *token.AuthorizationGrant, exp time.Duration) error {
+	data, err := json.Marshal(&authorizationCodeRecord{
+		ClientID:      grant.ClientID,
+		RedirectURI:   grant.RedirectURI,
+		Scope:         grant.Scope,
+		Audience:      grant.Audience,
+		CodeChallenge: grant.CodeChallenge,
+		Nonce:         grant.Nonce,
+		Login:         grant.Login,
+		AuthTime:      grant.AuthTime,
+	})
+	if err != nil {
+		return err
+	}
+	return td.rdb.Set(td.ctx, authorizationCodePrefix+codeHash, data, exp).Err()
+}
+
+// ConsumeAuthorizationCode fetches and deletes the grant of an authorization
+// code, nil if the code is unknown, expired or already used.
+func (td *TokenDB) ConsumeAuthorizationCode(codeHash string) (*token.AuthorizationGrant, error) {
+	data, err := td.rdb.GetDel(td.ctx, authorizationCodePrefix+codeHash).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec authorizationCodeRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return &token.AuthorizationGrant{
+		ClientID:      rec.ClientID,
+		RedirectURI:   rec.RedirectURI,
+		Scope:         rec.Scope,
+		Audience:      rec.Audience,
+		CodeChallenge: rec.CodeChallenge,
+		Nonce:         rec.Nonce,
+		Login:         rec.Login,
+		AuthTime:      rec.AuthTime,
+	}, nil
+}
+
+// SetDeviceGrant stores a device grant by the hash of its device code until
+// exp and indexes it by its user code until the grant expires. It reports
+// false if the user code is already in use.
+func (td *TokenDB) SetDeviceGrant(grant *token.DeviceGrant, exp time.Duration) (bool, error) {
+	data, err := json.Marshal(toDeviceGrantRecord(grant))
+	if err != nil {
+		return false, err
+	}
+	ok, err := td.rdb.SetNX(td.ctx, userCodePrefix+grant.UserCode, grant.DeviceCodeHash, grant.ExpiresIn()).Result()
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, td.rdb.Set(td.ctx, deviceGrantPrefix+grant.DeviceCodeHash, data, exp).Err()
+}
+
+// GetDeviceGrant fetches a device grant by the hash of its device code, nil
+// if it doesn't exist.
+func (td *TokenDB) GetDeviceGrant(deviceCodeHash string) (*token.DeviceGrant, error) {
+	data, err := td.rdb.Get(td.ctx, deviceGrantPrefix+deviceCodeHash).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDeviceGrant(deviceCodeHash, data)
+}
+
+// GetDeviceGrantByUserCode fetches an undecided device grant by its user
+// code, nil if it doesn't exist.
+func (td *TokenDB) GetDeviceGrantByUserCode(userCode string) (*token.DeviceGrant, error) {
+	hash, err := td.rdb.Get(td.ctx, userCodePrefix+userCode).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return td.GetDeviceGrant(hash)
+}
+
+// UpdateDeviceGrant stores the decision of the user on a device grant keeping
+// its expiry, the user code is released at once.
+func (td *TokenDB) UpdateDeviceGrant(grant *token.DeviceGrant) error {
+	data, err := json.Marshal(toDeviceGrantRecord(grant))
+	if err != nil {
+		return err
+	}
+	_, err = td.rdb.TxPipelined(td.ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetXX(td.ctx, deviceGrantPrefix+grant.DeviceCodeHash, data, redis.KeepTTL)
+		pipe.Del(td.ctx, userCodePrefix+grant.UserCode)
+		return nil
+	})
+	return err
+}
+
+// PollDeviceGrant records a poll of a device code. It reports false if the
+// device code has been polled within the interval.
+func (td *TokenDB) PollDeviceGrant(deviceCodeHash string, interval time.Duration) (bool, error) {
+	return td.rdb.SetNX(td.ctx, devicePollPrefix+deviceCodeHash, 1, interval).Result()
+}
+
+// ConsumeDeviceGrant deletes a device grant and its user code. It reports
+// whether the grant existed, only one of concurrent consumers succeeds.
+func (td *TokenDB) ConsumeDeviceGrant(grant *token.DeviceGrant) (bool, error) {
+	var del *redis.IntCmd
+	_, err := td.rdb.TxPipelined(td.ctx, func(pipe redis.Pipeliner) error {
+		del = pipe.Del(td.ctx, deviceGrantPrefix+grant.DeviceCodeHash)
+		pipe.Del(td.ctx, userCodePrefix+grant.UserCode)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return del.Val() == 1, nil
+}
+
+// GetUserCodeFailures fetches the number of wrong user codes of the client IP
+// within the current window.
+func (td *TokenDB) GetUserCodeFailures(ip string) (int, error) {
+	failures, err := td.rdb.Get(td.ctx, userCodeFailuresPrefix+ip).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return failures, err
+}
+
+// IncrUserCodeFailures counts a wrong user code of the client IP, the
+// failures expire window after the first one.
+func (td *TokenDB) IncrUserCodeFailures(ip string, window time.Duration) error {
+	return incrFailuresScript.Run(td.ctx, td.rdb, []string{userCodeFailuresPrefix + ip}, window.Milliseconds()).Err()
+}
+
+func toDeviceGrantRecord(grant *token.DeviceGrant) *deviceGrantRecord {
+	return &deviceGrantRecord{
+		ClientID: grant.ClientID,
+		Scope:    grant.Scope,
+		Audience: grant.Audience,
+		UserCode: grant.UserCode,
+		Status:   grant.Status,
+		Login:    grant.Login,
+		AuthTime: grant.AuthTime,
+		Expires:  grant.Expires,
+	}
+}
+
+func toDeviceGrant(deviceCodeHash string, data []byte) (*token.DeviceGrant, error) {
+	var rec deviceGrantRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return &token.DeviceGrant{
+		AuthorizationGrant: token.AuthorizationGrant{
+			ClientID: rec.ClientID,
+			Scope:    rec.Scope,
+			Audience: rec.Audience,
+			Login:    rec.Login,
+			AuthTime: rec.AuthTime,
+		},
+		DeviceCodeHash: deviceCodeHash,
+		UserCode:       rec.UserCode,
+		Status:         rec.Status,
+		Expires:        rec.Expires,
+	}, nil
+}
+
+// SetMFAChallenge stores the login of an MFA challenge by the hash of its
+// token until it expires.
+func (td *TokenDB) SetMFAChallenge(challengeHash, login string, exp time.Duration) error {
+	key := mfaChallengePrefix + challengeHash
+	_, err := td.rdb.TxPipelined(td.ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(td.ctx, key, "login", login, "attempts", 0)
+		pipe.Expire(td.ctx, key, exp)
+		return nil
+	})
+	return err
+}
+
+// GetMFAChallenge fetches the login of an MFA challenge, empty if the
+// challenge is unknown, expired, used or revoked.
+func (td *TokenDB) GetMFAChallenge(challengeHash string) (string, error) {
+	login, err := td.rdb.HGet(td.ctx, mfaChallengePrefix+challengeHash, "login").Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return login, err
+}
+
+// failMFAChallengeScript counts a failed attempt of an existing MFA
+// challenge, a challenge that has expired meanwhile is not recreated.
+var failMFAChallengeScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+if redis.call("HINCRBY", KEYS[1], "attempts", 1) >= tonumber(ARGV[1]) then
+	redis.call("DEL", KEYS[1])
+end
+return 1
+`)
+
+// FailMFAChallenge counts a failed attempt of an MFA challenge and deletes
+// the challenge once maxAttempts are reached.
+func (td *TokenDB) FailMFAChallenge(challengeHash string, maxAttempts int) error {
+	return failMFAChallengeScript.Run(td.ctx, td.rdb, []string{mfaChallengePrefix + challengeHash}, maxAttempts).Err()
+}
+
+// ConsumeMFAChallenge deletes an MFA challenge. It reports whether the
+// challenge existed, only one of concurrent consumers succeeds.
+func (td *TokenDB) ConsumeMFAChallenge(challengeHash string) (bool, error) {
+	n, err := td.rdb.Del(td.ctx, mfaChallengePrefix+challengeHash).Result()
+	return n == 1, err
+}
+
+// GetMFAFailures fetches the number of wrong codes of the user with the
+// login within the current window.
+func (td *TokenDB) GetMFAFailures(login string) (int, error) {
+	failures, err := td.rdb.Get(td.ctx, mfaFailuresPrefix+login).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return failures, err
+}
+
+// incrFailuresScript counts a wrong code, the window starts with the first
+// one.
+var incrFailuresScript = redis.NewScript(`
+if redis.call("INCR", KEYS[1]) == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// IncrMFAFailures counts a wrong code of the user with the login, the
+// failures expire window after the first one.
+func (td *TokenDB) IncrMFAFailures(login string, window time.Duration) error {
+	return incrFailuresScript.Run(td.ctx, td.rdb, []string{mfaFailuresPrefix + login}, window.Milliseconds()).Err()
+}
+
+// DelMFAFailures clears the wrong codes of the user with the login.
+func (td *TokenDB) DelMFAFailures(login string) error {
+	return td.rdb.Del(td.ctx, mfaFailuresPrefix+login).Err()
+}
+
+// webAuthnChallengeRecord is the stored form of a WebAuthn challenge.
+type webAuthnChallengeRecord struct {
+	Ceremony string `json:"ceremony"`
+	Login    string `json:"login"`
 }
 
-func (td *TokenDB) GetRefreshToken(key string) (string, error) {
-	return td.rdb.Get(td.ctx, key).Result()
+// SetWebAuthnChallenge stores a WebAuthn ceremony by the hash of its
+// challenge until exp.
+func (td *TokenDB) SetWebAuthnChallenge(challengeHash string, wc *token.WebAuthnChallenge, exp time.Duration) error {
+	data, err := json.Marshal(&webAuthnChallengeRecord{wc.Ceremony, wc.Login})
+	if err != nil {
+		return err
+	}
+	return td.rdb.Set(td.ctx, webAuthnChallengePrefix+challengeHash, data, exp).Err()
 }
 
-func (td *TokenDB) RevokeRefreshToken(key string) error {
-	return td.rdb.Del(td.ctx, key).Err()
+// ConsumeWebAuthnChallenge fetches and deletes a WebAuthn ceremony atomically,
+// nil if the challenge is unknown, expired or already used.
+func (td *TokenDB) ConsumeWebAuthnChallenge(challengeHash string) (*token.WebAuthnChallenge, error) {
+	data, err := td.rdb.GetDel(td.ctx, webAuthnChallengePrefix+challengeHash).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec webAuthnChallengeRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return &token.WebAuthnChallenge{Ceremony: rec.Ceremony, Login: rec.Login}, nil
 }
@@ -12,11 +12,17 @@ import (
 	"strings"
 
 	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/mfa"
+	"github.com/parthoshuvo/authsvc/passwd"
 	usrTable "github.com/parthoshuvo/authsvc/table/user"
 	"github.com/parthoshuvo/authsvc/token"
+	"github.com/parthoshuvo/authsvc/webauthn"
 )
 
-const defaultConfigFilePath = "authsvc.json"
+const (
+	defaultConfigFilePath  = "authsvc.json"
+	defaultPasswordHistory = 5
+)
 const defaultLogLevel = "DEBUG"
 
 // Config holds configuration data.
@@ -27,10 +33,12 @@ type Config struct {
 	appName    string
 }
 
-// ServerDef defines a server address and port.
+// ServerDef defines a server address and port. The X-Forwarded-For header is
+// honoured for requests of the trusted proxies only.
 type ServerDef struct {
-	Bind string
-	Port int
+	Bind           string
+	Port           int
+	TrustedProxies []string
 }
 
 // DBDef database definition
@@ -56,6 +64,11 @@ type SmtpServerDef struct {
 	From usrTable.Email
 }
 
+// SecurityEventDef defines how security events are reported.
+type SecurityEventDef struct {
+	MailUser bool
+}
+
 // logDef defines logging
 type logDef struct {
 	Filename string
@@ -64,16 +77,23 @@ type logDef struct {
 
 // configData defines the authsvc configuration file structure.
 type configData struct {
-	Name        string
-	Description string
-	AllowCORS   bool
-	Server      *ServerDef
-	DB          *DBDef
-	TokenDB     *TokenDBDef
-	JWTDef      *token.JWTDef
-	SmtpServer  *SmtpServerDef
-	Logging     *logDef
-	Indent      bool
+	Name            string
+	Description     string
+	AllowCORS       bool
+	Server          *ServerDef
+	DB              *DBDef
+	TokenDB         *TokenDBDef
+	JWTDef          *token.JWTDef
+	PasswordHash    *passwd.HashDef
+	ResetLink       string
+	PasswordHistory int
+	SmtpServer      *SmtpServerDef
+	TOTP            *mfa.TOTPDef
+	WebAuthn        *webauthn.RelyingPartyDef
+	Permissions     map[string][]string
+	Security        *SecurityEventDef
+	Logging         *logDef
+	Indent          bool
 }
 
 // NewConfig creates the application configuration.
@@ -115,11 +135,54 @@ func (c *Config) JWTDef() *token.JWTDef {
 	return c.configData.JWTDef
 }
 
+// PasswordHashDef returns the hashing definition of user passwords
+func (c *Config) PasswordHashDef() *passwd.HashDef {
+	return c.configData.PasswordHash
+}
+
+// PasswordResetLink returns the link of the password reset page mailed to users
+func (c *Config) PasswordResetLink() string {
+	return c.configData.ResetLink
+}
+
+// PasswordHistory returns the number of recent passwords, the current one
+// included, a new password must not be equal to
+func (c *Config) PasswordHistory() int {
+	if c.configData.PasswordHistory <= 0 {
+		return defaultPasswordHistory
+	}
+	return c.configData.PasswordHistory
+}
+
 // SmtpServerDef returns SMTP mail server definition
 func (c *Config) SmtpServerDef() *SmtpServerDef {
 	return c.configData.SmtpServer
 }
 
+// TOTPDef returns the time-based one-time password definition of two-factor
+// authentication
+func (c *Config) TOTPDef() *mfa.TOTPDef {
+	return c.configData.TOTP
+}
+
+// WebAuthnDef returns the WebAuthn relying party definition of passkeys
+func (c *Config) WebAuthnDef() *webauthn.RelyingPartyDef {
+	return c.configData.WebAuthn
+}
+
+// SecurityEventDef returns the reporting definition of security events.
+func (c *Config) SecurityEventDef() *SecurityEventDef {
+	if c.configData.Security == nil {
+		return &SecurityEventDef{}
+	}
+	return c.configData.Security
+}
+
+// ActionPermissions returns the permissions required by the protected actions.
+func (c *Config) ActionPermissions() map[string][]string {
+	return c.configData.Permissions
+}
+
 // IsLogDebug indicates whether debug logging is wanted.
 func (c *Config) IsLogDebug() bool {
 	return c.logDebug
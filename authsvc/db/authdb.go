@@ -7,9 +7,12 @@ import (
 	"github.com/parthoshuvo/authsvc/cfg"
 	log "github.com/parthoshuvo/authsvc/log4u"
 
-	_ "github.com/go-sql-driver/mysql" // mysql driver
+	"github.com/go-sql-driver/mysql"
 )
 
+// erSignalException is the MySQL error number of errors raised by SIGNAL statements.
+const erSignalException = 1644
+
 // AuthDB database.
 type AuthDB struct {
 	db *sql.DB
@@ -38,3 +41,14 @@ func openDatabase(dbDef *cfg.DBDef) *sql.DB {
 func (ad *AuthDB) Close() {
 	ad.db.Close()
 }
+
+// signalled maps an error raised by a SIGNAL statement of a stored procedure to
+// the error of its message, other errors are returned as they are.
+func signalled(err error, errs map[string]error) error {
+	if merr, ok := err.(*mysql.MySQLError); ok && merr.Number == erSignalException {
+		if serr, ok := errs[merr.Message]; ok {
+			return serr
+		}
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/parthoshuvo/authsvc/table/client"
+)
+
+// clientScanner is a single row or the rows a client is scanned from.
+type clientScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ReadClients fetches all OAuth clients.
+func (ad *AuthDB) ReadClients() ([]*client.Client, error) {
+	clnts := make([]*client.Client, 0, 10)
+	rows, err := ad.db.Query("call sp_read_oauth_clients()")
+	if err == sql.ErrNoRows {
+		return clnts, nil
+	}
+	if err != nil {
+		return clnts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		clnt, err := scanClient(rows)
+		if err != nil {
+			return clnts, err
+		}
+		clnts = append(clnts, clnt)
+	}
+	return clnts, nil
+}
+
+// ReadClient fetches an OAuth client by client ID.
+func (ad *AuthDB) ReadClient(clientID string) (*client.Client, error) {
+	clnt, err := scanClient(ad.db.QueryRow("call sp_read_oauth_client(?)", clientID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return clnt, err
+}
+
+// InsertClient registers an OAuth client.
+func (ad *AuthDB) InsertClient(clnt *client.Client) error {
+	var secret sql.NullString
+	if !clnt.Public {
+		secret = sql.NullString{String: clnt.Secret, Valid: true}
+	}
+	_, err := ad.db.Exec("call sp_insert_oauth_client(?,?,?,?,?,?,?)",
+		clnt.ID,
+		secret,
+		clnt.Name,
+		strings.Join(clnt.Grants, " "),
+		strings.Join(clnt.Scopes, " "),
+		strings.Join(clnt.RedirectURIs, " "),
+		strings.Join(clnt.Audience, " "),
+	)
+	return signalled(err, map[string]error{"client already exists": client.ErrClientExists})
+}
+
+// UpdateClient changes the registration of an OAuth client.
+func (ad *AuthDB) UpdateClient(clnt *client.Client) error {
+	_, err := ad.db.Exec("call sp_update_oauth_client(?,?,?,?,?,?)",
+		clnt.ID,
+		clnt.Name,
+		strings.Join(clnt.Grants, " "),
+		strings.Join(clnt.Scopes, " "),
+		strings.Join(clnt.RedirectURIs, " "),
+		strings.Join(clnt.Audience, " "),
+	)
+	return signalled(err, map[string]error{"no client is found": client.ErrClientNotFound})
+}
+
+// UpdateClientSecret replaces the hashed secret of an OAuth client.
+func (ad *AuthDB) UpdateClientSecret(clientID, secret string) error {
+	_, err := ad.db.Exec("call sp_oauth_client_secret_assignment(?,?)", clientID, secret)
+	return signalled(err, map[string]error{"no client is found": client.ErrClientNotFound})
+}
+
+// DeleteClient deletes an OAuth client.
+func (ad *AuthDB) DeleteClient(clientID string) error {
+	_, err := ad.db.Exec("call sp_delete_oauth_client(?)", clientID)
+	return signalled(err, map[string]error{"no client is found": client.ErrClientNotFound})
+}
+
+// scanClient scans a client, its lists are stored space separated and public
+// clients have no secret.
+func scanClient(row clientScanner) (*client.Client, error) {
+	var clnt client.Client
+	var secret sql.NullString
+	var grants, scopes, redirectURIs, audience string
+	if err := row.Scan(
+		&clnt.ID,
+		&secret,
+		&clnt.Name,
+		&grants,
+		&scopes,
+		&redirectURIs,
+		&audience,
+	); err != nil {
+		return nil, err
+	}
+	clnt.Secret = secret.String
+	clnt.Public = !secret.Valid
+	clnt.Grants = strings.Fields(grants)
+	clnt.Scopes = strings.Fields(scopes)
+	clnt.RedirectURIs = strings.Fields(redirectURIs)
+	clnt.Audience = strings.Fields(audience)
+	return &clnt, nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/parthoshuvo/authsvc/table/credential"
+)
+
+// ReadUserCredentials fetches the WebAuthn credentials of user.
+func (ad *AuthDB) ReadUserCredentials(login string) ([]*credential.Credential, error) {
+	creds := make([]*credential.Credential, 0, 5)
+	rows, err := ad.db.Query("call sp_read_user_credentials(?)", login)
+	if err == sql.ErrNoRows {
+		return creds, nil
+	}
+	if err != nil {
+		return creds, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		cred, err := scanCredential(rows)
+		if err != nil {
+			return creds, err
+		}
+		creds = append(creds, cred)
+	}
+	return creds, rows.Err()
+}
+
+// ReadCredential fetches a WebAuthn credential by its ID.
+func (ad *AuthDB) ReadCredential(id string) (*credential.Credential, error) {
+	cred, err := scanCredential(ad.db.QueryRow("call sp_read_credential(?)", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return cred, err
+}
+
+// InsertCredential registers a WebAuthn credential of its user.
+func (ad *AuthDB) InsertCredential(cred *credential.Credential) error {
+	_, err := ad.db.Exec("call sp_insert_credential(?,?,?,?,?,?,?,?)",
+		cred.Login,
+		cred.ID,
+		cred.Name,
+		cred.PublicKey,
+		cred.SignCount,
+		cred.AAGUID,
+		cred.Format,
+		strings.Join(cred.Transports, " "),
+	)
+	return signalled(err, map[string]error{"credential already exists": credential.ErrCredentialExists})
+}
+
+// UpdateCredentialSignCount records the sign count of a WebAuthn credential.
+func (ad *AuthDB) UpdateCredentialSignCount(id string, signCount uint32) (bool, error) {
+	var rows int
+	err := ad.db.QueryRow("call sp_credential_sign_count_assignment(?, ?)", id, signCount).Scan(&rows)
+	return rows == 1, err
+}
+
+// DisableCredential disables a WebAuthn credential.
+func (ad *AuthDB) DisableCredential(id string) error {
+	_, err := ad.db.Exec("call sp_credential_disabled_assignment(?)", id)
+	return err
+}
+
+// DeleteCredential deletes a WebAuthn credential of user.
+func (ad *AuthDB) DeleteCredential(login, id string) error {
+	_, err := ad.db.Exec("call sp_delete_credential(?, ?)", login, id)
+	return signalled(err, map[string]error{"no credential is found": credential.ErrCredentialNotFound})
+}
+
+// scanCredential scans a credential, its transports are stored space separated.
+func scanCredential(row interface{ Scan(...interface{}) error }) (*credential.Credential, error) {
+	var cred credential.Credential
+	var transports string
+	var lastUsed sql.NullString
+	if err := row.Scan(
+		&cred.ID,
+		&cred.Login,
+		&cred.UserGUID,
+		&cred.Name,
+		&cred.PublicKey,
+		&cred.SignCount,
+		&cred.AAGUID,
+		&cred.Format,
+		&transports,
+		&cred.Created,
+		&lastUsed,
+		&cred.Disabled,
+	); err != nil {
+		return nil, err
+	}
+	cred.Transports = strings.Fields(transports)
+	cred.LastUsed = lastUsed.String
+	return &cred, nil
+}
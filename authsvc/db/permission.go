@@ -36,3 +36,45 @@ func (ad *AuthDB) readPermissions(dbReader func() (*sql.Rows, error)) ([]*permis
 	}
 	return perms, nil
 }
+
+// ReadPermissions fetches all permissions.
+func (ad *AuthDB) ReadPermissions() ([]*permission.Permission, error) {
+	return ad.readPermissions(func() (*sql.Rows, error) {
+		return ad.db.Query("call sp_read_permissions()")
+	})
+}
+
+// ReadPermission fetches a permission by ID.
+func (ad *AuthDB) ReadPermission(id int) (*permission.Permission, error) {
+	perm := permission.Permission{}
+	err := ad.db.QueryRow("call sp_read_permission(?)", id).Scan(
+		&perm.ID,
+		&perm.Name,
+		&perm.Description,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &perm, err
+}
+
+// InsertPermission creates a permission.
+func (ad *AuthDB) InsertPermission(perm *permission.Permission) (*permission.Permission, error) {
+	err := ad.db.QueryRow("call sp_insert_permission(?,?)", perm.Name, perm.Description).Scan(&perm.ID)
+	return perm, signalled(err, map[string]error{"permission already exists": permission.ErrPermissionExists})
+}
+
+// UpdatePermission changes the name and description of a permission.
+func (ad *AuthDB) UpdatePermission(perm *permission.Permission) error {
+	_, err := ad.db.Exec("call sp_update_permission(?,?,?)", perm.ID, perm.Name, perm.Description)
+	return signalled(err, map[string]error{
+		"no permission is found":    permission.ErrPermissionNotFound,
+		"permission already exists": permission.ErrPermissionExists,
+	})
+}
+
+// DeletePermission deletes a permission.
+func (ad *AuthDB) DeletePermission(id int) error {
+	_, err := ad.db.Exec("call sp_delete_permission(?)", id)
+	return signalled(err, map[string]error{"no permission is found": permission.ErrPermissionNotFound})
+}
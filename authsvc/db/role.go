@@ -3,7 +3,9 @@ package db
 import (
 	"database/sql"
 
+	"github.com/parthoshuvo/authsvc/table/permission"
 	"github.com/parthoshuvo/authsvc/table/role"
+	"github.com/parthoshuvo/authsvc/table/user"
 )
 
 // ReadUserRoles fetches all assigned roles for a user.
@@ -36,3 +38,118 @@ func (ad *AuthDB) readRoles(dbReader func() (*sql.Rows, error)) ([]*role.Role, e
 	}
 	return roles, nil
 }
+
+// ReadRoles fetches all roles.
+func (ad *AuthDB) ReadRoles() ([]*role.Role, error) {
+	return ad.readRoles(func() (*sql.Rows, error) {
+		return ad.db.Query("call sp_read_roles()")
+	})
+}
+
+// ReadRole fetches a role by ID.
+func (ad *AuthDB) ReadRole(id int) (*role.Role, error) {
+	rl := role.Role{}
+	err := ad.db.QueryRow("call sp_read_role(?)", id).Scan(
+		&rl.ID,
+		&rl.Name,
+		&rl.Description,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &rl, err
+}
+
+// InsertRole creates a role.
+func (ad *AuthDB) InsertRole(rl *role.Role) (*role.Role, error) {
+	err := ad.db.QueryRow("call sp_insert_role(?,?)", rl.Name, rl.Description).Scan(&rl.ID)
+	return rl, signalled(err, map[string]error{"role already exists": role.ErrRoleExists})
+}
+
+// UpdateRole changes the name and description of a role.
+func (ad *AuthDB) UpdateRole(rl *role.Role) error {
+	_, err := ad.db.Exec("call sp_update_role(?,?,?)", rl.ID, rl.Name, rl.Description)
+	return signalled(err, map[string]error{
+		"no role is found":    role.ErrRoleNotFound,
+		"role already exists": role.ErrRoleExists,
+	})
+}
+
+// DeleteRole deletes a role.
+func (ad *AuthDB) DeleteRole(id int) error {
+	_, err := ad.db.Exec("call sp_delete_role(?)", id)
+	return signalled(err, map[string]error{"no role is found": role.ErrRoleNotFound})
+}
+
+// ReadRolePermissions fetches the permissions attached to a role.
+func (ad *AuthDB) ReadRolePermissions(roleID int) ([]*permission.Permission, error) {
+	return ad.readPermissions(func() (*sql.Rows, error) {
+		return ad.db.Query("call sp_read_role_permission(?)", roleID)
+	})
+}
+
+// InsertRolePermission attaches a permission to a role.
+func (ad *AuthDB) InsertRolePermission(roleID, permID int) error {
+	var id int
+	err := ad.db.QueryRow("call sp_insert_role_permission(?,?)", roleID, permID).Scan(&id)
+	return signalled(err, map[string]error{
+		"no role is found":                     role.ErrRoleNotFound,
+		"no permission is found":               permission.ErrPermissionNotFound,
+		"The group already has the permission": role.ErrRolePermissionExists,
+	})
+}
+
+// DeleteRolePermission detaches a permission from a role.
+func (ad *AuthDB) DeleteRolePermission(roleID, permID int) error {
+	_, err := ad.db.Exec("call sp_delete_role_permission(?,?)", roleID, permID)
+	return signalled(err, map[string]error{"The group does not have the permission": role.ErrRolePermissionNotFound})
+}
+
+// InsertUserRole assigns a role to a user.
+func (ad *AuthDB) InsertUserRole(userID, roleID int) error {
+	var id int
+	err := ad.db.QueryRow("call sp_insert_user_role(?,?)", userID, roleID).Scan(&id)
+	return signalled(err, map[string]error{
+		"no user is found":              user.ErrUserNotFound,
+		"no role is found":              role.ErrRoleNotFound,
+		"The user has already the role": role.ErrUserRoleExists,
+	})
+}
+
+// DeleteUserRole removes a role from a user.
+func (ad *AuthDB) DeleteUserRole(userID, roleID int) error {
+	_, err := ad.db.Exec("call sp_delete_user_role(?,?)", userID, roleID)
+	return signalled(err, map[string]error{"The user does not have the role": role.ErrUserRoleNotFound})
+}
+
+// ReadUserEffectiveRoles fetches the assigned roles of a user together with
+// the roles they inherit.
+func (ad *AuthDB) ReadUserEffectiveRoles(login string) ([]*role.Role, error) {
+	return ad.readRoles(func() (*sql.Rows, error) {
+		return ad.db.Query("call sp_read_user_effective_role(?)", login)
+	})
+}
+
+// ReadRoleParents fetches the parent roles of a role.
+func (ad *AuthDB) ReadRoleParents(roleID int) ([]*role.Role, error) {
+	return ad.readRoles(func() (*sql.Rows, error) {
+		return ad.db.Query("call sp_read_role_parent(?)", roleID)
+	})
+}
+
+// InsertRoleParent makes a role inherit the permissions of a parent role.
+func (ad *AuthDB) InsertRoleParent(roleID, parentID int) error {
+	var id int
+	err := ad.db.QueryRow("call sp_insert_role_parent(?,?)", roleID, parentID).Scan(&id)
+	return signalled(err, map[string]error{
+		"no role is found":                     role.ErrRoleNotFound,
+		"The role already has the parent role": role.ErrRoleParentExists,
+		"The parent role would create a cycle": role.ErrRoleCycle,
+	})
+}
+
+// DeleteRoleParent removes a parent role from a role.
+func (ad *AuthDB) DeleteRoleParent(roleID, parentID int) error {
+	_, err := ad.db.Exec("call sp_delete_role_parent(?,?)", roleID, parentID)
+	return signalled(err, map[string]error{"The role does not have the parent role": role.ErrRoleParentNotFound})
+}
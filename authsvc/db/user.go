@@ -2,16 +2,53 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 
 	"github.com/parthoshuvo/authsvc/table/user"
 )
 
 // ReadUserByLogin reads an user by login.
 func (ad *AuthDB) ReadUserByLogin(login string) (*user.User, error) {
+	return ad.readUser(ad.db.QueryRow("call sp_user_get_by_login(?)", login))
+}
+
+// ReadUserByID reads an user by its public ID (rowguid).
+func (ad *AuthDB) ReadUserByID(id string) (*user.User, error) {
+	return ad.readUser(ad.db.QueryRow("call sp_user_get_by_guid(?)", id))
+}
+
+// ReadUsers reads a page of the users whose login or name contains search.
+func (ad *AuthDB) ReadUsers(search string, offset, limit int) ([]*user.User, error) {
+	users := make([]*user.User, 0, limit)
+	rows, err := ad.db.Query("call sp_read_users(?, ?, ?)", search, offset, limit)
+	if err == sql.ErrNoRows {
+		return users, nil
+	}
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		usr, err := ad.readUser(rows)
+		if err != nil {
+			return users, err
+		}
+		users = append(users, usr)
+	}
+	return users, rows.Err()
+}
+
+// CountUsers counts the users whose login or name contains search.
+func (ad *AuthDB) CountUsers(search string) (int, error) {
+	var total int
+	err := ad.db.QueryRow("call sp_count_users(?)", search).Scan(&total)
+	return total, err
+}
+
+func (ad *AuthDB) readUser(row interface{ Scan(...interface{}) error }) (*user.User, error) {
 	usr := user.User{}
-	err := ad.db.QueryRow(
-		"call sp_user_get_by_login(?)",
-		login).Scan(
+	err := row.Scan(
+		&usr.ID,
 		&usr.Firstname,
 		&usr.Lastname,
 		&usr.Email,
@@ -19,6 +56,8 @@ func (ad *AuthDB) ReadUserByLogin(login string) (*user.User, error) {
 		&usr.RowGUID,
 		&usr.Verified,
 		&usr.VerificationCode,
+		&usr.Disabled,
+		&usr.TOTPEnabled,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -42,5 +81,105 @@ func (ad *AuthDB) InsertUser(usr *user.User) (*user.User, error) {
 // AssignUserVerification assigns verification status to user
 func (ad *AuthDB) AssignUserVerification(login string, isVerified bool) error {
 	_, err := ad.db.Exec("call sp_user_verification_assignment(?, ?)", login, isVerified)
+	return signalled(err, map[string]error{"no user is found": user.ErrUserNotFound})
+}
+
+// AssignUserPassword replaces the password hash of user
+func (ad *AuthDB) AssignUserPassword(login string, password user.Password) error {
+	_, err := ad.db.Exec("call sp_user_password_assignment(?, ?)", login, password)
+	return err
+}
+
+// ReadUserPasswordHistory fetches the most recent former password hashes of user
+func (ad *AuthDB) ReadUserPasswordHistory(login string, size int) ([]user.Password, error) {
+	passwords := make([]user.Password, 0, size)
+	rows, err := ad.db.Query("call sp_user_password_history_get(?, ?)", login, size)
+	if err == sql.ErrNoRows {
+		return passwords, nil
+	}
+	if err != nil {
+		return passwords, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var password user.Password
+		if err := rows.Scan(&password); err != nil {
+			return passwords, err
+		}
+		passwords = append(passwords, password)
+	}
+	return passwords, rows.Err()
+}
+
+// ChangeUserPassword replaces the password hash of user and keeps the former hash
+// in the password history
+func (ad *AuthDB) ChangeUserPassword(login string, password user.Password, historySize int) error {
+	_, err := ad.db.Exec("call sp_user_password_change(?, ?, ?)", login, password, historySize)
 	return err
 }
+
+// AssignUserDisabled disables or enables the account of user
+func (ad *AuthDB) AssignUserDisabled(login string, isDisabled bool) error {
+	_, err := ad.db.Exec("call sp_user_disabled_assignment(?, ?)", login, isDisabled)
+	return signalled(err, map[string]error{"no user is found": user.ErrUserNotFound})
+}
+
+// DeleteUser deletes a user with its roles and password history
+func (ad *AuthDB) DeleteUser(login string) error {
+	_, err := ad.db.Exec("call sp_delete_user(?)", login)
+	return signalled(err, map[string]error{"no user is found": user.ErrUserNotFound})
+}
+
+// ReadUserTOTP reads the two-factor authentication state of user
+func (ad *AuthDB) ReadUserTOTP(login string) (*user.TOTP, error) {
+	var totp user.TOTP
+	var secret sql.NullString
+	err := ad.db.QueryRow("call sp_user_totp_get(?)", login).Scan(&secret, &totp.Enabled, &totp.Counter)
+	if err == sql.ErrNoRows {
+		return nil, user.ErrUserNotFound
+	}
+	totp.Secret = secret.String
+	return &totp, err
+}
+
+// AssignUserTOTP replaces the TOTP secret of user with a pending one, an
+// empty secret is stored as NULL
+func (ad *AuthDB) AssignUserTOTP(login string, secret string) error {
+	_, err := ad.db.Exec("call sp_user_totp_assignment(?, ?)", login, sql.NullString{String: secret, Valid: secret != ""})
+	return signalled(err, map[string]error{"no user is found": user.ErrUserNotFound})
+}
+
+// EnableUserTOTP enables the pending TOTP secret of user
+func (ad *AuthDB) EnableUserTOTP(login string, counter int64) (bool, error) {
+	var rows int
+	err := ad.db.QueryRow("call sp_user_totp_enabled_assignment(?, ?)", login, counter).Scan(&rows)
+	return rows == 1, err
+}
+
+// AdvanceUserTOTPCounter records the time step of an accepted TOTP code
+func (ad *AuthDB) AdvanceUserTOTPCounter(login string, counter int64) (bool, error) {
+	var rows int
+	err := ad.db.QueryRow("call sp_user_totp_counter_assignment(?, ?)", login, counter).Scan(&rows)
+	return rows == 1, err
+}
+
+// AssignUserRecoveryCodes replaces the recovery code hashes of user, passed
+// as JSON array
+func (ad *AuthDB) AssignUserRecoveryCodes(login string, hashes []string) error {
+	if hashes == nil {
+		hashes = []string{}
+	}
+	codes, err := json.Marshal(hashes)
+	if err != nil {
+		return err
+	}
+	_, err = ad.db.Exec("call sp_user_recovery_codes_assignment(?, ?)", login, string(codes))
+	return signalled(err, map[string]error{"no user is found": user.ErrUserNotFound})
+}
+
+// ConsumeUserRecoveryCode removes a recovery code hash of user
+func (ad *AuthDB) ConsumeUserRecoveryCode(login string, hash string) (bool, int, error) {
+	var consumed, remaining int
+	err := ad.db.QueryRow("call sp_user_recovery_code_consumption(?, ?)", login, hash).Scan(&consumed, &remaining)
+	return consumed == 1, remaining, err
+}
@@ -0,0 +1,78 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount is the number of recovery codes of a set.
+	RecoveryCodeCount = 10
+	// recoveryCodeLength is the number of characters of a recovery code
+	// without separators, 5 bits each.
+	recoveryCodeLength = 12
+	recoveryCodeGroup  = 4
+	recoveryCodeChars  = "abcdefghijkmnpqrstuvwxyz23456789"
+)
+
+// NewRecoveryCodes generates a set of single-use recovery codes and their
+// hashes, only the hashes are stored.
+func NewRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i], hashes[i] = code, HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode hashes a recovery code regardless of case and separators.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsTOTPCode distinguishes codes of authenticator apps from recovery codes.
+func IsTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRecoveryCode generates a recovery code of groups separated by dashes
+// e.g. k7dm-x2qa-9fhn.
+func newRecoveryCode() (string, error) {
+	data := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, b := range data {
+		if i > 0 && i%recoveryCodeGroup == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeChars[int(b)%len(recoveryCodeChars)])
+	}
+	return sb.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
@@ -0,0 +1,178 @@
+package mfa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// TOTP parameters of RFC 6238, the defaults of authenticator apps.
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// secretSize is the number of random bytes of a secret, the HMAC-SHA1 block.
+	secretSize = 20
+	// qrCodeSize is the width and height of QR code images in pixels.
+	qrCodeSize = 256
+)
+
+const (
+	defaultIssuer = "AuthSvc"
+	defaultSkew   = 1
+)
+
+var (
+	ErrTOTPEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled = errors.New("two-factor authentication isn't enrolled")
+	ErrTOTPNotEnabled  = errors.New("two-factor authentication isn't enabled")
+	ErrInvalidCode     = errors.New("authentication code is invalid or already used")
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPDef defines time-based one-time passwords (RFC 6238) of two-factor
+// authentication.
+type TOTPDef struct {
+	Issuer        string
+	EncryptionKey string
+	// Skew is the number of time steps accepted before and after the current
+	// one, defaultSkew if unset.
+	Skew *int
+}
+
+// TOTP generates and verifies time-based one-time passwords. Secrets are
+// stored encrypted with AES-256-GCM.
+type TOTP struct {
+	issuer string
+	skew   int
+	aead   cipher.AEAD
+}
+
+func NewTOTP(def *TOTPDef) *TOTP {
+	if def == nil {
+		log.Fatal("TOTP definition is missing")
+	}
+	key, err := base64.StdEncoding.DecodeString(def.EncryptionKey)
+	if err != nil || len(key) != 32 {
+		log.Fatalf("TOTP encryption key must be 32 base64 encoded bytes: [%v]", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		log.Fatalf("failed to create TOTP cipher: [%v]", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		log.Fatalf("failed to create TOTP cipher: [%v]", err)
+	}
+	issuer, skew := def.Issuer, defaultSkew
+	if issuer == "" {
+		issuer = defaultIssuer
+	}
+	if def.Skew != nil {
+		if *def.Skew < 0 {
+			log.Fatalf("TOTP skew must not be negative: [%d]", *def.Skew)
+		}
+		skew = *def.Skew
+	}
+	return &TOTP{issuer, skew, aead}
+}
+
+// NewSecret generates a base32 encoded secret and its encrypted form.
+func (t *TOTP) NewSecret() (string, string, error) {
+	data := make([]byte, secretSize)
+	if _, err := rand.Read(data); err != nil {
+		return "", "", err
+	}
+	secret := b32.EncodeToString(data)
+	encrypted, err := t.Encrypt(secret)
+	return secret, encrypted, err
+}
+
+// Encrypt encrypts a secret with a random nonce prepended.
+func (t *TOTP) Encrypt(secret string) (string, error) {
+	nonce := make([]byte, t.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(t.aead.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// Decrypt decrypts an encrypted secret.
+func (t *TOTP) Decrypt(encrypted string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < t.aead.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+	nonce, ciphertext := data[:t.aead.NonceSize()], data[t.aead.NonceSize():]
+	secret, err := t.aead.Open(nil, nonce, ciphertext, nil)
+	return string(secret), err
+}
+
+// URI is the otpauth:// key URI of a secret scanned by authenticator apps.
+func (t *TOTP) URI(account, secret string) string {
+	uri := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + t.issuer + ":" + account,
+		RawQuery: url.Values{
+			"secret":    {secret},
+			"issuer":    {t.issuer},
+			"algorithm": {"SHA1"},
+			"digits":    {fmt.Sprint(totpDigits)},
+			"period":    {fmt.Sprint(int(totpPeriod.Seconds()))},
+		}.Encode(),
+	}
+	return uri.String()
+}
+
+// Verify checks a code of the secret at the time, codes of skew time steps
+// before and after are accepted too. The time step of the matching code is
+// returned, 0 if no code matches. A code must not be accepted twice, i.e.
+// only time steps after the one of the last accepted code are valid.
+func (t *TOTP) Verify(secret, code string, at time.Time) (int64, error) {
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		return 0, err
+	}
+	step := at.Unix() / int64(totpPeriod.Seconds())
+	for i := -t.skew; i <= t.skew; i++ {
+		counter := step + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, counter)), []byte(code)) == 1 {
+			return counter, nil
+		}
+	}
+	return 0, nil
+}
+
+// QRCode encodes the key URI as QR code PNG image.
+func QRCode(uri string) ([]byte, error) {
+	return qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+}
+
+// hotp is the HMAC-based one-time password of the counter (RFC 4226).
+func hotp(key []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
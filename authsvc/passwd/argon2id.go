@@ -0,0 +1,71 @@
+package passwd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idDef defines the argon2id parameters.
+type Argon2idDef struct {
+	Memory      uint32 // memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+type argon2idHasher struct {
+	def *Argon2idDef
+}
+
+func newArgon2idHasher(def *Argon2idDef) *argon2idHasher {
+	d := Argon2idDef{Memory: 64 * 1024, Iterations: 3, Parallelism: 2}
+	if def != nil {
+		if def.Memory > 0 {
+			d.Memory = def.Memory
+		}
+		if def.Iterations > 0 {
+			d.Iterations = def.Iterations
+		}
+		if def.Parallelism > 0 {
+			d.Parallelism = def.Parallelism
+		}
+	}
+	return &argon2idHasher{&d}
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt, err := salt(argon2SaltLen)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.def.Iterations, h.def.Memory, h.def.Parallelism, argon2KeyLen)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2id, argon2.Version, h.def.Memory, h.def.Iterations, h.def.Parallelism, b64(salt), b64(key)), nil
+}
+
+func (h *argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, hash, err := decodePHC(Argon2id, encoded)
+	if err != nil {
+		return false, err
+	}
+	if params["m"] <= 0 || params["t"] <= 0 || params["p"] <= 0 || params["p"] > 255 {
+		return false, fmt.Errorf("invalid %s hash parameters", Argon2id)
+	}
+	key := argon2.IDKey([]byte(password), salt, uint32(params["t"]), uint32(params["m"]), uint8(params["p"]), uint32(len(hash)))
+	return subtle.ConstantTimeCompare(key, hash) == 1, nil
+}
+
+func (h *argon2idHasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+Argon2id+"$")
+}
+
+func (h *argon2idHasher) IsCurrent(encoded string) bool {
+	return strings.Contains(encoded, fmt.Sprintf("$v=%d$m=%d,t=%d,p=%d$", argon2.Version, h.def.Memory, h.def.Iterations, h.def.Parallelism))
+}
@@ -0,0 +1,50 @@
+package passwd
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptDef defines the bcrypt parameters.
+type BcryptDef struct {
+	Cost int
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+func newBcryptHasher(def *BcryptDef) *bcryptHasher {
+	if def == nil || def.Cost < bcrypt.MinCost {
+		return &bcryptHasher{12}
+	}
+	return &bcryptHasher{def.Cost}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(hash), err
+}
+
+func (h *bcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *bcryptHasher) Identifies(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *bcryptHasher) IsCurrent(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err == nil && cost == h.cost
+}
@@ -0,0 +1,135 @@
+package passwd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+)
+
+// Supported password hashing algorithms.
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+	Scrypt   = "scrypt"
+)
+
+// HashDef defines the algorithm and its parameters used to hash new passwords.
+type HashDef struct {
+	Algorithm string
+	Argon2id  *Argon2idDef
+	Bcrypt    *BcryptDef
+	Scrypt    *ScryptDef
+}
+
+// Hasher hashes passwords with one algorithm into PHC string formatted hashes.
+type Hasher interface {
+	// Hash creates an encoded hash of the password with a random salt.
+	Hash(password string) (string, error)
+	// Verify compares the password with an encoded hash in constant time.
+	Verify(password, encoded string) (bool, error)
+	// Identifies reports whether the encoded hash is created by the algorithm of the hasher.
+	Identifies(encoded string) bool
+	// IsCurrent reports whether the encoded hash uses the parameters of the hasher.
+	IsCurrent(encoded string) bool
+}
+
+// PasswordHasher hashes passwords with the configured algorithm and verifies
+// hashes of every supported algorithm, including legacy unsalted MD5 hashes.
+type PasswordHasher struct {
+	current Hasher
+	hashers []Hasher
+}
+
+// NewPasswordHasher creates a password hasher, argon2id is used by default.
+func NewPasswordHasher(def *HashDef) *PasswordHasher {
+	if def == nil {
+		def = &HashDef{}
+	}
+	hashers := []Hasher{
+		newArgon2idHasher(def.Argon2id),
+		newBcryptHasher(def.Bcrypt),
+		newScryptHasher(def.Scrypt),
+		new(md5Hasher),
+	}
+	var current Hasher
+	switch strings.ToLower(def.Algorithm) {
+	case "", Argon2id:
+		current = hashers[0]
+	case Bcrypt:
+		current = hashers[1]
+	case Scrypt:
+		current = hashers[2]
+	default:
+		log.Fatalf("unsupported password hashing algorithm: [%s]", def.Algorithm)
+	}
+	return &PasswordHasher{current, hashers}
+}
+
+// Hash hashes a password with the configured algorithm.
+func (ph *PasswordHasher) Hash(password string) (string, error) {
+	return ph.current.Hash(password)
+}
+
+// Verify compares a password with an encoded hash. It also reports whether the
+// hash should be replaced by a hash of the configured algorithm and parameters.
+func (ph *PasswordHasher) Verify(password, encoded string) (bool, bool, error) {
+	for _, hasher := range ph.hashers {
+		if !hasher.Identifies(encoded) {
+			continue
+		}
+		ok, err := hasher.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, hasher != ph.current || !hasher.IsCurrent(encoded), nil
+	}
+	return false, false, errors.New("unknown password hash format")
+}
+
+func salt(size int) ([]byte, error) {
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	return data, err
+}
+
+func b64(data []byte) string {
+	return base64.RawStdEncoding.EncodeToString(data)
+}
+
+// decodePHC splits a PHC string "$id$v=19$a=1,b=2$salt$hash" of the algorithm id.
+// The version part is optional.
+func decodePHC(id, encoded string) (map[string]int, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) == 6 {
+		parts = append(parts[:2], parts[3:]...)
+	}
+	if len(parts) != 5 || parts[0] != "" || parts[1] != id {
+		return nil, nil, nil, fmt.Errorf("invalid %s hash format", id)
+	}
+	params := make(map[string]int)
+	for _, param := range strings.Split(parts[2], ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, nil, nil, fmt.Errorf("invalid %s hash parameter: [%s]", id, param)
+		}
+		value, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid %s hash parameter: [%s]", id, param)
+		}
+		params[kv[0]] = value
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return params, salt, hash, nil
+}
@@ -0,0 +1,33 @@
+package passwd
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+)
+
+// md5Hasher verifies the legacy unsalted MD5 hashes. It never hashes new
+// passwords, verified MD5 hashes are always replaced.
+type md5Hasher struct{}
+
+func (h *md5Hasher) Hash(password string) (string, error) {
+	return "", errors.New("md5 password hashing is not supported")
+}
+
+func (h *md5Hasher) Verify(password, encoded string) (bool, error) {
+	sum := md5.Sum([]byte(password))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(encoded)) == 1, nil
+}
+
+func (h *md5Hasher) Identifies(encoded string) bool {
+	if len(encoded) != hex.EncodedLen(md5.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+func (h *md5Hasher) IsCurrent(encoded string) bool {
+	return false
+}
@@ -0,0 +1,77 @@
+package passwd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptDef defines the scrypt parameters.
+type ScryptDef struct {
+	LogN        int // CPU/memory cost as log2(N)
+	BlockSize   int
+	Parallelism int
+}
+
+const (
+	scryptSaltLen = 16
+	scryptKeyLen  = 32
+)
+
+type scryptHasher struct {
+	def *ScryptDef
+}
+
+func newScryptHasher(def *ScryptDef) *scryptHasher {
+	d := ScryptDef{LogN: 15, BlockSize: 8, Parallelism: 1}
+	if def != nil {
+		if def.LogN > 0 {
+			d.LogN = def.LogN
+		}
+		if def.BlockSize > 0 {
+			d.BlockSize = def.BlockSize
+		}
+		if def.Parallelism > 0 {
+			d.Parallelism = def.Parallelism
+		}
+	}
+	return &scryptHasher{&d}
+}
+
+func (h *scryptHasher) Hash(password string) (string, error) {
+	salt, err := salt(scryptSaltLen)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<h.def.LogN, h.def.BlockSize, h.def.Parallelism, scryptKeyLen)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("$%s$ln=%d,r=%d,p=%d$%s$%s",
+		Scrypt, h.def.LogN, h.def.BlockSize, h.def.Parallelism, b64(salt), b64(key)), nil
+}
+
+func (h *scryptHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, hash, err := decodePHC(Scrypt, encoded)
+	if err != nil {
+		return false, err
+	}
+	if params["ln"] <= 0 || params["ln"] > 30 {
+		return false, fmt.Errorf("invalid %s hash parameters", Scrypt)
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<params["ln"], params["r"], params["p"], len(hash))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, hash) == 1, nil
+}
+
+func (h *scryptHasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+Scrypt+"$")
+}
+
+func (h *scryptHasher) IsCurrent(encoded string) bool {
+	return strings.Contains(encoded, fmt.Sprintf("$ln=%d,r=%d,p=%d$", h.def.LogN, h.def.BlockSize, h.def.Parallelism))
+}
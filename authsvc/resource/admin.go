@@ -0,0 +1,41 @@
+package resource
+
+import (
+	"fmt"
+	"net/http"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	"github.com/parthoshuvo/authsvc/uc/token"
+)
+
+type AdminResource struct {
+	toknHndlr *token.Handler
+	rndr      render.Renderer
+}
+
+func NewAdminResource(toknHndlr *token.Handler, rndr render.Renderer) *AdminResource {
+	return &AdminResource{toknHndlr, rndr}
+}
+
+func (adrs *AdminResource) SigningKeyLister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := adrs.rndr.Render(w, adrs.toknHndlr.SigningKeys(), http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling signing keys [%v]", err))
+		}
+	}
+}
+
+func (adrs *AdminResource) SigningKeyRotator() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := adrs.toknHndlr.RotateSigningKeys()
+		if err != nil {
+			log.Errorf("signing key rotation error: [%v]", err)
+			sendISError(w, "failed to rotate signing keys")
+			return
+		}
+		if err := adrs.rndr.Render(w, keys, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling signing keys [%v]", err))
+		}
+	}
+}
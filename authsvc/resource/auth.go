@@ -9,8 +9,12 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/parthoshuvo/authsvc/email"
 	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/mfa"
+	"github.com/parthoshuvo/authsvc/passwd"
 	"github.com/parthoshuvo/authsvc/render"
 	usrTable "github.com/parthoshuvo/authsvc/table/user"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	ucmfa "github.com/parthoshuvo/authsvc/uc/mfa"
 	"github.com/parthoshuvo/authsvc/uc/token"
 	"github.com/parthoshuvo/authsvc/uc/user"
 )
@@ -18,19 +22,36 @@ import (
 type AuthResource struct {
 	usrHndlr    *user.Handler
 	toknHndlr   *token.Handler
+	mfaHndlr    *ucmfa.Handler
 	rndr        render.Renderer
 	validate    *validator.Validate
 	emailClient *email.EmailClient
+	pwdHasher   *passwd.PasswordHasher
 }
 
 func NewAuthResource(
 	usrHandlr *user.Handler,
 	toknHandlr *token.Handler,
+	mfaHandlr *ucmfa.Handler,
 	rndr render.Renderer,
 	validate *validator.Validate,
 	emailClient *email.EmailClient,
+	pwdHasher *passwd.PasswordHasher,
 ) *AuthResource {
-	return &AuthResource{usrHandlr, toknHandlr, rndr, validate, emailClient}
+	return &AuthResource{usrHandlr, toknHandlr, mfaHandlr, rndr, validate, emailClient, pwdHasher}
+}
+
+// mfaRequired answers the password step of the login of a user with
+// two-factor authentication.
+type mfaRequired struct {
+	MFARequired bool `json:"mfa_required"`
+	*toknSvc.MFAChallenge
+}
+
+// mfaLogin redeems an MFA challenge token with an authentication code.
+type mfaLogin struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
 }
 
 func (aurs *AuthResource) UserLogin() http.HandlerFunc {
@@ -44,7 +65,7 @@ func (aurs *AuthResource) UserLogin() http.HandlerFunc {
 		}
 
 		if err := aurs.validate.Struct(lusr); err != nil {
-			err = aurs.toCustomValidatorError(err)
+			err = toCustomValidatorError(err)
 			log.Errorf("validation error: [%s]", err.Error())
 			sendError(w, NewError(http.StatusBadRequest, err.Error()))
 			return
@@ -62,27 +83,184 @@ func (aurs *AuthResource) UserLogin() http.HandlerFunc {
 			sendError(w, NewError(http.StatusNotFound, err.Error()))
 			return
 		}
-		if !lusr.isAuthenticated(usr.Password) {
+		authenticated, rehash := lusr.isAuthenticated(aurs.pwdHasher, usr.Password)
+		if !authenticated {
 			err := errors.New("login failed, credentials mismatch")
 			log.Error(err.Error())
 			sendError(w, NewError(http.StatusUnauthorized, err.Error()))
 			return
 		}
+		if rehash {
+			rehashPassword(aurs.usrHndlr, aurs.pwdHasher, usr, lusr.Password)
+		}
+		if usr.Disabled {
+			err := fmt.Errorf("login failed, %s is disabled", usr.Email)
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusForbidden, err.Error()))
+			return
+		}
 		if !usr.Verified {
 			err := fmt.Errorf("login failed, %s is not verified", usr.Email)
 			log.Error(err.Error())
 			sendError(w, NewError(http.StatusForbidden, err.Error()))
 			return
 		}
+		if usr.TOTPEnabled {
+			challenge, err := aurs.toknHndlr.NewMFAChallenge(usr.Email.String())
+			if err != nil {
+				sendISError(w, fmt.Sprintf("error occurred while creating mfa challenge: [%v]", err))
+				return
+			}
+			if err := aurs.rndr.Render(w, &mfaRequired{true, challenge}, http.StatusOK); err != nil {
+				sendISError(w, fmt.Sprintf("error marshalling mfa challenge [%v]", err))
+			}
+			return
+		}
+		aurs.sendAuthTokenPair(w, rw, usr)
+	}
+}
 
-		toknPair, err := aurs.toknHndlr.NewAuthTokenPair(usr)
+// UserMFALogin completes the login of a user with two-factor authentication,
+// the MFA challenge token of the password step is redeemed with a code of
+// the authenticator app or a recovery code. The challenge is revoked after
+// too many wrong codes, codes of the user are refused for a while after too
+// many wrong codes over all of its challenges.
+func (aurs *AuthResource) UserMFALogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		data, err := rw.body()
 		if err != nil {
-			sendISError(w, fmt.Sprintf("error occurred while creating tokens: [%v]", err))
+			sendISError(w, fmt.Sprintf("error reading mfa login [%v]", err))
 			return
 		}
-		if err := aurs.rndr.Render(w, toknPair, http.StatusOK); err != nil {
-			sendISError(w, fmt.Sprintf("error marshalling tokens [%v]", err))
+		lgn := mfaLogin{}
+		if err := unmarshall(data, &lgn); err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling mfa login [%v]", err))
+			return
+		}
+		if lgn.MFAToken == "" || lgn.Code == "" {
+			err := errors.New("mfa_token and code are required")
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+
+		login, err := aurs.toknHndlr.MFAChallengeLogin(lgn.MFAToken)
+		if err == toknSvc.ErrMFAChallengeInvalid {
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusUnauthorized, err.Error()))
+			return
+		}
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on reading mfa challenge", err))
+			return
+		}
+		usr, err := aurs.usrHndlr.ReadUserByLogin(login)
+		if err != nil {
+			log.Errorf("user fetching error: [%s]", err.Error())
+			sendISError(w, "user fetching error")
+			return
+		}
+		if usr == nil || usr.Disabled {
+			err := fmt.Errorf("login failed, %s doesn't exists or is disabled", login)
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusForbidden, err.Error()))
+			return
+		}
+
+		if err := aurs.toknHndlr.CheckMFALogin(login); err != nil {
+			if err != toknSvc.ErrMFALocked {
+				sendISError(w, fmt.Sprintf("error [%v] occurred on checking mfa failures of user: [%s]", err, login))
+				return
+			}
+			log.Errorf("login failed, mfa of user: [%s] is locked", login)
+			sendError(w, NewError(http.StatusTooManyRequests, "login failed, "+err.Error()))
+			return
 		}
+		if err := aurs.mfaHndlr.VerifyCode(usr, lgn.Code); err != nil {
+			if err != mfa.ErrInvalidCode {
+				sendISError(w, fmt.Sprintf("error [%v] occurred on verifying code of user: [%s]", err, login))
+				return
+			}
+			if err := aurs.toknHndlr.FailMFAChallenge(lgn.MFAToken); err != nil {
+				log.Errorf("error [%v] occurred on counting failed mfa attempt of user: [%s]", err, login)
+			}
+			if err := aurs.toknHndlr.FailMFALogin(login); err != nil {
+				log.Errorf("error [%v] occurred on counting failed mfa attempt of user: [%s]", err, login)
+			}
+			log.Errorf("login failed, invalid authentication code of user: [%s]", login)
+			sendError(w, NewError(http.StatusUnauthorized, "login failed, "+err.Error()))
+			return
+		}
+		if err := aurs.toknHndlr.CompleteMFAChallenge(lgn.MFAToken); err != nil {
+			if err != toknSvc.ErrMFAChallengeInvalid {
+				sendISError(w, fmt.Sprintf("error [%v] occurred on completing mfa challenge", err))
+				return
+			}
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusUnauthorized, err.Error()))
+			return
+		}
+		if err := aurs.toknHndlr.ResetMFALogin(login); err != nil {
+			log.Errorf("error [%v] occurred on resetting mfa failures of user: [%s]", err, login)
+		}
+		aurs.sendAuthTokenPair(w, rw, usr)
+	}
+}
+
+// sendAuthTokenPair responds with the token pair of a new session of user.
+func (aurs *AuthResource) sendAuthTokenPair(w http.ResponseWriter, rw *wrapper, usr *usrTable.User) {
+	toknPair, err := aurs.toknHndlr.NewAuthTokenPair(usr, rw.device())
+	if err != nil {
+		sendISError(w, fmt.Sprintf("error occurred while creating tokens: [%v]", err))
+		return
+	}
+	if err := aurs.rndr.Render(w, toknPair, http.StatusOK); err != nil {
+		sendISError(w, fmt.Sprintf("error marshalling tokens [%v]", err))
+	}
+}
+
+// UserLogout revokes the refresh token of the request body and denies the
+// bearer access token, if any, until it expires.
+func (aurs *AuthResource) UserLogout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		refreshToken, err := unmarshallRefreshToken(rw)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling refresh token [%v]", err))
+			return
+		}
+		if refreshToken == "" {
+			err = errors.New("refresh token is empty")
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		refreshClaims, err := aurs.toknHndlr.VerifyRefreshToken(refreshToken)
+		if err != nil {
+			log.Errorf("Invalid token: [%s], error: [%v]", refreshToken, err)
+			sendError(w, NewError(http.StatusUnauthorized, "Refresh token has expired or is not yet valid."))
+			return
+		}
+		if err := aurs.toknHndlr.RevokeRefreshToken(refreshToken); err != nil {
+			log.Errorf("failed to revoke refresh token: [%v]", err)
+			sendISError(w, "failed to revoke refresh token")
+			return
+		}
+
+		if accessToken, err := rw.bearerAuth(); err == nil {
+			accessClaims, err := aurs.toknHndlr.VerifyAccessToken(accessToken)
+			if err == nil && accessClaims.SessionID == refreshClaims.SessionID {
+				if err := aurs.toknHndlr.RevokeAccessToken(accessToken); err != nil {
+					log.Errorf("failed to revoke access token: [%v]", err)
+					sendISError(w, "failed to revoke access token")
+					return
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
@@ -97,7 +275,7 @@ func (aurs *AuthResource) UserRegistration() http.HandlerFunc {
 		}
 
 		if err := aurs.validate.Struct(usr); err != nil {
-			err = aurs.toCustomValidatorError(err)
+			err = toCustomValidatorError(err)
 			log.Errorf("validation error: [%s]", err.Error())
 			sendError(w, NewError(http.StatusBadRequest, err.Error()))
 			return
@@ -116,7 +294,12 @@ func (aurs *AuthResource) UserRegistration() http.HandlerFunc {
 			return
 		}
 
-		usr.Password = usr.Password.Hash()
+		hash, err := aurs.pwdHasher.Hash(string(usr.Password))
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error hashing password: [%v]", err))
+			return
+		}
+		usr.Password = usrTable.Password(hash)
 		newUsr, err := aurs.usrHndlr.InsertUser(usr)
 		if err != nil {
 			sendISError(w, fmt.Sprintf("error creating slurpy user: [%v]", err))
@@ -180,7 +363,19 @@ func (aurs *AuthResource) EmailVerifier() http.HandlerFunc {
 	}
 }
 
-func (ar *AuthResource) toCustomValidatorError(err error) error {
+// rehashPassword replaces a legacy or outdated password hash of user. A failure
+// doesn't fail the login, the hash is replaced on a later login.
+func rehashPassword(usrHndlr *user.Handler, hasher *passwd.PasswordHasher, usr *usrTable.User, password usrTable.Password) {
+	hash, err := hasher.Hash(string(password))
+	if err == nil {
+		err = usrHndlr.AssignUserPassword(usr.Email.String(), usrTable.Password(hash))
+	}
+	if err != nil {
+		log.Errorf("failed to rehash password of user %s: [%v]", usr.Email, err)
+	}
+}
+
+func toCustomValidatorError(err error) error {
 	fieldErrors, ok := err.(validator.ValidationErrors)
 	if !ok {
 		log.Errorf("Failed to convert to ValidationErrors")
@@ -196,8 +391,14 @@ func (ar *AuthResource) toCustomValidatorError(err error) error {
 		msg = append(msg, "must contain alphabetical characters")
 	case "validPwd":
 		msg = append(msg, "must contain alpha numeric characters, any of special charaters: _!@$%")
+	case "validPerm":
+		msg = append(msg, "must have the form resource:action[:scope] without empty segments or white space")
 	case "email":
 		msg = append(msg, "must contain valid email address")
+	case "url":
+		msg = append(msg, "must contain a valid URL")
+	case "oneof":
+		msg = append(msg, fmt.Sprintf("must be one of: %s", fieldErr.Param()))
 	case "min":
 		msg = append(msg, fmt.Sprintf("at least %s characters", fieldErr.Param()))
 	case "max":
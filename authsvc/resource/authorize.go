@@ -0,0 +1,78 @@
+package resource
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/parthoshuvo/authsvc/authz"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	"github.com/parthoshuvo/authsvc/uc/permission"
+	"github.com/parthoshuvo/authsvc/uc/token"
+)
+
+// AuthorizationResource answers authorization checks of resource servers.
+type AuthorizationResource struct {
+	toknHndlr *token.Handler
+	permHndlr *permission.Handler
+	rndr      render.Renderer
+}
+
+func NewAuthorizationResource(toknHndlr *token.Handler, permHndlr *permission.Handler, rndr render.Renderer) *AuthorizationResource {
+	return &AuthorizationResource{toknHndlr, permHndlr, rndr}
+}
+
+type authorizationRequest struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+type authorizationDecision struct {
+	Permission string `json:"permission"`
+	Allowed    bool   `json:"allowed"`
+}
+
+// Authorizer checks whether the user of the bearer access token may perform
+// an action on a resource, optionally within a scope.
+func (azrs *AuthorizationResource) Authorizer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		claims := authenticate(azrs.toknHndlr, w, rw)
+		if claims == nil {
+			return
+		}
+		data, err := rw.body()
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error reading authorization request [%v]", err))
+			return
+		}
+		req := authorizationRequest{}
+		if err := unmarshall(data, &req); err != nil {
+			sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("error unmarshalling authorization request [%v]", err)))
+			return
+		}
+		required, err := authz.New(req.Resource, req.Action, req.Scope)
+		if err != nil {
+			log.Errorf("validation error: [%s]", err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+
+		perms, err := azrs.permHndlr.ReadUserPermissions(claims.Subject())
+		if err != nil {
+			log.Errorf("permission fetching error: [%s]", err.Error())
+			sendISError(w, "permission fetching error")
+			return
+		}
+		names := make([]string, 0, len(perms))
+		for _, perm := range perms {
+			names = append(names, perm.Name)
+		}
+		decision := &authorizationDecision{required.String(), authz.NewSet(names).Implies(required)}
+		if err := azrs.rndr.Render(w, decision, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling authorization decision [%v]", err))
+		}
+	}
+}
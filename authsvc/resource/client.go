@@ -0,0 +1,177 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	clntTable "github.com/parthoshuvo/authsvc/table/client"
+	"github.com/parthoshuvo/authsvc/uc/client"
+)
+
+// ClientResource handles the administration of OAuth clients.
+type ClientResource struct {
+	clntHndlr *client.Handler
+	rndr      render.Renderer
+	validate  *validator.Validate
+}
+
+func NewClientResource(clntHndlr *client.Handler, rndr render.Renderer, validate *validator.Validate) *ClientResource {
+	return &ClientResource{clntHndlr, rndr, validate}
+}
+
+// clientCredentials is a client with its generated secret, the secret is
+// rendered only once.
+type clientCredentials struct {
+	*clntTable.Client
+	Secret string `json:"client_secret,omitempty"`
+}
+
+func (clrs *ClientResource) ClientLister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		clnts, err := clrs.clntHndlr.ReadClients()
+		if err != nil {
+			log.Errorf("client fetching error: [%s]", err.Error())
+			sendISError(w, "client fetching error")
+			return
+		}
+		if err := clrs.rndr.Render(w, clnts, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling clients [%v]", err))
+		}
+	}
+}
+
+func (clrs *ClientResource) ClientGetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		clnt, err := clrs.clntHndlr.ReadClient(reqmuxv(r, "id"))
+		if err != nil {
+			log.Errorf("client fetching error: [%s]", err.Error())
+			sendISError(w, "client fetching error")
+			return
+		}
+		if clnt == nil {
+			sendError(w, NewError(http.StatusNotFound, clntTable.ErrClientNotFound.Error()))
+			return
+		}
+		if err := clrs.rndr.Render(w, clnt, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling client [%v]", err))
+		}
+	}
+}
+
+// ClientCreator registers a client with a generated client ID. The generated
+// secret of a confidential client is rendered in the response only.
+func (clrs *ClientResource) ClientCreator() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		clnt, err := clrs.unmarshallClient(w, requestWrapper(r))
+		if err != nil {
+			return
+		}
+		clnt, secret, err := clrs.clntHndlr.CreateClient(clnt)
+		if err != nil {
+			sendClientError(w, err)
+			return
+		}
+		if err := clrs.rndr.Render(w, &clientCredentials{clnt, secret}, http.StatusCreated); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling client [%v]", err))
+		}
+	}
+}
+
+// ClientUpdater changes the registration of a client, whether the client is
+// public can't be changed.
+func (clrs *ClientResource) ClientUpdater() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		clnt, err := clrs.unmarshallClient(w, requestWrapper(r))
+		if err != nil {
+			return
+		}
+		clnt.ID = reqmuxv(r, "id")
+		if err := clrs.clntHndlr.UpdateClient(clnt); err != nil {
+			sendClientError(w, err)
+			return
+		}
+		if err := clrs.rndr.Render(w, clnt, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling client [%v]", err))
+		}
+	}
+}
+
+// ClientSecretRotator generates a new secret of a confidential client.
+func (clrs *ClientResource) ClientSecretRotator() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		clientID := reqmuxv(r, "id")
+		secret, err := clrs.clntHndlr.RotateClientSecret(clientID)
+		if err != nil {
+			sendClientError(w, err)
+			return
+		}
+		clnt, err := clrs.clntHndlr.ReadClient(clientID)
+		if err != nil || clnt == nil {
+			log.Errorf("client fetching error: [%v]", err)
+			sendISError(w, "client fetching error")
+			return
+		}
+		if err := clrs.rndr.Render(w, &clientCredentials{clnt, secret}, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling client [%v]", err))
+		}
+	}
+}
+
+// ClientDeleter deletes a client, its tokens can't be refreshed or
+// introspected any more.
+func (clrs *ClientResource) ClientDeleter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		if err := clrs.clntHndlr.DeleteClient(reqmuxv(r, "id")); err != nil {
+			sendClientError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// unmarshallClient reads and validates the client of the request body, a
+// failure is sent to the client.
+func (clrs *ClientResource) unmarshallClient(w http.ResponseWriter, rw *wrapper) (*clntTable.Client, error) {
+	data, err := rw.body()
+	if err != nil {
+		sendISError(w, fmt.Sprintf("error reading client [%v]", err))
+		return nil, err
+	}
+	clnt := clntTable.Client{}
+	if err := unmarshall(data, &clnt); err != nil {
+		sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("error unmarshalling client [%v]", err)))
+		return nil, err
+	}
+	if err := clrs.validate.Struct(&clnt); err != nil {
+		err = toCustomValidatorError(err)
+		log.Errorf("validation error: [%s]", err.Error())
+		sendError(w, NewError(http.StatusBadRequest, err.Error()))
+		return nil, err
+	}
+	return &clnt, nil
+}
+
+// sendClientError sends a client store error with its status to the client.
+func sendClientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, clntTable.ErrClientNotFound):
+		sendError(w, NewError(http.StatusNotFound, err.Error()))
+	case errors.Is(err, clntTable.ErrClientExists):
+		sendError(w, NewError(http.StatusConflict, err.Error()))
+	case errors.Is(err, clntTable.ErrClientInvalid):
+		sendError(w, NewError(http.StatusBadRequest, err.Error()))
+	default:
+		log.Errorf("client store error: [%v]", err)
+		sendISError(w, "client store error")
+	}
+}
@@ -2,10 +2,20 @@ package resource
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/gorilla/mux"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/passwd"
 	usrTable "github.com/parthoshuvo/authsvc/table/user"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	"github.com/parthoshuvo/authsvc/uc/token"
+	"github.com/parthoshuvo/authsvc/uc/user"
 )
 
 type LoginUser struct {
@@ -13,8 +23,15 @@ type LoginUser struct {
 	Password usrTable.Password `json:"password" validate:"required,validPwd,min=8,max=64"`
 }
 
-func (lusr *LoginUser) isAuthenticated(password usrTable.Password) bool {
-	return lusr.Password.Hash().Equals(password)
+// isAuthenticated verifies the login password against the stored password hash
+// in constant time. It also reports whether the stored hash needs a rehash.
+func (lusr *LoginUser) isAuthenticated(hasher *passwd.PasswordHasher, password usrTable.Password) (bool, bool) {
+	ok, rehash, err := hasher.Verify(string(lusr.Password), string(password))
+	if err != nil {
+		log.Errorf("password verification error of user %s: [%v]", lusr.Email, err)
+		return false, false
+	}
+	return ok, rehash
 }
 
 type wrapper struct {
@@ -34,6 +51,21 @@ func (w *wrapper) host() string {
 	return w.req.Host
 }
 
+// device identifies the user agent and the client IP of the request.
+func (w *wrapper) device() *toknSvc.Device {
+	return &toknSvc.Device{UserAgent: w.req.UserAgent(), IP: w.clientIP()}
+}
+
+// clientIP provides the IP of the client, the route builder resolves the
+// remote address of requests forwarded by a trusted proxy.
+func (w *wrapper) clientIP() string {
+	host, _, err := net.SplitHostPort(w.req.RemoteAddr)
+	if err != nil {
+		return w.req.RemoteAddr
+	}
+	return host
+}
+
 func (w *wrapper) email() string {
 	return reqmuxq(w.req, "email")
 }
@@ -42,6 +74,43 @@ func (w *wrapper) verificationCode() string {
 	return reqmuxq(w.req, "verification_code")
 }
 
+func (w *wrapper) sessionID() string {
+	return reqmuxv(w.req, "id")
+}
+
+func (w *wrapper) credentialID() string {
+	return reqmuxv(w.req, "id")
+}
+
+// intVar provides a positive integer path variable e.g. an ID.
+func (w *wrapper) intVar(name string) (int, error) {
+	v, err := strconv.Atoi(reqmuxv(w.req, name))
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid %s: [%s]", name, reqmuxv(w.req, name))
+	}
+	return v, nil
+}
+
+func (w *wrapper) formValue(name string) string {
+	return w.req.PostFormValue(name)
+}
+
+// formValues reads all values of a repeated form parameter.
+func (w *wrapper) formValues(name string) []string {
+	w.req.ParseForm()
+	return w.req.PostForm[name]
+}
+
+// clientCredentials reads the client credentials from the basic authorization
+// header or else from the client_id and client_secret form parameters.
+func (w *wrapper) clientCredentials() (string, string, bool) {
+	if clientID, secret, ok := w.req.BasicAuth(); ok {
+		return clientID, secret, true
+	}
+	clientID, secret := w.formValue("client_id"), w.formValue("client_secret")
+	return clientID, secret, clientID != ""
+}
+
 func (w *wrapper) loginUser() (*LoginUser, error) {
 	data, err := w.body()
 	if err != nil {
@@ -55,22 +124,26 @@ func (w *wrapper) loginUser() (*LoginUser, error) {
 	return &lusr, nil
 }
 
-// func (w *wrapper) bearerAuth() (string, error) {
-// 	header := w.req.Header.Get("Authorization")
-// 	if header == "" {
-// 		return "", fmt.Errorf("missing authorization header")
-// 	}
-// 	const authScheme = "Bearer"
-// 	if !strings.HasPrefix(header, authScheme) {
-// 		return "", fmt.Errorf("missing bearer auth scheme at authorization header")
-// 	}
-// 	return header[len(authScheme)+1:], nil
-// }
+func (w *wrapper) bearerAuth() (string, error) {
+	header := w.req.Header.Get("Authorization")
+	if header == "" {
+		return "", fmt.Errorf("missing authorization header")
+	}
+	const authScheme = "Bearer"
+	if !strings.HasPrefix(header, authScheme+" ") {
+		return "", fmt.Errorf("missing bearer auth scheme at authorization header")
+	}
+	return header[len(authScheme)+1:], nil
+}
 
 func reqmuxq(r *http.Request, name string) string {
 	return r.URL.Query().Get(name)
 }
 
+func reqmuxv(r *http.Request, name string) string {
+	return mux.Vars(r)[name]
+}
+
 func reqmuxb(r *http.Request) ([]byte, error) {
 	defer r.Body.Close()
 	return ioutil.ReadAll(r.Body)
@@ -79,3 +152,41 @@ func reqmuxb(r *http.Request) ([]byte, error) {
 func unmarshall(data []byte, v interface{}) error {
 	return json.Unmarshal(data, v)
 }
+
+// authenticate verifies the bearer access token, a failure is sent to the client.
+func authenticate(toknHndlr *token.Handler, w http.ResponseWriter, rw *wrapper) *toknSvc.JWTCustomClaims {
+	accessToken, err := rw.bearerAuth()
+	if err != nil {
+		sendBearerAuthError(w, "", err.Error())
+		return nil
+	}
+	claims, err := toknHndlr.VerifyAccessToken(accessToken)
+	if err != nil {
+		log.Errorf("Invalid token: [%s], error: [%v]", accessToken, err)
+		sendBearerAuthError(w, "invalid_token", "Access token has expired or is not yet valid.")
+		return nil
+	}
+	return claims
+}
+
+// authenticatedUser reads the user of the bearer access token of self-service
+// requests.
+func authenticatedUser(usrHndlr *user.Handler, toknHndlr *token.Handler, w http.ResponseWriter, rw *wrapper) *usrTable.User {
+	claims := authenticate(toknHndlr, w, rw)
+	if claims == nil {
+		return nil
+	}
+	usr, err := usrHndlr.ReadUserByLogin(claims.Subject())
+	if err != nil {
+		log.Errorf("user fetching error: [%s]", err.Error())
+		sendISError(w, "user fetching error")
+		return nil
+	}
+	if usr == nil || usr.RowGUID != claims.ID {
+		err := fmt.Errorf("user: %s doesn't exists", claims.Subject())
+		log.Error(err.Error())
+		sendError(w, NewError(http.StatusNotFound, err.Error()))
+		return nil
+	}
+	return usr
+}
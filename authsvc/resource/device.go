@@ -0,0 +1,290 @@
+package resource
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	clntTable "github.com/parthoshuvo/authsvc/table/client"
+	usrTable "github.com/parthoshuvo/authsvc/table/user"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+)
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// devicePageData is rendered by the verification page, the user code is asked
+// first and the login and consent of its client next.
+type devicePageData struct {
+	UserCode   string
+	ClientName string
+	Scopes     []string
+	Email      string
+	CSRFToken  string
+	Error      string
+	Message    string
+}
+
+// DeviceAuthorizer issues the device code and the user code of a device
+// authorization request (RFC 8628 section 3.1). The client polls the token
+// endpoint with the device code while the user enters the user code at the
+// verification URI.
+func (oars *OAuthResource) DeviceAuthorizer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		clnt := oars.authenticateClient(w, rw)
+		if clnt == nil {
+			return
+		}
+		if !clnt.HasGrant(clntTable.GrantDeviceCode) {
+			log.Errorf("client: %s isn't registered for grant_type: [%s]", clnt.ID, clntTable.GrantDeviceCode)
+			sendOAuthError(w, http.StatusBadRequest, "unauthorized_client", "the client may not use the device authorization grant")
+			return
+		}
+		scope, err := clientScope(clnt, rw.formValue("scope"))
+		if err != nil {
+			oerr := err.(*oauthError)
+			sendOAuthError(w, http.StatusBadRequest, oerr.code, oerr.description)
+			return
+		}
+		deviceCode, grant, err := oars.toknHndlr.NewDeviceAuthorization(&toknSvc.Authorization{ClientID: clnt.ID, Scope: scope, Audience: clnt.Audience})
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on creating device authorization", err))
+			return
+		}
+		verificationURI := oars.baseURL + "/oauth/device"
+		userCode := toknSvc.FormatUserCode(grant.UserCode)
+		resp := &deviceAuthorizationResponse{
+			DeviceCode:              deviceCode,
+			UserCode:                userCode,
+			VerificationURI:         verificationURI,
+			VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {userCode}}.Encode(),
+			ExpiresIn:               int64(grant.ExpiresIn().Seconds()),
+			Interval:                int64(toknSvc.DevicePollInterval.Seconds()),
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+		if err := oars.rndr.Render(w, resp, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling device authorization [%v]", err))
+		}
+	}
+}
+
+// DeviceVerifier renders the verification page. Without a valid user code the
+// user is asked for it, otherwise the login and consent of its client is
+// rendered.
+func (oars *OAuthResource) DeviceVerifier() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		page := &devicePageData{UserCode: r.URL.Query().Get("user_code")}
+		if page.UserCode == "" {
+			renderDevicePage(w, http.StatusOK, page)
+			return
+		}
+		grant, ok := oars.pendingDeviceGrant(w, r, page)
+		if !ok {
+			return
+		}
+		clnt, err := oars.clntHndlr.ReadClient(grant.ClientID)
+		if err != nil || clnt == nil {
+			log.Errorf("client fetching error: [%v]", err)
+			sendISError(w, "client fetching error")
+			return
+		}
+		csrfToken, err := newCSRFToken()
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on creating csrf token", err))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     csrfCookieName,
+			Value:    csrfToken,
+			Path:     r.URL.Path,
+			HttpOnly: true,
+			Secure:   isSecure(r),
+			SameSite: http.SameSiteStrictMode,
+		})
+		page.UserCode = toknSvc.FormatUserCode(grant.UserCode)
+		page.ClientName = clnt.Name
+		page.Scopes = strings.Fields(grant.Scope)
+		page.CSRFToken = csrfToken
+		renderDevicePage(w, http.StatusOK, page)
+	}
+}
+
+// DeviceGranter authenticates the user of the verification page and records
+// the approval or denial of the device grant, the device learns it on its
+// next poll.
+func (oars *OAuthResource) DeviceGranter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		if err := r.ParseForm(); err != nil {
+			sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("error parsing form [%v]", err)))
+			return
+		}
+		cookie, err := r.Cookie(csrfCookieName)
+		csrfToken := r.PostForm.Get("csrf_token")
+		if err != nil || csrfToken == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(csrfToken)) != 1 {
+			log.Errorf("csrf token mismatch of device verification of user code: %s", r.PostForm.Get("user_code"))
+			sendError(w, NewError(http.StatusForbidden, "Login form is expired, please enter the code again."))
+			return
+		}
+		page := &devicePageData{UserCode: r.PostForm.Get("user_code")}
+		grant, ok := oars.pendingDeviceGrant(w, r, page)
+		if !ok {
+			return
+		}
+		if r.PostForm.Get("action") != "approve" {
+			if err := oars.toknHndlr.DenyDeviceGrant(grant); err != nil {
+				sendISError(w, fmt.Sprintf("error [%v] occurred on denying device grant", err))
+				return
+			}
+			renderDevicePage(w, http.StatusOK, &devicePageData{Message: "Access is denied, the device isn't signed in."})
+			return
+		}
+
+		clnt, err := oars.clntHndlr.ReadClient(grant.ClientID)
+		if err != nil || clnt == nil {
+			log.Errorf("client fetching error: [%v]", err)
+			sendISError(w, "client fetching error")
+			return
+		}
+		page.ClientName = clnt.Name
+		page.Scopes = strings.Fields(grant.Scope)
+		page.Email = r.PostForm.Get("email")
+		page.CSRFToken = csrfToken
+		usr, err := oars.authenticateUser(page.Email, usrTable.Password(r.PostForm.Get("password")), r.PostForm.Get("totp_code"))
+		if err != nil {
+			var serr *AuthSvcError
+			if !errors.As(err, &serr) {
+				sendISError(w, err.Error())
+				return
+			}
+			log.Error(serr.Error())
+			page.Error = serr.Error()
+			renderDevicePage(w, serr.Status, page)
+			return
+		}
+		if err := oars.toknHndlr.ApproveDeviceGrant(grant, usr.Email.String()); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on approving device grant", err))
+			return
+		}
+		renderDevicePage(w, http.StatusOK, &devicePageData{Message: fmt.Sprintf("%s is signed in, you may return to your device.", clnt.Name)})
+	}
+}
+
+// exchangeDeviceCode issues the tokens of an approved device grant, pending
+// grants are answered with authorization_pending or slow_down.
+func (oars *OAuthResource) exchangeDeviceCode(clnt *clntTable.Client, rw *wrapper) (*oauthTokenResponse, error) {
+	deviceCode := rw.formValue("device_code")
+	if deviceCode == "" {
+		return nil, newOAuthError("invalid_request", "device_code is required")
+	}
+	grant, err := oars.toknHndlr.PollDeviceGrant(deviceCode, clnt.ID)
+	switch {
+	case errors.Is(err, toknSvc.ErrAuthorizationPending):
+		return nil, newOAuthError("authorization_pending", err.Error())
+	case errors.Is(err, toknSvc.ErrSlowDown):
+		return nil, newOAuthError("slow_down", err.Error())
+	case errors.Is(err, toknSvc.ErrAccessDenied):
+		return nil, newOAuthError("access_denied", err.Error())
+	case errors.Is(err, toknSvc.ErrExpiredToken):
+		return nil, newOAuthError("expired_token", err.Error())
+	case errors.Is(err, toknSvc.ErrInvalidGrant):
+		log.Errorf("device code exchange of client: %s failed: [%v]", clnt.ID, err)
+		return nil, newOAuthError("invalid_grant", "device code is invalid or already used")
+	case err != nil:
+		return nil, err
+	}
+	usr, err := oars.activeUser(grant.Login)
+	if err != nil {
+		return nil, err
+	}
+	authz := &toknSvc.Authorization{ClientID: clnt.ID, Scope: grant.Scope, Audience: grant.Audience}
+	pair, err := oars.toknHndlr.NewClientTokenPair(usr, authz, rw.device())
+	if err != nil {
+		return nil, err
+	}
+	resp := newOAuthTokenResponse(pair, grant.Scope)
+	if toknSvc.HasScope(grant.Scope, toknSvc.ScopeOpenID) {
+		if resp.IDToken, err = oars.toknHndlr.NewIDToken(usr, grant, pair.AccessToken); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// pendingDeviceGrant fetches the undecided grant of the user code of the page,
+// an unknown or expired code or a client IP with too many wrong codes is
+// rendered with an error.
+func (oars *OAuthResource) pendingDeviceGrant(w http.ResponseWriter, r *http.Request, page *devicePageData) (*toknSvc.DeviceGrant, bool) {
+	ip := requestWrapper(r).clientIP()
+	grant, err := oars.toknHndlr.PendingDeviceGrant(page.UserCode, ip)
+	if errors.Is(err, toknSvc.ErrUserCodeLocked) {
+		log.Errorf("user code lookups of ip: [%s] are locked", ip)
+		page.Error = "Too many wrong codes, please try again later."
+		renderDevicePage(w, http.StatusTooManyRequests, page)
+		return nil, false
+	}
+	if err != nil {
+		sendISError(w, fmt.Sprintf("error [%v] occurred on fetching device grant", err))
+		return nil, false
+	}
+	if grant == nil {
+		log.Errorf("user code: [%s] is unknown or expired", page.UserCode)
+		page.Error = "The code is wrong or expired, please check the code shown on your device."
+		renderDevicePage(w, http.StatusBadRequest, page)
+		return nil, false
+	}
+	return grant, true
+}
+
+func renderDevicePage(w http.ResponseWriter, status int, data *devicePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := devicePage.Execute(w, data); err != nil {
+		log.Errorf("error rendering device page: [%v]", err)
+	}
+}
+
+var devicePage = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>Sign in a device</title></head>
+<body>
+<h1>Sign in a device</h1>
+{{if .Message}}<p>{{.Message}}</p>
+{{else}}{{if .Error}}<p style="color:red">{{.Error}}</p>{{end}}
+{{if .CSRFToken}}<form method="post">
+<input type="hidden" name="user_code" value="{{.UserCode}}">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<p>Code <strong>{{.UserCode}}</strong> signs in {{.ClientName}}.</p>
+<p><label>Email <input type="email" name="email" value="{{.Email}}" autocomplete="username"></label></p>
+<p><label>Password <input type="password" name="password" autocomplete="current-password"></label></p>
+<p><label>Authentication code <input type="text" name="totp_code" autocomplete="one-time-code"></label> (two-factor authentication only, or a recovery code)</p>
+{{if .Scopes}}<p>{{.ClientName}} requests access to:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<button type="submit" name="action" value="approve">Allow</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>
+{{else}}<form method="get">
+<p><label>Enter the code shown on your device <input type="text" name="user_code" value="{{.UserCode}}" autocomplete="off" autocapitalize="characters"></label></p>
+<button type="submit">Continue</button>
+</form>{{end}}{{end}}
+</body>
+</html>
+`))
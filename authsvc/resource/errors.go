@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -46,9 +47,64 @@ func sendError(w http.ResponseWriter, err error) {
 	http.Error(w, serr.Error(), serr.Status)
 }
 
+// sendClientAuthError sends a StatusUnauthorized to a client that failed to
+// authenticate and challenges it for basic authentication.
+func sendClientAuthError(w http.ResponseWriter, err error) {
+	log.Error(err.Error())
+	w.Header().Set("WWW-Authenticate", `Basic realm="authsvc"`)
+	sendError(w, NewError(http.StatusUnauthorized, err.Error()))
+}
+
+// sendBearerAuthError sends a StatusUnauthorized to a client that failed to
+// authenticate with a bearer access token (RFC 6750).
+func sendBearerAuthError(w http.ResponseWriter, code, msg string) {
+	log.Error(msg)
+	challenge := `Bearer realm="authsvc"`
+	if code != "" {
+		challenge += fmt.Sprintf(`, error="%s"`, code)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	sendError(w, NewError(http.StatusUnauthorized, msg))
+}
+
+// sendInsufficientScopeError rejects a bearer token lacking the scope (RFC 6750 section 3.1).
+func sendInsufficientScopeError(w http.ResponseWriter, scope, msg string) {
+	log.Error(msg)
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="authsvc", error="insufficient_scope", scope="%s"`, scope))
+	sendError(w, NewError(http.StatusForbidden, msg))
+}
+
 func toAuthSvcError(err error) *AuthSvcError {
 	if terr, ok := err.(*AuthSvcError); ok {
 		return terr
 	}
 	return NewError(http.StatusInternalServerError, err.Error())
 }
+
+// oauthError is an error response of the OAuth 2.0 token endpoint (RFC 6749
+// section 5.2).
+type oauthError struct {
+	code        string
+	description string
+}
+
+func newOAuthError(code, description string) *oauthError {
+	return &oauthError{code, description}
+}
+
+func (e *oauthError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.description)
+}
+
+// sendOAuthError sends an OAuth 2.0 error response, clients failing to
+// authenticate are challenged for basic authentication.
+func sendOAuthError(w http.ResponseWriter, status int, code, description string) {
+	log.Errorf("oauth error: %s, %s", code, description)
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="authsvc"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
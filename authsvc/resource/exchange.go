@@ -0,0 +1,125 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	clntTable "github.com/parthoshuvo/authsvc/table/client"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+)
+
+// exchangeToken exchanges the access token of a subject for an access token of
+// another audience with a narrower scope (RFC 8693), e.g. a gateway calling
+// internal services on behalf of a user. The subject of an actor token is
+// recorded as the actor of the issued token.
+func (oars *OAuthResource) exchangeToken(clnt *clntTable.Client, rw *wrapper) (*oauthTokenResponse, error) {
+	if tokenType := rw.formValue("requested_token_type"); tokenType != "" && tokenType != toknSvc.TokenTypeAccessToken {
+		return nil, newOAuthError("invalid_request", fmt.Sprintf("requested_token_type: [%s] is not supported", tokenType))
+	}
+	subject, err := oars.verifyExchangedToken(clnt, rw, "subject_token")
+	if err != nil {
+		return nil, err
+	}
+	if subject == nil {
+		return nil, newOAuthError("invalid_request", "subject_token is required")
+	}
+	actor, err := oars.verifyExchangedToken(clnt, rw, "actor_token")
+	if err != nil {
+		return nil, err
+	}
+	audience, err := exchangeAudience(clnt, rw.formValues("audience"), oars.toknHndlr.Issuer())
+	if err != nil {
+		return nil, err
+	}
+	scope, err := exchangeScope(clnt, subject, rw.formValue("scope"))
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := oars.toknHndlr.ExchangeToken(&toknSvc.TokenExchange{
+		Subject:  subject,
+		Actor:    actor,
+		ClientID: clnt.ID,
+		Scope:    scope,
+		Audience: audience,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &oauthTokenResponse{
+		AccessToken:     accessToken.String(),
+		IssuedTokenType: toknSvc.TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(accessToken.Expires().Seconds()),
+		Scope:           scope,
+	}, nil
+}
+
+// verifyExchangedToken verifies the access token of the form parameter and
+// its token type, nil if the parameter is absent. The token must be issued for
+// authsvc or the audience of the exchanging client.
+func (oars *OAuthResource) verifyExchangedToken(clnt *clntTable.Client, rw *wrapper, param string) (*toknSvc.JWTCustomClaims, error) {
+	tokenStr := rw.formValue(param)
+	if tokenStr == "" {
+		return nil, nil
+	}
+	switch tokenType := rw.formValue(param + "_type"); tokenType {
+	case toknSvc.TokenTypeAccessToken, toknSvc.TokenTypeJWT:
+	case "":
+		return nil, newOAuthError("invalid_request", fmt.Sprintf("%s_type is required", param))
+	default:
+		return nil, newOAuthError("invalid_request", fmt.Sprintf("%s_type: [%s] is not supported", param, tokenType))
+	}
+	claims, err := oars.toknHndlr.VerifyClientAccessToken(tokenStr, clnt.Audience)
+	if err != nil {
+		log.Errorf("Invalid token: [%s], error: [%v]", tokenStr, err)
+		return nil, newOAuthError("invalid_grant", fmt.Sprintf("%s is invalid, expired or revoked", param))
+	}
+	return claims, nil
+}
+
+// exchangeAudience checks the requested audience of a token exchange, the
+// client may request its own audience only. All of it but authsvc itself, the
+// issuer, is granted if none is requested, authsvc must be requested
+// explicitly.
+func exchangeAudience(clnt *clntTable.Client, audience []string, issuer string) ([]string, error) {
+	if len(audience) == 0 {
+		granted := make([]string, 0, len(clnt.Audience))
+		for _, a := range clnt.Audience {
+			if a != issuer {
+				granted = append(granted, a)
+			}
+		}
+		return granted, nil
+	}
+	for _, a := range audience {
+		if !clnt.HasAudience(a) {
+			return nil, newOAuthError("invalid_target", fmt.Sprintf("audience: [%s] isn't allowed for the client", a))
+		}
+	}
+	return audience, nil
+}
+
+// exchangeScope checks the requested scope of a token exchange, it may
+// narrow the scope of a scoped subject token but never widen it. The scopes of
+// the client the subject token has are granted if the scope is empty.
+func exchangeScope(clnt *clntTable.Client, subject *toknSvc.JWTCustomClaims, scope string) (string, error) {
+	allowed := func(s string) bool {
+		return clnt.HasScope(s) && (subject.Scope == "" || toknSvc.HasScope(subject.Scope, s))
+	}
+	if scope == "" {
+		granted := make([]string, 0, len(clnt.Scopes))
+		for _, s := range clnt.Scopes {
+			if allowed(s) {
+				granted = append(granted, s)
+			}
+		}
+		return strings.Join(granted, " "), nil
+	}
+	for _, s := range strings.Fields(scope) {
+		if !allowed(s) {
+			return "", newOAuthError("invalid_scope", fmt.Sprintf("scope: [%s] isn't allowed for the client or the subject token", s))
+		}
+	}
+	return scope, nil
+}
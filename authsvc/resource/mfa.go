@@ -0,0 +1,271 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/mfa"
+	"github.com/parthoshuvo/authsvc/passwd"
+	"github.com/parthoshuvo/authsvc/render"
+	usrTable "github.com/parthoshuvo/authsvc/table/user"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	ucmfa "github.com/parthoshuvo/authsvc/uc/mfa"
+	"github.com/parthoshuvo/authsvc/uc/token"
+	"github.com/parthoshuvo/authsvc/uc/user"
+)
+
+// MFAResource manages the two-factor authentication of the caller.
+type MFAResource struct {
+	usrHndlr  *user.Handler
+	toknHndlr *token.Handler
+	mfaHndlr  *ucmfa.Handler
+	pwdHasher *passwd.PasswordHasher
+	rndr      render.Renderer
+}
+
+func NewMFAResource(usrHndlr *user.Handler, toknHndlr *token.Handler, mfaHndlr *ucmfa.Handler, pwdHasher *passwd.PasswordHasher, rndr render.Renderer) *MFAResource {
+	return &MFAResource{usrHndlr, toknHndlr, mfaHndlr, pwdHasher, rndr}
+}
+
+// totpEnrollment is the pending TOTP secret to add to an authenticator app.
+type totpEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// mfaCode is an authentication code of an authenticator app, enrolling and
+// disabling also require the current password of the caller.
+type mfaCode struct {
+	Code     string            `json:"code"`
+	Password usrTable.Password `json:"password"`
+}
+
+// recoveryCodes is a set of single-use recovery codes, they're shown once.
+type recoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TOTPEnroller generates a pending TOTP secret of the caller, the current
+// password is required. Two-factor authentication is enabled once the secret
+// is confirmed with a first code.
+func (mfrs *MFAResource) TOTPEnroller() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		usr := authenticatedUser(mfrs.usrHndlr, mfrs.toknHndlr, w, rw)
+		if usr == nil {
+			return
+		}
+		req, err := unmarshallMFACode(rw)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling password [%v]", err))
+			return
+		}
+		if !mfrs.verifyPassword(w, usr, req.Password) {
+			return
+		}
+		secret, uri, err := mfrs.mfaHndlr.EnrollTOTP(usr)
+		if err != nil {
+			sendMFAError(w, usr, err)
+			return
+		}
+		if err := mfrs.rndr.Render(w, &totpEnrollment{secret, uri}, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling totp enrollment [%v]", err))
+		}
+	}
+}
+
+// TOTPQRCodeProvider renders the key URI of the pending TOTP secret of the
+// caller as QR code PNG image.
+func (mfrs *MFAResource) TOTPQRCodeProvider() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		usr := authenticatedUser(mfrs.usrHndlr, mfrs.toknHndlr, w, requestWrapper(r))
+		if usr == nil {
+			return
+		}
+		uri, err := mfrs.mfaHndlr.PendingTOTPURI(usr)
+		if err != nil {
+			sendMFAError(w, usr, err)
+			return
+		}
+		png, err := mfa.QRCode(uri)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on encoding qr code", err))
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		w.Write(png)
+	}
+}
+
+// TOTPConfirmer enables the two-factor authentication of the caller with a
+// first code of the pending TOTP secret and responds with recovery codes.
+func (mfrs *MFAResource) TOTPConfirmer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		usr := authenticatedUser(mfrs.usrHndlr, mfrs.toknHndlr, w, rw)
+		if usr == nil {
+			return
+		}
+		req, err := unmarshallMFACode(rw)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling code [%v]", err))
+			return
+		}
+		if !mfrs.checkMFALogin(w, usr) {
+			return
+		}
+		codes, err := mfrs.mfaHndlr.ConfirmTOTP(usr, req.Code)
+		mfrs.countMFAAttempt(usr, err)
+		if err != nil {
+			sendMFAError(w, usr, err)
+			return
+		}
+		if err := mfrs.rndr.Render(w, &recoveryCodes{codes}, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling recovery codes [%v]", err))
+		}
+	}
+}
+
+// RecoveryCodesRegenerator replaces the recovery codes of the caller, a code
+// of the authenticator app is required. The former codes become invalid.
+func (mfrs *MFAResource) RecoveryCodesRegenerator() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		usr := authenticatedUser(mfrs.usrHndlr, mfrs.toknHndlr, w, rw)
+		if usr == nil {
+			return
+		}
+		req, err := unmarshallMFACode(rw)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling code [%v]", err))
+			return
+		}
+		if !mfrs.checkMFALogin(w, usr) {
+			return
+		}
+		codes, err := mfrs.mfaHndlr.RegenerateRecoveryCodes(usr, req.Code)
+		mfrs.countMFAAttempt(usr, err)
+		if err != nil {
+			sendMFAError(w, usr, err)
+			return
+		}
+		if err := mfrs.rndr.Render(w, &recoveryCodes{codes}, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling recovery codes [%v]", err))
+		}
+	}
+}
+
+// TOTPDisabler disables the two-factor authentication of the caller with the
+// current password and a valid code or recovery code.
+func (mfrs *MFAResource) TOTPDisabler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		usr := authenticatedUser(mfrs.usrHndlr, mfrs.toknHndlr, w, rw)
+		if usr == nil {
+			return
+		}
+		req, err := unmarshallMFACode(rw)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling code [%v]", err))
+			return
+		}
+		if !mfrs.verifyPassword(w, usr, req.Password) || !mfrs.checkMFALogin(w, usr) {
+			return
+		}
+		err = mfrs.mfaHndlr.DisableTOTP(usr, req.Code)
+		mfrs.countMFAAttempt(usr, err)
+		if err != nil {
+			sendMFAError(w, usr, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verifyPassword checks the current password of the caller, a missing or
+// wrong password is responded with an error.
+func (mfrs *MFAResource) verifyPassword(w http.ResponseWriter, usr *usrTable.User, password usrTable.Password) bool {
+	if password == "" {
+		err := errors.New("current password is empty")
+		log.Error(err.Error())
+		sendError(w, NewError(http.StatusBadRequest, err.Error()))
+		return false
+	}
+	lusr := &LoginUser{Email: usr.Email, Password: password}
+	if ok, _ := lusr.isAuthenticated(mfrs.pwdHasher, usr.Password); !ok {
+		err := errors.New("current password mismatch")
+		log.Errorf("%s of user: [%s]", err.Error(), usr.Email)
+		sendError(w, NewError(http.StatusForbidden, err.Error()))
+		return false
+	}
+	return true
+}
+
+// checkMFALogin refuses the codes of the caller after too many wrong ones,
+// the failures are shared with the login.
+func (mfrs *MFAResource) checkMFALogin(w http.ResponseWriter, usr *usrTable.User) bool {
+	login := usr.Email.String()
+	if err := mfrs.toknHndlr.CheckMFALogin(login); err != nil {
+		if err != toknSvc.ErrMFALocked {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on checking mfa failures of user: [%s]", err, login))
+			return false
+		}
+		log.Errorf("mfa of user: [%s] is locked", login)
+		sendError(w, NewError(http.StatusTooManyRequests, err.Error()))
+		return false
+	}
+	return true
+}
+
+// countMFAAttempt counts a wrong code of the caller and clears the count
+// after a valid one.
+func (mfrs *MFAResource) countMFAAttempt(usr *usrTable.User, err error) {
+	login := usr.Email.String()
+	switch err {
+	case nil:
+		if err := mfrs.toknHndlr.ResetMFALogin(login); err != nil {
+			log.Errorf("error [%v] occurred on resetting mfa failures of user: [%s]", err, login)
+		}
+	case mfa.ErrInvalidCode:
+		if err := mfrs.toknHndlr.FailMFALogin(login); err != nil {
+			log.Errorf("error [%v] occurred on counting failed mfa attempt of user: [%s]", err, login)
+		}
+	}
+}
+
+// sendMFAError maps the errors of two-factor authentication use-cases.
+func sendMFAError(w http.ResponseWriter, usr *usrTable.User, err error) {
+	switch err {
+	case mfa.ErrTOTPEnabled:
+		sendError(w, NewError(http.StatusConflict, err.Error()))
+	case mfa.ErrTOTPNotEnrolled, mfa.ErrTOTPNotEnabled:
+		sendError(w, NewError(http.StatusNotFound, err.Error()))
+	case mfa.ErrInvalidCode:
+		log.Errorf("invalid authentication code of user: [%s]", usr.Email)
+		sendError(w, NewError(http.StatusForbidden, err.Error()))
+	default:
+		log.Errorf("error [%v] occurred on two-factor authentication of user: [%s]", err, usr.Email)
+		sendISError(w, "two-factor authentication error")
+	}
+}
+
+func unmarshallMFACode(rw *wrapper) (*mfaCode, error) {
+	data, err := rw.body()
+	if err != nil {
+		return nil, err
+	}
+	v := &mfaCode{}
+	if err := unmarshall(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
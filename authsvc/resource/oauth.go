@@ -0,0 +1,608 @@
+package resource
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/mfa"
+	"github.com/parthoshuvo/authsvc/passwd"
+	"github.com/parthoshuvo/authsvc/render"
+	clntTable "github.com/parthoshuvo/authsvc/table/client"
+	usrTable "github.com/parthoshuvo/authsvc/table/user"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	"github.com/parthoshuvo/authsvc/uc/adm"
+	"github.com/parthoshuvo/authsvc/uc/client"
+	ucmfa "github.com/parthoshuvo/authsvc/uc/mfa"
+	"github.com/parthoshuvo/authsvc/uc/token"
+	"github.com/parthoshuvo/authsvc/uc/user"
+)
+
+const csrfCookieName = "authsvc_csrf"
+
+// OAuthResource implements the OAuth 2.0 authorization server (RFC 6749), i.e.
+// the authorization code grant with mandatory PKCE (RFC 7636), the client
+// credentials grant, the device authorization grant (RFC 8628) and token
+// exchange (RFC 8693), and the OpenID Connect provider on top of it.
+type OAuthResource struct {
+	usrHndlr  *user.Handler
+	admHndlr  *adm.Handler
+	toknHndlr *token.Handler
+	clntHndlr *client.Handler
+	mfaHndlr  *ucmfa.Handler
+	pwdHasher *passwd.PasswordHasher
+	rndr      render.Renderer
+	baseURL   string
+}
+
+func NewOAuthResource(
+	usrHndlr *user.Handler,
+	admHndlr *adm.Handler,
+	toknHndlr *token.Handler,
+	clntHndlr *client.Handler,
+	mfaHndlr *ucmfa.Handler,
+	pwdHasher *passwd.PasswordHasher,
+	rndr render.Renderer,
+) *OAuthResource {
+	return &OAuthResource{usrHndlr, admHndlr, toknHndlr, clntHndlr, mfaHndlr, pwdHasher, rndr, issuerBaseURL(toknHndlr.Issuer())}
+}
+
+// authorizeRequest is the authorization request of a client.
+type authorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+func readAuthorizeRequest(values url.Values) *authorizeRequest {
+	return &authorizeRequest{
+		ResponseType:        values.Get("response_type"),
+		ClientID:            values.Get("client_id"),
+		RedirectURI:         values.Get("redirect_uri"),
+		Scope:               values.Get("scope"),
+		State:               values.Get("state"),
+		CodeChallenge:       values.Get("code_challenge"),
+		CodeChallengeMethod: values.Get("code_challenge_method"),
+		Nonce:               values.Get("nonce"),
+	}
+}
+
+// authorizePageData is rendered by the login and consent page.
+type authorizePageData struct {
+	*authorizeRequest
+	ClientName string
+	Scopes     []string
+	Email      string
+	CSRFToken  string
+	Error      string
+}
+
+type oauthTokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	IDToken         string `json:"id_token,omitempty"`
+}
+
+// userInfoResponse are the claims of the userinfo endpoint, the subject is
+// the ID of the user like in ID tokens.
+type userInfoResponse struct {
+	Subject string `json:"sub"`
+	*toknSvc.UserInfo
+}
+
+// Authorizer renders the login and consent page of an authorization request.
+func (oars *OAuthResource) Authorizer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		req := readAuthorizeRequest(r.URL.Query())
+		clnt, ok := oars.validateAuthorizeRequest(w, r, req)
+		if !ok {
+			return
+		}
+		csrfToken, err := newCSRFToken()
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on creating csrf token", err))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     csrfCookieName,
+			Value:    csrfToken,
+			Path:     r.URL.Path,
+			HttpOnly: true,
+			Secure:   isSecure(r),
+			SameSite: http.SameSiteStrictMode,
+		})
+		renderAuthorizePage(w, http.StatusOK, &authorizePageData{
+			authorizeRequest: req,
+			ClientName:       clnt.Name,
+			Scopes:           strings.Fields(req.Scope),
+			CSRFToken:        csrfToken,
+		})
+	}
+}
+
+// AuthorizationGranter authenticates the user of the login and consent page.
+// Granted authorizations are redirected to the client with an authorization
+// code, denied ones with the access_denied error.
+func (oars *OAuthResource) AuthorizationGranter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		if err := r.ParseForm(); err != nil {
+			sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("error parsing form [%v]", err)))
+			return
+		}
+		req := readAuthorizeRequest(r.PostForm)
+		clnt, ok := oars.validateAuthorizeRequest(w, r, req)
+		if !ok {
+			return
+		}
+		cookie, err := r.Cookie(csrfCookieName)
+		csrfToken := r.PostForm.Get("csrf_token")
+		if err != nil || csrfToken == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(csrfToken)) != 1 {
+			log.Errorf("csrf token mismatch of authorization request of client: %s", req.ClientID)
+			sendError(w, NewError(http.StatusForbidden, "Login form is expired, please start the sign-in again."))
+			return
+		}
+		if r.PostForm.Get("action") != "approve" {
+			redirectAuthorizeError(w, r, req, "access_denied", "the user denied the authorization")
+			return
+		}
+
+		page := &authorizePageData{
+			authorizeRequest: req,
+			ClientName:       clnt.Name,
+			Scopes:           strings.Fields(req.Scope),
+			Email:            r.PostForm.Get("email"),
+			CSRFToken:        csrfToken,
+		}
+		usr, err := oars.authenticateUser(page.Email, usrTable.Password(r.PostForm.Get("password")), r.PostForm.Get("totp_code"))
+		if err != nil {
+			var serr *AuthSvcError
+			if !errors.As(err, &serr) {
+				sendISError(w, err.Error())
+				return
+			}
+			log.Error(serr.Error())
+			page.Error = serr.Error()
+			renderAuthorizePage(w, serr.Status, page)
+			return
+		}
+
+		code, err := oars.toknHndlr.NewAuthorizationCode(&toknSvc.AuthorizationGrant{
+			ClientID:      clnt.ID,
+			RedirectURI:   req.RedirectURI,
+			Scope:         req.Scope,
+			Audience:      clnt.Audience,
+			CodeChallenge: req.CodeChallenge,
+			Nonce:         req.Nonce,
+			Login:         usr.Email.String(),
+		})
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on creating authorization code", err))
+			return
+		}
+		redirectAuthorize(w, r, req, url.Values{"code": {code}})
+	}
+}
+
+// TokenIssuer implements the token endpoint of the authorization_code,
+// refresh_token, client_credentials, device_code and token-exchange grants. A
+// client may use the grant types it is registered for only.
+func (oars *OAuthResource) TokenIssuer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		clnt := oars.authenticateClient(w, rw)
+		if clnt == nil {
+			return
+		}
+
+		var grant func(*clntTable.Client, *wrapper) (*oauthTokenResponse, error)
+		grantType := rw.formValue("grant_type")
+		switch grantType {
+		case clntTable.GrantAuthorizationCode:
+			grant = oars.exchangeAuthorizationCode
+		case clntTable.GrantRefreshToken:
+			grant = oars.refreshTokenPair
+		case clntTable.GrantClientCredentials:
+			grant = oars.issueClientToken
+		case clntTable.GrantDeviceCode:
+			grant = oars.exchangeDeviceCode
+		case clntTable.GrantTokenExchange:
+			grant = oars.exchangeToken
+		default:
+			sendOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("grant_type: [%s] is not supported", grantType))
+			return
+		}
+		if !clnt.HasGrant(grantType) {
+			log.Errorf("client: %s isn't registered for grant_type: [%s]", clnt.ID, grantType)
+			sendOAuthError(w, http.StatusBadRequest, "unauthorized_client", fmt.Sprintf("the client may not use grant_type: [%s]", grantType))
+			return
+		}
+		resp, err := grant(clnt, rw)
+		if err != nil {
+			var oerr *oauthError
+			if errors.As(err, &oerr) {
+				sendOAuthError(w, http.StatusBadRequest, oerr.code, oerr.description)
+				return
+			}
+			sendISError(w, fmt.Sprintf("error occurred while creating tokens: [%v]", err))
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+		if err := oars.rndr.Render(w, resp, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling tokens [%v]", err))
+		}
+	}
+}
+
+func (oars *OAuthResource) exchangeAuthorizationCode(clnt *clntTable.Client, rw *wrapper) (*oauthTokenResponse, error) {
+	code, redirectURI, verifier := rw.formValue("code"), rw.formValue("redirect_uri"), rw.formValue("code_verifier")
+	if code == "" || redirectURI == "" || verifier == "" {
+		return nil, newOAuthError("invalid_request", "code, redirect_uri and code_verifier are required")
+	}
+	grant, err := oars.toknHndlr.ExchangeAuthorizationCode(code, clnt.ID, redirectURI, verifier)
+	if errors.Is(err, toknSvc.ErrInvalidGrant) {
+		log.Errorf("authorization code exchange of client: %s failed: [%v]", clnt.ID, err)
+		return nil, newOAuthError("invalid_grant", toknSvc.ErrInvalidGrant.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	usr, err := oars.activeUser(grant.Login)
+	if err != nil {
+		return nil, err
+	}
+	authz := &toknSvc.Authorization{ClientID: clnt.ID, Scope: grant.Scope, Audience: grant.Audience}
+	pair, err := oars.toknHndlr.NewClientTokenPair(usr, authz, rw.device())
+	if err != nil {
+		return nil, err
+	}
+	resp := newOAuthTokenResponse(pair, grant.Scope)
+	if toknSvc.HasScope(grant.Scope, toknSvc.ScopeOpenID) {
+		if resp.IDToken, err = oars.toknHndlr.NewIDToken(usr, grant, pair.AccessToken); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (oars *OAuthResource) refreshTokenPair(clnt *clntTable.Client, rw *wrapper) (*oauthTokenResponse, error) {
+	refreshToken := rw.formValue("refresh_token")
+	if refreshToken == "" {
+		return nil, newOAuthError("invalid_request", "refresh_token is required")
+	}
+	claims, err := oars.toknHndlr.VerifyRefreshToken(refreshToken)
+	if err != nil {
+		log.Errorf("Invalid token: [%s], error: [%v]", refreshToken, err)
+		return nil, newOAuthError("invalid_grant", "refresh token is invalid, expired or already used")
+	}
+	if claims.ClientID != clnt.ID {
+		log.Errorf("refresh token of client: [%s] is used by client: %s", claims.ClientID, clnt.ID)
+		return nil, newOAuthError("invalid_grant", "refresh token is issued to another client")
+	}
+	usr, err := oars.activeUser(claims.Subject())
+	if err != nil {
+		return nil, err
+	}
+	pair, err := oars.toknHndlr.RenewAuthTokenPair(usr, claims, rw.device())
+	if err == toknSvc.ErrRefreshTokenReuse {
+		log.Errorf("refresh token: [%s] is used concurrently", refreshToken)
+		return nil, newOAuthError("invalid_grant", "refresh token is invalid, expired or already used")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newOAuthTokenResponse(pair, claims.Scope), nil
+}
+
+// issueClientToken issues an access token of a confidential client acting on
+// its own behalf, the client_credentials grant has no refresh token.
+func (oars *OAuthResource) issueClientToken(clnt *clntTable.Client, rw *wrapper) (*oauthTokenResponse, error) {
+	scope, err := clientScope(clnt, rw.formValue("scope"))
+	if err != nil {
+		return nil, err
+	}
+	authz := &toknSvc.Authorization{ClientID: clnt.ID, Scope: scope, Audience: clnt.Audience}
+	accessToken, err := oars.toknHndlr.NewClientAccessToken(authz)
+	if err != nil {
+		return nil, err
+	}
+	return &oauthTokenResponse{
+		AccessToken: accessToken.String(),
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(accessToken.Expires().Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+func newOAuthTokenResponse(pair *toknSvc.AuthTokenPair, scope string) *oauthTokenResponse {
+	return &oauthTokenResponse{
+		AccessToken:  pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(pair.Expires),
+		RefreshToken: pair.RefreshToken,
+		Scope:        scope,
+	}
+}
+
+// clientScope checks the requested scope of a client, all scopes of the
+// client are granted if the scope is empty.
+func clientScope(clnt *clntTable.Client, scope string) (string, error) {
+	if scope == "" {
+		return strings.Join(clnt.Scopes, " "), nil
+	}
+	for _, s := range strings.Fields(scope) {
+		if !clnt.HasScope(s) {
+			return "", newOAuthError("invalid_scope", fmt.Sprintf("scope: [%s] isn't allowed for the client", s))
+		}
+	}
+	return scope, nil
+}
+
+// UserInfoProvider releases the claims of the user of an access token issued
+// with the openid scope (OpenID Connect Core 1.0 section 5.3). The profile
+// and email scopes of the token select the released claims.
+func (oars *OAuthResource) UserInfoProvider() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		accessToken, err := requestWrapper(r).bearerAuth()
+		if err != nil {
+			sendBearerAuthError(w, "", err.Error())
+			return
+		}
+		claims, err := oars.toknHndlr.VerifyClientAccessToken(accessToken, nil)
+		if err != nil {
+			log.Errorf("Invalid token: [%s], error: [%v]", accessToken, err)
+			sendBearerAuthError(w, "invalid_token", "Access token has expired or is not yet valid.")
+			return
+		}
+		if claims.ID == "" || !toknSvc.HasScope(claims.Scope, toknSvc.ScopeOpenID) {
+			sendInsufficientScopeError(w, toknSvc.ScopeOpenID, fmt.Sprintf("access token of %s isn't issued with the openid scope", claims.Subject()))
+			return
+		}
+		details, err := oars.admHndlr.UserDetailsByJWTClaims(claims)
+		if errors.Is(err, usrTable.ErrUserNotFound) {
+			sendBearerAuthError(w, "invalid_token", "The user of the access token doesn't exist.")
+			return
+		}
+		if err != nil {
+			log.Errorf("error [%v] occurred on user details for user: [%s]", err, claims.Subject())
+			sendISError(w, "error reading user details")
+			return
+		}
+		info := toknSvc.NewUserInfo(details.Firstname, details.Lastname, details.Email.String(), details.EmailVerified, claims.Scope)
+		w.Header().Set("Cache-Control", "no-store")
+		if err := oars.rndr.Render(w, &userInfoResponse{claims.ID, info}, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling user info [%v]", err))
+		}
+	}
+}
+
+// activeUser fetches the user of a grant, the grant is invalid if the user
+// doesn't exist or is disabled.
+func (oars *OAuthResource) activeUser(login string) (*usrTable.User, error) {
+	usr, err := oars.usrHndlr.ReadUserByLogin(login)
+	if err != nil {
+		return nil, err
+	}
+	if usr == nil || usr.Disabled {
+		log.Errorf("user: %s of grant doesn't exist or is disabled", login)
+		return nil, newOAuthError("invalid_grant", "user of the grant is not active")
+	}
+	return usr, nil
+}
+
+// authenticateUser verifies the credentials of the login page, the code of
+// the authenticator app or a recovery code too if two-factor authentication
+// is enabled. Wrong codes count towards the MFA lockout of the user shared
+// with the MFA login. Failures are returned as AuthSvcError.
+func (oars *OAuthResource) authenticateUser(email string, password usrTable.Password, code string) (*usrTable.User, error) {
+	usr, err := oars.usrHndlr.ReadUserByLogin(email)
+	if err != nil {
+		return nil, fmt.Errorf("user fetching error: [%v]", err)
+	}
+	lusr := &LoginUser{Email: usrTable.Email(email), Password: password}
+	if usr == nil {
+		return nil, NewError(http.StatusUnauthorized, "Email or password is wrong.")
+	}
+	authenticated, rehash := lusr.isAuthenticated(oars.pwdHasher, usr.Password)
+	if !authenticated {
+		return nil, NewError(http.StatusUnauthorized, "Email or password is wrong.")
+	}
+	if rehash {
+		rehashPassword(oars.usrHndlr, oars.pwdHasher, usr, password)
+	}
+	if usr.Disabled {
+		return nil, NewError(http.StatusForbidden, "The account is disabled.")
+	}
+	if !usr.Verified {
+		return nil, NewError(http.StatusForbidden, "The email is not verified yet.")
+	}
+	if usr.TOTPEnabled {
+		if code == "" {
+			return nil, NewError(http.StatusUnauthorized, "Enter the authentication code of your authenticator app.")
+		}
+		login := usr.Email.String()
+		err := oars.toknHndlr.CheckMFALogin(login)
+		if err == toknSvc.ErrMFALocked {
+			return nil, NewError(http.StatusTooManyRequests, "Too many wrong authentication codes, try again later.")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error [%v] occurred on checking mfa failures of user: [%s]", err, login)
+		}
+		err = oars.mfaHndlr.VerifyCode(usr, code)
+		if err == mfa.ErrInvalidCode {
+			if err := oars.toknHndlr.FailMFALogin(login); err != nil {
+				log.Errorf("error [%v] occurred on counting failed mfa attempt of user: [%s]", err, login)
+			}
+			return nil, NewError(http.StatusUnauthorized, "The authentication code is wrong or already used.")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error [%v] occurred on verifying code of user: [%s]", err, email)
+		}
+		if err := oars.toknHndlr.ResetMFALogin(login); err != nil {
+			log.Errorf("error [%v] occurred on resetting mfa failures of user: [%s]", err, login)
+		}
+	}
+	return usr, nil
+}
+
+// validateAuthorizeRequest checks an authorization request. Requests of
+// unknown clients or unregistered redirect URIs are answered with an error
+// page, other failures are redirected to the client.
+func (oars *OAuthResource) validateAuthorizeRequest(w http.ResponseWriter, r *http.Request, req *authorizeRequest) (*clntTable.Client, bool) {
+	clnt, err := oars.clntHndlr.ReadClient(req.ClientID)
+	if err != nil {
+		log.Errorf("client fetching error: [%v]", err)
+		sendISError(w, "client fetching error")
+		return nil, false
+	}
+	if clnt == nil {
+		sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("client: [%s] is unknown", req.ClientID)))
+		return nil, false
+	}
+	if !clnt.HasRedirectURI(req.RedirectURI) {
+		log.Errorf("redirect_uri: [%s] isn't registered for client: %s", req.RedirectURI, clnt.ID)
+		sendError(w, NewError(http.StatusBadRequest, "redirect_uri isn't registered for the client"))
+		return nil, false
+	}
+	if req.ResponseType != "code" {
+		redirectAuthorizeError(w, r, req, "unsupported_response_type", "only response_type code is supported")
+		return nil, false
+	}
+	if !clnt.HasGrant(clntTable.GrantAuthorizationCode) {
+		redirectAuthorizeError(w, r, req, "unauthorized_client", "the client may not use the authorization code grant")
+		return nil, false
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != toknSvc.CodeChallengeS256 {
+		redirectAuthorizeError(w, r, req, "invalid_request", "code_challenge with code_challenge_method S256 is required")
+		return nil, false
+	}
+	scope, err := clientScope(clnt, req.Scope)
+	if err != nil {
+		oerr := err.(*oauthError)
+		redirectAuthorizeError(w, r, req, oerr.code, oerr.description)
+		return nil, false
+	}
+	req.Scope = scope
+	return clnt, true
+}
+
+// authenticateClient authenticates a confidential client, public clients are
+// identified by client_id only. A failure is sent to the client.
+func (oars *OAuthResource) authenticateClient(w http.ResponseWriter, rw *wrapper) *clntTable.Client {
+	clientID, secret, ok := rw.clientCredentials()
+	if !ok {
+		sendOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication is required")
+		return nil
+	}
+	clnt, err := oars.clntHndlr.ReadClient(clientID)
+	if err == nil && clnt != nil && !clnt.Public {
+		clnt, err = oars.clntHndlr.Authenticate(clientID, secret)
+	}
+	if err != nil {
+		log.Errorf("client fetching error: [%v]", err)
+		sendISError(w, "client fetching error")
+		return nil
+	}
+	if clnt == nil {
+		sendOAuthError(w, http.StatusUnauthorized, "invalid_client", fmt.Sprintf("client: %s authentication failed", clientID))
+		return nil
+	}
+	return clnt
+}
+
+// redirectAuthorize redirects the user agent to the redirect URI of the
+// client with the parameters and the state of the request.
+func redirectAuthorize(w http.ResponseWriter, r *http.Request, req *authorizeRequest, params url.Values) {
+	uri, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		sendError(w, NewError(http.StatusBadRequest, "redirect_uri is invalid"))
+		return
+	}
+	query := uri.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	uri.RawQuery = query.Encode()
+	http.Redirect(w, r, uri.String(), http.StatusFound)
+}
+
+func redirectAuthorizeError(w http.ResponseWriter, r *http.Request, req *authorizeRequest, code, description string) {
+	log.Errorf("authorization request of client: %s failed: %s, %s", req.ClientID, code, description)
+	redirectAuthorize(w, r, req, url.Values{"error": {code}, "error_description": {description}})
+}
+
+func renderAuthorizePage(w http.ResponseWriter, status int, data *authorizePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := authorizePage.Execute(w, data); err != nil {
+		log.Errorf("error rendering authorize page: [%v]", err)
+	}
+}
+
+func newCSRFToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// isSecure checks whether the request is sent over TLS, directly or via a proxy.
+func isSecure(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>Sign in to {{.ClientName}}</title></head>
+<body>
+<h1>Sign in to {{.ClientName}}</h1>
+{{if .Error}}<p style="color:red">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="hidden" name="response_type" value="{{.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Scope}}">
+<input type="hidden" name="state" value="{{.State}}">
+<input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.CodeChallengeMethod}}">
+<input type="hidden" name="nonce" value="{{.Nonce}}">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<p><label>Email <input type="email" name="email" value="{{.Email}}" autocomplete="username"></label></p>
+<p><label>Password <input type="password" name="password" autocomplete="current-password"></label></p>
+<p><label>Authentication code <input type="text" name="totp_code" autocomplete="one-time-code"></label> (two-factor authentication only, or a recovery code)</p>
+{{if .Scopes}}<p>{{.ClientName}} requests access to:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<button type="submit" name="action" value="approve">Allow</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>
+</body>
+</html>
+`))
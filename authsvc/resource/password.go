@@ -0,0 +1,310 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/parthoshuvo/authsvc/email"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/passwd"
+	usrTable "github.com/parthoshuvo/authsvc/table/user"
+	"github.com/parthoshuvo/authsvc/uc/token"
+	"github.com/parthoshuvo/authsvc/uc/user"
+	pwdValidator "github.com/parthoshuvo/authsvc/validator"
+)
+
+// PasswordResource handles the password change and reset of users.
+type PasswordResource struct {
+	usrHndlr    *user.Handler
+	toknHndlr   *token.Handler
+	validate    *validator.Validate
+	pwdValdtr   *pwdValidator.PasswordValidator
+	pwdHasher   *passwd.PasswordHasher
+	emailClient *email.EmailClient
+	resetLink   string
+	historySize int
+}
+
+func NewPasswordResource(
+	usrHandlr *user.Handler,
+	toknHandlr *token.Handler,
+	validate *validator.Validate,
+	pwdHasher *passwd.PasswordHasher,
+	emailClient *email.EmailClient,
+	resetLink string,
+	historySize int,
+) *PasswordResource {
+	if resetLink == "" {
+		log.Fatal("password reset link is missing")
+	}
+	return &PasswordResource{usrHandlr, toknHandlr, validate, pwdValidator.NewPasswordValidator(), pwdHasher, emailClient, resetLink, historySize}
+}
+
+// PasswordChanger changes the password of the user of the bearer access token.
+// The other sessions and the password reset tokens of the user are revoked.
+func (pwrs *PasswordResource) PasswordChanger() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		claims := authenticate(pwrs.toknHndlr, w, rw)
+		if claims == nil {
+			return
+		}
+		change, err := unmarshallPasswordChange(rw)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling password change [%v]", err))
+			return
+		}
+		if change.CurrentPassword == "" {
+			err := errors.New("current password is empty")
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if err := pwrs.validatePassword(change.NewPassword); err != nil {
+			log.Errorf("validation error: [%s]", err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+
+		usr, err := pwrs.usrHndlr.ReadUserByLogin(claims.Subject())
+		if err != nil {
+			log.Errorf("user fetching error: [%s]", err.Error())
+			sendISError(w, "user fetching error")
+			return
+		}
+		if usr == nil || usr.RowGUID != claims.ID {
+			err := fmt.Errorf("user: %s doesn't exists", claims.Subject())
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusNotFound, err.Error()))
+			return
+		}
+		if ok, _, err := pwrs.pwdHasher.Verify(string(change.CurrentPassword), string(usr.Password)); err != nil || !ok {
+			err := errors.New("password change failed, current password mismatch")
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusForbidden, err.Error()))
+			return
+		}
+		reused, err := pwrs.isRecentlyUsed(usr, change.NewPassword)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on reading password history", err))
+			return
+		}
+		if reused {
+			err := fmt.Errorf("non-complaint password: must not be equal to any of the last %d passwords", pwrs.historySize)
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+
+		if err := pwrs.changePassword(usr, change.NewPassword); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on password change", err))
+			return
+		}
+		if err := pwrs.toknHndlr.RevokeOtherSessions(claims); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on signing out sessions", err))
+			return
+		}
+		if err := pwrs.toknHndlr.RevokePasswordResetTokens(usr.RowGUID); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on revoking password reset tokens", err))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "password is successfully changed!!")
+	}
+}
+
+// PasswordForgotten mails a password reset link to the user. The request is
+// always accepted, hence it doesn't reveal whether the user exists.
+func (pwrs *PasswordResource) PasswordForgotten() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		login, err := unmarshallEmail(rw)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling email [%v]", err))
+			return
+		}
+		if err := pwrs.validate.Var(login, "required,email"); err != nil {
+			log.Errorf("email validation error: [%s]", err.Error())
+			sendError(w, NewError(http.StatusBadRequest, "non-complaint email: must contain valid email address"))
+			return
+		}
+
+		go pwrs.sendResetMail(login)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, "If the email is registered, a password reset link is sent to it")
+	}
+}
+
+// PasswordReset sets a new password with a password reset token, signs out
+// all sessions of the user and revokes its other password reset tokens.
+func (pwrs *PasswordResource) PasswordReset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		reset, err := unmarshallPasswordReset(rw)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error unmarshalling password reset [%v]", err))
+			return
+		}
+		if reset.Token == "" {
+			err := errors.New("password reset token is empty")
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if err := pwrs.validatePassword(reset.Password); err != nil {
+			log.Errorf("validation error: [%s]", err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+
+		claims, err := pwrs.toknHndlr.VerifyPasswordResetToken(reset.Token)
+		if err != nil {
+			log.Errorf("Invalid password reset token: [%s], error: [%v]", reset.Token, err)
+			sendError(w, NewError(http.StatusBadRequest, "Password reset token is invalid, expired or already used."))
+			return
+		}
+		usr, err := pwrs.usrHndlr.ReadUserByLogin(claims.Subject())
+		if err != nil {
+			log.Errorf("user fetching error: [%s]", err.Error())
+			sendISError(w, "user fetching error")
+			return
+		}
+		if usr == nil || usr.RowGUID != claims.ID {
+			err := fmt.Errorf("user: %s doesn't exists", claims.Subject())
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, "Password reset token is invalid, expired or already used."))
+			return
+		}
+
+		if err := pwrs.changePassword(usr, reset.Password); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on password reset", err))
+			return
+		}
+		if err := pwrs.toknHndlr.RevokeUserSessions(usr.RowGUID); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on signing out sessions", err))
+			return
+		}
+		if err := pwrs.toknHndlr.RevokePasswordResetTokens(usr.RowGUID); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on revoking password reset tokens", err))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "password is successfully reset!!")
+	}
+}
+
+// validatePassword checks a new password against the password requirements.
+func (pwrs *PasswordResource) validatePassword(password usrTable.Password) error {
+	pw := string(password)
+	if len(pw) < 8 || len(pw) > 64 {
+		return errors.New("non-complaint password: at least 8 and at most 64 characters")
+	}
+	if err := pwrs.pwdValdtr.Validate(pw); err != nil {
+		return fmt.Errorf("non-complaint password: must contain %s", err.Error())
+	}
+	return nil
+}
+
+// isRecentlyUsed checks whether the password equals the current or a former
+// password of the password history.
+func (pwrs *PasswordResource) isRecentlyUsed(usr *usrTable.User, password usrTable.Password) (bool, error) {
+	history, err := pwrs.usrHndlr.PasswordHistory(usr, pwrs.historySize)
+	if err != nil {
+		return false, err
+	}
+	for _, hash := range history {
+		if ok, _, _ := pwrs.pwdHasher.Verify(string(password), string(hash)); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// changePassword stores the hash of the new password, the former hash is kept
+// in the password history.
+func (pwrs *PasswordResource) changePassword(usr *usrTable.User, password usrTable.Password) error {
+	hash, err := pwrs.pwdHasher.Hash(string(password))
+	if err != nil {
+		return err
+	}
+	return pwrs.usrHndlr.ChangeUserPassword(usr.Email.String(), usrTable.Password(hash), pwrs.historySize)
+}
+
+// sendResetMail mails the configured reset link with a new password reset
+// token, the link is never derived from the request.
+func (pwrs *PasswordResource) sendResetMail(login string) {
+	usr, err := pwrs.usrHndlr.ReadUserByLogin(login)
+	if err != nil {
+		log.Errorf("user fetching error: [%s]", err.Error())
+		return
+	}
+	if usr == nil {
+		log.Infof("password reset is requested for unknown user: %s", login)
+		return
+	}
+	resetToken, err := pwrs.toknHndlr.NewPasswordResetToken(usr)
+	if err != nil {
+		log.Errorf("failed to create password reset token of %s. error: [%v]", usr.Email, err)
+		return
+	}
+	message := fmt.Sprintf(`click <a href="%s?token=%s">here</a> to reset your password. The link expires in %d minutes and can be used once.`,
+		pwrs.resetLink, url.QueryEscape(resetToken.String()), int(resetToken.Expires().Minutes()+0.5))
+	mail := pwrs.emailClient.NewMail(usr.Email, "Password Reset", message)
+	if err := pwrs.emailClient.SendEmail(mail); err != nil {
+		log.Errorf("failed to send password reset mail to %s. error: [%v]", usr.Email, err)
+	}
+}
+
+type passwordReset struct {
+	Token    string            `json:"token"`
+	Password usrTable.Password `json:"password"`
+}
+
+type passwordChange struct {
+	CurrentPassword usrTable.Password `json:"current_password"`
+	NewPassword     usrTable.Password `json:"new_password"`
+}
+
+func unmarshallPasswordChange(rw *wrapper) (*passwordChange, error) {
+	data, err := rw.body()
+	if err != nil {
+		return nil, err
+	}
+	change := passwordChange{}
+	if err := unmarshall(data, &change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func unmarshallPasswordReset(rw *wrapper) (*passwordReset, error) {
+	data, err := rw.body()
+	if err != nil {
+		return nil, err
+	}
+	reset := passwordReset{}
+	if err := unmarshall(data, &reset); err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+func unmarshallEmail(rw *wrapper) (string, error) {
+	data, err := rw.body()
+	if err != nil {
+		return "", err
+	}
+	v := struct {
+		Email string `json:"email"`
+	}{}
+	if err := unmarshall(data, &v); err != nil {
+		return "", err
+	}
+	return v.Email, nil
+}
@@ -0,0 +1,157 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	permTable "github.com/parthoshuvo/authsvc/table/permission"
+	"github.com/parthoshuvo/authsvc/uc/permission"
+)
+
+// PermissionResource handles the administration of permissions.
+type PermissionResource struct {
+	permHndlr *permission.Handler
+	rndr      render.Renderer
+	validate  *validator.Validate
+}
+
+func NewPermissionResource(permHndlr *permission.Handler, rndr render.Renderer, validate *validator.Validate) *PermissionResource {
+	return &PermissionResource{permHndlr, rndr, validate}
+}
+
+func (pmrs *PermissionResource) PermissionLister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		perms, err := pmrs.permHndlr.ReadPermissions()
+		if err != nil {
+			log.Errorf("permission fetching error: [%s]", err.Error())
+			sendISError(w, "permission fetching error")
+			return
+		}
+		if err := pmrs.rndr.Render(w, perms, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling permissions [%v]", err))
+		}
+	}
+}
+
+func (pmrs *PermissionResource) PermissionGetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		id, err := requestWrapper(r).intVar("id")
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		perm, err := pmrs.permHndlr.ReadPermission(id)
+		if err != nil {
+			log.Errorf("permission fetching error: [%s]", err.Error())
+			sendISError(w, "permission fetching error")
+			return
+		}
+		if perm == nil {
+			sendError(w, NewError(http.StatusNotFound, permTable.ErrPermissionNotFound.Error()))
+			return
+		}
+		if err := pmrs.rndr.Render(w, perm, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling permission [%v]", err))
+		}
+	}
+}
+
+func (pmrs *PermissionResource) PermissionCreator() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		perm, err := pmrs.unmarshallPermission(w, requestWrapper(r))
+		if err != nil {
+			return
+		}
+		perm, err = pmrs.permHndlr.CreatePermission(perm)
+		if err != nil {
+			sendPermissionError(w, err)
+			return
+		}
+		if err := pmrs.rndr.Render(w, perm, http.StatusCreated); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling permission [%v]", err))
+		}
+	}
+}
+
+func (pmrs *PermissionResource) PermissionUpdater() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		id, err := rw.intVar("id")
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		perm, err := pmrs.unmarshallPermission(w, rw)
+		if err != nil {
+			return
+		}
+		perm.ID = id
+		if err := pmrs.permHndlr.UpdatePermission(perm); err != nil {
+			sendPermissionError(w, err)
+			return
+		}
+		if err := pmrs.rndr.Render(w, perm, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling permission [%v]", err))
+		}
+	}
+}
+
+// PermissionDeleter deletes a permission, the permission is detached from its roles.
+func (pmrs *PermissionResource) PermissionDeleter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		id, err := requestWrapper(r).intVar("id")
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if err := pmrs.permHndlr.DeletePermission(id); err != nil {
+			sendPermissionError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// unmarshallPermission reads and validates the permission of the request body,
+// a failure is sent to the client.
+func (pmrs *PermissionResource) unmarshallPermission(w http.ResponseWriter, rw *wrapper) (*permTable.Permission, error) {
+	data, err := rw.body()
+	if err != nil {
+		sendISError(w, fmt.Sprintf("error reading permission [%v]", err))
+		return nil, err
+	}
+	perm := permTable.Permission{}
+	if err := unmarshall(data, &perm); err != nil {
+		sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("error unmarshalling permission [%v]", err)))
+		return nil, err
+	}
+	if err := pmrs.validate.Struct(&perm); err != nil {
+		err = toCustomValidatorError(err)
+		log.Errorf("validation error: [%s]", err.Error())
+		sendError(w, NewError(http.StatusBadRequest, err.Error()))
+		return nil, err
+	}
+	return &perm, nil
+}
+
+// sendPermissionError sends a permission store error with its status to the client.
+func sendPermissionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, permTable.ErrPermissionNotFound):
+		sendError(w, NewError(http.StatusNotFound, err.Error()))
+	case errors.Is(err, permTable.ErrPermissionExists):
+		sendError(w, NewError(http.StatusConflict, err.Error()))
+	default:
+		log.Errorf("permission store error: [%v]", err)
+		sendISError(w, "permission store error")
+	}
+}
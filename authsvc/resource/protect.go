@@ -1,7 +1,15 @@
 package resource
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+
+	"github.com/parthoshuvo/authsvc/authz"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	"github.com/parthoshuvo/authsvc/uc/permission"
+	"github.com/parthoshuvo/authsvc/uc/token"
 )
 
 // Action defines an area of functionality used for authorization purposes.
@@ -16,11 +24,87 @@ type Protector interface {
 	Protect(Action, http.Handler) http.HandlerFunc
 }
 
-type DefaultProtector struct{}
+// Principal is the authenticated caller of a protected action.
+type Principal struct {
+	Claims      *toknSvc.JWTCustomClaims
+	Permissions []string
+}
+
+type principalKey struct{}
 
-func (dp *DefaultProtector) Protect(action Action, inner http.Handler) http.HandlerFunc {
+// PrincipalOf provides the principal of a request to a protected action, nil
+// if the action isn't protected.
+func PrincipalOf(r *http.Request) *Principal {
+	principal, _ := r.Context().Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// PermissionProtector authenticates the bearer access token of a request and
+// authorizes the action if the permissions of the user grant all permissions
+// required by it. Actions without required permissions are denied.
+type PermissionProtector struct {
+	toknHndlr *token.Handler
+	permHndlr *permission.Handler
+	required  map[Action][]string
+}
+
+func NewPermissionProtector(toknHndlr *token.Handler, permHndlr *permission.Handler, actionPerms map[string][]string) *PermissionProtector {
+	required := make(map[Action][]string, len(actionPerms))
+	for action, perms := range actionPerms {
+		required[Action(action)] = perms
+	}
+	return &PermissionProtector{toknHndlr, permHndlr, required}
+}
+
+func (pp *PermissionProtector) Protect(action Action, inner http.Handler) http.HandlerFunc {
+	if len(pp.required[action]) == 0 {
+		log.Warnf("no permissions are configured for protected action: [%s], it is denied", action)
+	}
+	for _, perm := range pp.required[action] {
+		if _, err := authz.Parse(perm); err != nil {
+			log.Warnf("invalid permission is configured for protected action: [%s], it is denied. error: [%v]", action, err)
+		}
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer ServerError(w, r)
-		inner.ServeHTTP(w, r)
+		rw := requestWrapper(r)
+		accessToken, err := rw.bearerAuth()
+		if err != nil {
+			sendBearerAuthError(w, "", err.Error())
+			return
+		}
+		claims, err := pp.toknHndlr.VerifyAccessToken(accessToken)
+		if err != nil {
+			log.Errorf("Invalid token: [%s], error: [%v]", accessToken, err)
+			sendBearerAuthError(w, "invalid_token", "Access token has expired or is not yet valid.")
+			return
+		}
+		perms, err := pp.permHndlr.ReadUserPermissions(claims.Subject())
+		if err != nil {
+			log.Errorf("permission fetching error: [%s]", err.Error())
+			sendISError(w, "permission fetching error")
+			return
+		}
+		principal := &Principal{claims, make([]string, 0, len(perms))}
+		for _, perm := range perms {
+			principal.Permissions = append(principal.Permissions, perm.Name)
+		}
+		if !pp.isAuthorized(action, principal) {
+			err := fmt.Errorf("user: %s is not permitted to %s", claims.Subject(), action)
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusForbidden, err.Error()))
+			return
+		}
+		inner.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
 	})
 }
+
+// isAuthorized checks whether the permissions of the principal grant all
+// permissions required by the action, wildcards included.
+func (pp *PermissionProtector) isAuthorized(action Action, principal *Principal) bool {
+	required := pp.required[action]
+	if len(required) == 0 {
+		return false
+	}
+	return authz.NewSet(principal.Permissions).ImpliesAll(required)
+}
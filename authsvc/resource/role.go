@@ -0,0 +1,204 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	permTable "github.com/parthoshuvo/authsvc/table/permission"
+	roleTable "github.com/parthoshuvo/authsvc/table/role"
+	"github.com/parthoshuvo/authsvc/uc/role"
+)
+
+// RoleResource handles the administration of roles and their permissions.
+type RoleResource struct {
+	roleHndlr *role.Handler
+	rndr      render.Renderer
+	validate  *validator.Validate
+}
+
+func NewRoleResource(roleHndlr *role.Handler, rndr render.Renderer, validate *validator.Validate) *RoleResource {
+	return &RoleResource{roleHndlr, rndr, validate}
+}
+
+func (rlrs *RoleResource) RoleLister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		roles, err := rlrs.roleHndlr.ReadRoles()
+		if err != nil {
+			log.Errorf("role fetching error: [%s]", err.Error())
+			sendISError(w, "role fetching error")
+			return
+		}
+		if err := rlrs.rndr.Render(w, roles, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling roles [%v]", err))
+		}
+	}
+}
+
+// RoleGetter renders a role with its permissions.
+func (rlrs *RoleResource) RoleGetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		id, err := requestWrapper(r).intVar("id")
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		rl, err := rlrs.roleHndlr.ReadRole(id)
+		if err != nil {
+			log.Errorf("role fetching error: [%s]", err.Error())
+			sendISError(w, "role fetching error")
+			return
+		}
+		if rl == nil {
+			sendError(w, NewError(http.StatusNotFound, roleTable.ErrRoleNotFound.Error()))
+			return
+		}
+		if err := rlrs.rndr.Render(w, rl, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling role [%v]", err))
+		}
+	}
+}
+
+func (rlrs *RoleResource) RoleCreator() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rl, err := rlrs.unmarshallRole(w, requestWrapper(r))
+		if err != nil {
+			return
+		}
+		rl, err = rlrs.roleHndlr.CreateRole(rl)
+		if err != nil {
+			sendRoleError(w, err)
+			return
+		}
+		if err := rlrs.rndr.Render(w, rl, http.StatusCreated); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling role [%v]", err))
+		}
+	}
+}
+
+func (rlrs *RoleResource) RoleUpdater() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		id, err := rw.intVar("id")
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		rl, err := rlrs.unmarshallRole(w, rw)
+		if err != nil {
+			return
+		}
+		rl.ID = id
+		if err := rlrs.roleHndlr.UpdateRole(rl); err != nil {
+			sendRoleError(w, err)
+			return
+		}
+		if err := rlrs.rndr.Render(w, rl, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling role [%v]", err))
+		}
+	}
+}
+
+// RoleDeleter deletes a role, the role is removed from its users.
+func (rlrs *RoleResource) RoleDeleter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		id, err := requestWrapper(r).intVar("id")
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if err := rlrs.roleHndlr.DeleteRole(id); err != nil {
+			sendRoleError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (rlrs *RoleResource) PermissionAttacher() http.HandlerFunc {
+	return rlrs.roleRelationHandler("permission_id", rlrs.roleHndlr.AttachPermission)
+}
+
+func (rlrs *RoleResource) PermissionDetacher() http.HandlerFunc {
+	return rlrs.roleRelationHandler("permission_id", rlrs.roleHndlr.DetachPermission)
+}
+
+// ParentAdder makes a role inherit the permissions of a parent role. Parent
+// roles making a role inherit from itself are rejected.
+func (rlrs *RoleResource) ParentAdder() http.HandlerFunc {
+	return rlrs.roleRelationHandler("parent_id", rlrs.roleHndlr.AddParent)
+}
+
+func (rlrs *RoleResource) ParentRemover() http.HandlerFunc {
+	return rlrs.roleRelationHandler("parent_id", rlrs.roleHndlr.RemoveParent)
+}
+
+// roleRelationHandler relates the role of the path variable id to the item of
+// the path variable name.
+func (rlrs *RoleResource) roleRelationHandler(name string, exec func(int, int) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		roleID, err := rw.intVar("id")
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		itemID, err := rw.intVar(name)
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if err := exec(roleID, itemID); err != nil {
+			sendRoleError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// unmarshallRole reads and validates the role of the request body, a failure is
+// sent to the client.
+func (rlrs *RoleResource) unmarshallRole(w http.ResponseWriter, rw *wrapper) (*roleTable.Role, error) {
+	data, err := rw.body()
+	if err != nil {
+		sendISError(w, fmt.Sprintf("error reading role [%v]", err))
+		return nil, err
+	}
+	rl := roleTable.Role{}
+	if err := unmarshall(data, &rl); err != nil {
+		sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("error unmarshalling role [%v]", err)))
+		return nil, err
+	}
+	rl.Permissions = nil
+	if err := rlrs.validate.Struct(&rl); err != nil {
+		err = toCustomValidatorError(err)
+		log.Errorf("validation error: [%s]", err.Error())
+		sendError(w, NewError(http.StatusBadRequest, err.Error()))
+		return nil, err
+	}
+	return &rl, nil
+}
+
+// sendRoleError sends a role store error with its status to the client.
+func sendRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, roleTable.ErrRoleNotFound), errors.Is(err, permTable.ErrPermissionNotFound),
+		errors.Is(err, roleTable.ErrRolePermissionNotFound), errors.Is(err, roleTable.ErrRoleParentNotFound):
+		sendError(w, NewError(http.StatusNotFound, err.Error()))
+	case errors.Is(err, roleTable.ErrRoleExists), errors.Is(err, roleTable.ErrRolePermissionExists),
+		errors.Is(err, roleTable.ErrRoleParentExists), errors.Is(err, roleTable.ErrRoleCycle):
+		sendError(w, NewError(http.StatusConflict, err.Error()))
+	default:
+		log.Errorf("role store error: [%v]", err)
+		sendISError(w, "role store error")
+	}
+}
@@ -0,0 +1,71 @@
+package resource
+
+import (
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/parthoshuvo/authsvc/email"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	usrTable "github.com/parthoshuvo/authsvc/table/user"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+)
+
+// SecurityNotifier reports security events and optionally mails the affected user.
+type SecurityNotifier struct {
+	emailClient *email.EmailClient
+	mailUser    bool
+}
+
+func NewSecurityNotifier(emailClient *email.EmailClient, mailUser bool) *SecurityNotifier {
+	return &SecurityNotifier{emailClient, mailUser}
+}
+
+// Notify handles a security event.
+func (sn *SecurityNotifier) Notify(evt *toknSvc.SecurityEvent) {
+	log.Warnf("security event: [%s], user: [%s], session: [%s], client: [%s], credential: [%s], user agent: [%s], ip: [%s]",
+		evt.Type, evt.Subject, evt.SessionID, evt.ClientID, evt.CredentialID, evt.UserAgent, evt.IP)
+	if sn.mailUser {
+		go sn.sendSecurityMail(evt)
+	}
+}
+
+func (sn *SecurityNotifier) sendSecurityMail(evt *toknSvc.SecurityEvent) {
+	var subject, message string
+	switch evt.Type {
+	case toknSvc.EventRefreshTokenReuse:
+		subject = "Security alert: session signed out"
+		message = fmt.Sprintf("A previously used sign-in token of your session on %s (%s) was presented again at %s. "+
+			"The session is signed out for your protection. If this wasn't you, please change your password.",
+			html.EscapeString(evt.UserAgent), html.EscapeString(evt.IP), evt.Time.Format("2006-01-02 15:04:05 MST"))
+	case toknSvc.EventWebAuthnCloneDetected:
+		subject = "Security alert: passkey disabled"
+		message = fmt.Sprintf("One of your passkeys was used to sign in from %s (%s) at %s with a signature counter that indicates a copied authenticator. "+
+			"The passkey is disabled for your protection, please delete it and register your authenticator again. If this wasn't you, please change your password.",
+			html.EscapeString(evt.UserAgent), html.EscapeString(evt.IP), evt.Time.Format("2006-01-02 15:04:05 MST"))
+	default:
+		return
+	}
+	recipient := usrTable.Email(evt.Subject)
+	mail := sn.emailClient.NewMail(recipient, subject, message)
+	if err := sn.emailClient.SendEmail(mail); err != nil {
+		log.Errorf("failed to send security mail to %s. error: [%v]", recipient, err)
+	}
+}
+
+// NotifyRecoveryCodeUsed mails the user whose recovery code is consumed,
+// regardless of MailUser.
+func (sn *SecurityNotifier) NotifyRecoveryCodeUsed(usr *usrTable.User, remaining int) {
+	log.Warnf("security event: [recovery_code_used], user: [%s], remaining: [%d]", usr.Email, remaining)
+	go sn.sendRecoveryCodeMail(usr, remaining)
+}
+
+func (sn *SecurityNotifier) sendRecoveryCodeMail(usr *usrTable.User, remaining int) {
+	message := fmt.Sprintf("A recovery code of your two-factor authentication was used to sign in at %s, %d recovery codes are left. "+
+		"If this wasn't you, please change your password and generate new recovery codes.",
+		time.Now().Format("2006-01-02 15:04:05 MST"), remaining)
+	mail := sn.emailClient.NewMail(usr.Email, "Security alert: recovery code used", message)
+	if err := sn.emailClient.SendEmail(mail); err != nil {
+		log.Errorf("failed to send security mail to %s. error: [%v]", usr.Email, err)
+	}
+}
@@ -0,0 +1,80 @@
+package resource
+
+import (
+	"fmt"
+	"net/http"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	"github.com/parthoshuvo/authsvc/uc/token"
+)
+
+type SessionResource struct {
+	toknHndlr *token.Handler
+	rndr      render.Renderer
+}
+
+func NewSessionResource(toknHndlr *token.Handler, rndr render.Renderer) *SessionResource {
+	return &SessionResource{toknHndlr, rndr}
+}
+
+// SessionLister lists the active sessions of the caller.
+func (srs *SessionResource) SessionLister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		claims := authenticate(srs.toknHndlr, w, requestWrapper(r))
+		if claims == nil {
+			return
+		}
+		sessions, err := srs.toknHndlr.Sessions(claims)
+		if err != nil {
+			log.Errorf("error [%v] occurred on reading sessions of user: [%s]", err, claims.Subject())
+			sendISError(w, "error reading sessions")
+			return
+		}
+		if err := srs.rndr.Render(w, sessions, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling sessions [%v]", err))
+		}
+	}
+}
+
+// SessionRevoker signs out a session of the caller.
+func (srs *SessionResource) SessionRevoker() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		claims := authenticate(srs.toknHndlr, w, rw)
+		if claims == nil {
+			return
+		}
+		err := srs.toknHndlr.RevokeSession(claims, rw.sessionID())
+		if err == toknSvc.ErrSessionNotFound {
+			sendError(w, NewError(http.StatusNotFound, err.Error()))
+			return
+		}
+		if err != nil {
+			log.Errorf("error [%v] occurred on revoking session of user: [%s]", err, claims.Subject())
+			sendISError(w, "failed to revoke session")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// OtherSessionsRevoker signs out all sessions of the caller except the current one.
+func (srs *SessionResource) OtherSessionsRevoker() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		claims := authenticate(srs.toknHndlr, w, requestWrapper(r))
+		if claims == nil {
+			return
+		}
+		if err := srs.toknHndlr.RevokeOtherSessions(claims); err != nil {
+			log.Errorf("error [%v] occurred on revoking sessions of user: [%s]", err, claims.Subject())
+			sendISError(w, "failed to revoke sessions")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -7,7 +7,10 @@ import (
 
 	log "github.com/parthoshuvo/authsvc/log4u"
 	"github.com/parthoshuvo/authsvc/render"
+	clntTable "github.com/parthoshuvo/authsvc/table/client"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
 	"github.com/parthoshuvo/authsvc/uc/adm"
+	"github.com/parthoshuvo/authsvc/uc/client"
 	"github.com/parthoshuvo/authsvc/uc/token"
 	"github.com/parthoshuvo/authsvc/uc/user"
 )
@@ -16,11 +19,18 @@ type TokenResource struct {
 	toknHndlr *token.Handler
 	admHndlr  *adm.Handler
 	usrHndlr  *user.Handler
+	clntHndlr *client.Handler
 	rndr      render.Renderer
 }
 
-func NewTokenResource(toknHandlr *token.Handler, admHndlr *adm.Handler, usrHndlr *user.Handler, rndr render.Renderer) *TokenResource {
-	return &TokenResource{toknHandlr, admHndlr, usrHndlr, rndr}
+func NewTokenResource(
+	toknHandlr *token.Handler,
+	admHndlr *adm.Handler,
+	usrHndlr *user.Handler,
+	clntHndlr *client.Handler,
+	rndr render.Renderer,
+) *TokenResource {
+	return &TokenResource{toknHandlr, admHndlr, usrHndlr, clntHndlr, rndr}
 }
 
 func (trs *TokenResource) AccessTokenVerifier() http.HandlerFunc {
@@ -71,11 +81,21 @@ func (trs *TokenResource) TokenPairGenerator() http.HandlerFunc {
 		}
 
 		tokenClaims, err := trs.toknHndlr.VerifyRefreshToken(refreshToken)
+		if err == toknSvc.ErrRefreshTokenReuse {
+			sendError(w, NewError(http.StatusUnauthorized, "Refresh token has already been used, the session is signed out."))
+			return
+		}
 		if err != nil {
 			log.Errorf("Invalid token: [%s], error: [%v]", refreshToken, err)
 			sendError(w, NewError(http.StatusUnauthorized, "Refresh token has expired or is not yet valid."))
 			return
 		}
+		if tokenClaims.ClientID != "" {
+			err := fmt.Errorf("refresh token is issued to client: %s, it is refreshed at /oauth/token", tokenClaims.ClientID)
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
 		usr, err := trs.usrHndlr.ReadUserByLogin(tokenClaims.Subject())
 		if err != nil {
 			log.Errorf("error [%v] occurred on reading user: [%s]", err, tokenClaims.Subject())
@@ -86,13 +106,16 @@ func (trs *TokenResource) TokenPairGenerator() http.HandlerFunc {
 			sendError(w, NewError(http.StatusNotFound, "user not found"))
 			return
 		}
+		if usr.Disabled {
+			sendError(w, NewError(http.StatusForbidden, fmt.Sprintf("%s is disabled", usr.Email)))
+			return
+		}
 
-		if err := trs.toknHndlr.RevokeRefreshToken(refreshToken); err != nil {
-			log.Errorf("failed to revoke refresh token: [%v]", err)
-			sendISError(w, "failed to revoke refresh token")
+		toknPair, err := trs.toknHndlr.RenewAuthTokenPair(usr, tokenClaims, rw.device())
+		if err == toknSvc.ErrRefreshTokenReuse {
+			sendError(w, NewError(http.StatusUnauthorized, "Refresh token has already been used, the session is signed out."))
 			return
 		}
-		toknPair, err := trs.toknHndlr.NewAuthTokenPair(usr)
 		if err != nil {
 			sendISError(w, fmt.Sprintf("error occurred while creating tokens: [%v]", err))
 			return
@@ -103,6 +126,80 @@ func (trs *TokenResource) TokenPairGenerator() http.HandlerFunc {
 	}
 }
 
+// TokenIntrospector implements token introspection (RFC 7662) for
+// authenticated clients e.g. API gateways.
+func (trs *TokenResource) TokenIntrospector() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		clnt := trs.authenticateClient(w, rw)
+		if clnt == nil {
+			return
+		}
+
+		tokenStr := rw.formValue("token")
+		if tokenStr == "" {
+			err := errors.New("token is empty")
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		intro := trs.toknHndlr.Introspect(tokenStr, rw.formValue("token_type_hint"), clnt.ID, clnt.Audience)
+		w.Header().Set("Cache-Control", "no-store")
+		if err := trs.rndr.Render(w, intro, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling token introspection [%v]", err))
+		}
+	}
+}
+
+// TokenRevoker implements token revocation (RFC 7009) for authenticated
+// clients. Invalid tokens are ignored, hence the response is always 200.
+func (trs *TokenResource) TokenRevoker() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		clnt := trs.authenticateClient(w, rw)
+		if clnt == nil {
+			return
+		}
+
+		tokenStr := rw.formValue("token")
+		if tokenStr == "" {
+			err := errors.New("token is empty")
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if err := trs.toknHndlr.Revoke(tokenStr, rw.formValue("token_type_hint"), clnt.ID, clnt.Audience); err != nil {
+			log.Errorf("failed to revoke token: [%v]", err)
+			sendISError(w, "failed to revoke token")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// authenticateClient authenticates the calling client, a failure is sent to
+// the client and nil is returned.
+func (trs *TokenResource) authenticateClient(w http.ResponseWriter, rw *wrapper) *clntTable.Client {
+	clientID, secret, ok := rw.clientCredentials()
+	if !ok {
+		sendClientAuthError(w, errors.New("client authentication is required"))
+		return nil
+	}
+	clnt, err := trs.clntHndlr.Authenticate(clientID, secret)
+	if err != nil {
+		log.Errorf("client fetching error: [%v]", err)
+		sendISError(w, "client fetching error")
+		return nil
+	}
+	if clnt == nil {
+		sendClientAuthError(w, fmt.Errorf("client: %s authentication failed", clientID))
+		return nil
+	}
+	return clnt
+}
+
 func unmarshallRefreshToken(rw *wrapper) (string, error) {
 	data, err := rw.body()
 	if err != nil {
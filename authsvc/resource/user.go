@@ -0,0 +1,210 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	roleTable "github.com/parthoshuvo/authsvc/table/role"
+	usrTable "github.com/parthoshuvo/authsvc/table/user"
+	"github.com/parthoshuvo/authsvc/uc/adm"
+	"github.com/parthoshuvo/authsvc/uc/token"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// UserResource handles the administration of users.
+type UserResource struct {
+	admHndlr  *adm.Handler
+	toknHndlr *token.Handler
+	rndr      render.Renderer
+}
+
+func NewUserResource(admHndlr *adm.Handler, toknHndlr *token.Handler, rndr render.Renderer) *UserResource {
+	return &UserResource{admHndlr, toknHndlr, rndr}
+}
+
+// UserLister renders a page of the users whose login or name contains the
+// query parameter q.
+func (usrs *UserResource) UserLister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		search := reqmuxq(r, "q")
+		offset, limit, err := pagination(r)
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if len(search) > 64 {
+			sendError(w, NewError(http.StatusBadRequest, "non-complaint q: at most 64 characters"))
+			return
+		}
+		page, err := usrs.admHndlr.UserAccounts(search, offset, limit)
+		if err != nil {
+			log.Errorf("user fetching error: [%s]", err.Error())
+			sendISError(w, "user fetching error")
+			return
+		}
+		if err := usrs.rndr.Render(w, page, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling users [%v]", err))
+		}
+	}
+}
+
+// UserGetter renders a user with the roles and permissions.
+func (usrs *UserResource) UserGetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		account, err := usrs.admHndlr.UserAccount(reqmuxv(r, "id"))
+		if err != nil {
+			log.Errorf("user fetching error: [%s]", err.Error())
+			sendISError(w, "user fetching error")
+			return
+		}
+		if account == nil {
+			sendError(w, NewError(http.StatusNotFound, usrTable.ErrUserNotFound.Error()))
+			return
+		}
+		if err := usrs.rndr.Render(w, account, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling user [%v]", err))
+		}
+	}
+}
+
+func (usrs *UserResource) RoleAssigner() http.HandlerFunc {
+	return usrs.userRoleHandler(usrs.admHndlr.AssignUserRole)
+}
+
+func (usrs *UserResource) RoleRemover() http.HandlerFunc {
+	return usrs.userRoleHandler(usrs.admHndlr.RemoveUserRole)
+}
+
+// UserVerifier marks the email of a user as verified.
+func (usrs *UserResource) UserVerifier() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		if err := usrs.admHndlr.VerifyUser(reqmuxv(r, "id")); err != nil {
+			sendUserError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UserDisabler disables the account of a user and signs out all sessions.
+func (usrs *UserResource) UserDisabler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		id := reqmuxv(r, "id")
+		if !usrs.isOtherUser(w, r, id) {
+			return
+		}
+		if err := usrs.admHndlr.DisableUser(id, true); err != nil {
+			sendUserError(w, err)
+			return
+		}
+		if err := usrs.toknHndlr.RevokeUserSessions(id); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on signing out sessions", err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (usrs *UserResource) UserEnabler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		if err := usrs.admHndlr.DisableUser(reqmuxv(r, "id"), false); err != nil {
+			sendUserError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UserDeleter deletes a user and signs out all sessions.
+func (usrs *UserResource) UserDeleter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		id := reqmuxv(r, "id")
+		if !usrs.isOtherUser(w, r, id) {
+			return
+		}
+		if err := usrs.admHndlr.DeleteUser(id); err != nil {
+			sendUserError(w, err)
+			return
+		}
+		if err := usrs.toknHndlr.RevokeUserSessions(id); err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on signing out sessions", err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (usrs *UserResource) userRoleHandler(exec func(string, int) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		roleID, err := rw.intVar("role_id")
+		if err != nil {
+			sendError(w, NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if err := exec(reqmuxv(r, "id"), roleID); err != nil {
+			sendUserError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// isOtherUser prevents administrators from locking themselves out, a
+// failure is sent to the client.
+func (usrs *UserResource) isOtherUser(w http.ResponseWriter, r *http.Request, id string) bool {
+	if principal := PrincipalOf(r); principal != nil && principal.Claims.ID == id {
+		sendError(w, NewError(http.StatusConflict, "own account can't be disabled or deleted"))
+		return false
+	}
+	return true
+}
+
+// pagination provides the offset and limit query parameters.
+func pagination(r *http.Request) (int, int, error) {
+	offset, limit := 0, defaultPageLimit
+	if v := reqmuxq(r, "offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid offset: [%s]", v)
+		}
+		offset = n
+	}
+	if v := reqmuxq(r, "limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return 0, 0, fmt.Errorf("invalid limit: [%s], must be between 1 and %d", v, maxPageLimit)
+		}
+		limit = n
+	}
+	return offset, limit, nil
+}
+
+// sendUserError sends a user or role store error with its status to the client.
+func sendUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usrTable.ErrUserNotFound), errors.Is(err, roleTable.ErrRoleNotFound),
+		errors.Is(err, roleTable.ErrUserRoleNotFound):
+		sendError(w, NewError(http.StatusNotFound, err.Error()))
+	case errors.Is(err, roleTable.ErrUserRoleExists):
+		sendError(w, NewError(http.StatusConflict, err.Error()))
+	default:
+		log.Errorf("user store error: [%v]", err)
+		sendISError(w, "user store error")
+	}
+}
@@ -0,0 +1,249 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	"github.com/parthoshuvo/authsvc/table/credential"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	"github.com/parthoshuvo/authsvc/uc/token"
+	"github.com/parthoshuvo/authsvc/uc/user"
+	ucwebauthn "github.com/parthoshuvo/authsvc/uc/webauthn"
+	"github.com/parthoshuvo/authsvc/webauthn"
+)
+
+// WebAuthnResource implements the registration of WebAuthn credentials i.e.
+// passkeys and the passwordless login with them.
+type WebAuthnResource struct {
+	usrHndlr      *user.Handler
+	toknHndlr     *token.Handler
+	webauthnHndlr *ucwebauthn.Handler
+	rndr          render.Renderer
+}
+
+func NewWebAuthnResource(usrHndlr *user.Handler, toknHndlr *token.Handler, webauthnHndlr *ucwebauthn.Handler, rndr render.Renderer) *WebAuthnResource {
+	return &WebAuthnResource{usrHndlr, toknHndlr, webauthnHndlr, rndr}
+}
+
+// creationOptions are passed to navigator.credentials.create().
+type creationOptions struct {
+	PublicKey *webauthn.CreationOptions `json:"publicKey"`
+}
+
+// requestOptions are passed to navigator.credentials.get().
+type requestOptions struct {
+	PublicKey *webauthn.RequestOptions `json:"publicKey"`
+}
+
+// credentialRegistration is the new credential with a name given by the user.
+type credentialRegistration struct {
+	webauthn.AttestationResponse
+	Name string `json:"name"`
+}
+
+// RegistrationBeginner starts the registration of a credential of the caller.
+func (wars *WebAuthnResource) RegistrationBeginner() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		usr := authenticatedUser(wars.usrHndlr, wars.toknHndlr, w, requestWrapper(r))
+		if usr == nil {
+			return
+		}
+		opts, err := wars.webauthnHndlr.BeginRegistration(usr)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on beginning webauthn registration", err))
+			return
+		}
+		if err := wars.rndr.Render(w, &creationOptions{opts}, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling creation options [%v]", err))
+		}
+	}
+}
+
+// RegistrationFinisher verifies the new credential of the caller and stores it.
+func (wars *WebAuthnResource) RegistrationFinisher() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		usr := authenticatedUser(wars.usrHndlr, wars.toknHndlr, w, rw)
+		if usr == nil {
+			return
+		}
+		data, err := rw.body()
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error reading credential [%v]", err))
+			return
+		}
+		reg := credentialRegistration{}
+		if err := unmarshall(data, &reg); err != nil {
+			sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("credential is malformed: [%v]", err)))
+			return
+		}
+		if len(reg.Name) > 128 {
+			sendError(w, NewError(http.StatusBadRequest, "credential name is longer than 128 characters"))
+			return
+		}
+		cred, err := wars.webauthnHndlr.FinishRegistration(usr, &reg.AttestationResponse, reg.Name)
+		if err == credential.ErrCredentialExists {
+			sendError(w, NewError(http.StatusConflict, err.Error()))
+			return
+		}
+		if err != nil {
+			sendWebAuthnError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := wars.rndr.Render(w, cred, http.StatusCreated); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling credential [%v]", err))
+		}
+	}
+}
+
+// CredentialLister lists the credentials of the caller.
+func (wars *WebAuthnResource) CredentialLister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		usr := authenticatedUser(wars.usrHndlr, wars.toknHndlr, w, requestWrapper(r))
+		if usr == nil {
+			return
+		}
+		creds, err := wars.webauthnHndlr.Credentials(usr)
+		if err != nil {
+			log.Errorf("error [%v] occurred on reading credentials of user: [%s]", err, usr.Email)
+			sendISError(w, "error reading credentials")
+			return
+		}
+		if err := wars.rndr.Render(w, creds, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling credentials [%v]", err))
+		}
+	}
+}
+
+// CredentialDeleter deletes a credential of the caller.
+func (wars *WebAuthnResource) CredentialDeleter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		usr := authenticatedUser(wars.usrHndlr, wars.toknHndlr, w, rw)
+		if usr == nil {
+			return
+		}
+		err := wars.webauthnHndlr.DeleteCredential(usr, rw.credentialID())
+		if err == credential.ErrCredentialNotFound {
+			sendError(w, NewError(http.StatusNotFound, err.Error()))
+			return
+		}
+		if err != nil {
+			log.Errorf("error [%v] occurred on deleting credential of user: [%s]", err, usr.Email)
+			sendISError(w, "failed to delete credential")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// LoginBeginner starts a passwordless login. Without email any passkey is
+// accepted, else the credentials of the email.
+func (wars *WebAuthnResource) LoginBeginner() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		email := ""
+		if r.ContentLength != 0 {
+			var err error
+			if email, err = unmarshallEmail(rw); err != nil {
+				sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("login is malformed: [%v]", err)))
+				return
+			}
+		}
+		opts, err := wars.webauthnHndlr.BeginLogin(email)
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error [%v] occurred on beginning webauthn login", err))
+			return
+		}
+		if err := wars.rndr.Render(w, &requestOptions{opts}, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling request options [%v]", err))
+		}
+	}
+}
+
+// LoginFinisher verifies the assertion of a credential and signs its user in
+// with a token pair like the password login. The user is verified by the
+// authenticator, two-factor authentication doesn't apply.
+func (wars *WebAuthnResource) LoginFinisher() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		rw := requestWrapper(r)
+		data, err := rw.body()
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error reading assertion [%v]", err))
+			return
+		}
+		resp := webauthn.AssertionResponse{}
+		if err := unmarshall(data, &resp); err != nil {
+			sendError(w, NewError(http.StatusBadRequest, fmt.Sprintf("assertion is malformed: [%v]", err)))
+			return
+		}
+		cred, err := wars.webauthnHndlr.FinishLogin(&resp, rw.device())
+		if err != nil {
+			sendWebAuthnError(w, err, http.StatusUnauthorized)
+			return
+		}
+
+		usr, err := wars.usrHndlr.ReadUserByLogin(cred.Login)
+		if err != nil {
+			log.Errorf("user fetching error: [%s]", err.Error())
+			sendISError(w, "user fetching error")
+			return
+		}
+		if usr == nil || usr.RowGUID != cred.UserGUID {
+			err := fmt.Errorf("login failed, user of credential: %s doesn't exists", cred.ID)
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusUnauthorized, err.Error()))
+			return
+		}
+		if usr.Disabled {
+			err := fmt.Errorf("login failed, %s is disabled", usr.Email)
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusForbidden, err.Error()))
+			return
+		}
+		if !usr.Verified {
+			err := fmt.Errorf("login failed, %s is not verified", usr.Email)
+			log.Error(err.Error())
+			sendError(w, NewError(http.StatusForbidden, err.Error()))
+			return
+		}
+
+		toknPair, err := wars.toknHndlr.NewAuthTokenPair(usr, rw.device())
+		if err != nil {
+			sendISError(w, fmt.Sprintf("error occurred while creating tokens: [%v]", err))
+			return
+		}
+		if err := wars.rndr.Render(w, toknPair, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling tokens [%v]", err))
+		}
+	}
+}
+
+// sendWebAuthnError maps the errors of WebAuthn ceremonies, failed
+// verifications are answered with status.
+func sendWebAuthnError(w http.ResponseWriter, err error, status int) {
+	switch {
+	case err == toknSvc.ErrWebAuthnChallengeInvalid,
+		err == credential.ErrCredentialNotFound,
+		err == credential.ErrCredentialDisabled,
+		errors.Is(err, webauthn.ErrVerification),
+		errors.Is(err, webauthn.ErrUnsupported),
+		errors.Is(err, webauthn.ErrInvalidEncoding),
+		errors.Is(err, webauthn.ErrNoChallengeFound),
+		err == webauthn.ErrUserNotVerified,
+		err == webauthn.ErrCloneDetected:
+		log.Errorf("webauthn ceremony failed: [%v]", err)
+		sendError(w, NewError(status, err.Error()))
+	default:
+		sendISError(w, fmt.Sprintf("error [%v] occurred on webauthn ceremony", err))
+	}
+}
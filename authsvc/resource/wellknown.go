@@ -0,0 +1,113 @@
+package resource
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/render"
+	clntTable "github.com/parthoshuvo/authsvc/table/client"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	"github.com/parthoshuvo/authsvc/uc/token"
+)
+
+// openIDConfiguration is the OpenID Provider metadata (OpenID Connect
+// Discovery 1.0 section 3).
+type openIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type WellKnownResource struct {
+	toknHndlr *token.Handler
+	rndr      render.Renderer
+	baseURL   string
+}
+
+func NewWellKnownResource(toknHndlr *token.Handler, rndr render.Renderer) *WellKnownResource {
+	return &WellKnownResource{toknHndlr, rndr, issuerBaseURL(toknHndlr.Issuer())}
+}
+
+// JWKSPublisher publishes the public keys that verify the issued tokens.
+func (wkrs *WellKnownResource) JWKSPublisher() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		if err := wkrs.rndr.Render(w, wkrs.toknHndlr.JWKS(), http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling jwks [%v]", err))
+		}
+	}
+}
+
+// OpenIDConfigurationPublisher publishes the OpenID Provider metadata.
+func (wkrs *WellKnownResource) OpenIDConfigurationPublisher() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer ServerError(w, r)
+		baseURL := wkrs.baseURL
+		config := &openIDConfiguration{
+			Issuer:                            wkrs.toknHndlr.Issuer(),
+			AuthorizationEndpoint:             baseURL + "/oauth/authorize",
+			TokenEndpoint:                     baseURL + "/oauth/token",
+			UserInfoEndpoint:                  baseURL + "/oauth/userinfo",
+			JWKSURI:                           baseURL + "/.well-known/jwks.json",
+			IntrospectionEndpoint:             baseURL + "/auth/token/introspect",
+			RevocationEndpoint:                baseURL + "/auth/token/revoke",
+			DeviceAuthorizationEndpoint:       baseURL + "/oauth/device_authorization",
+			ScopesSupported:                   []string{toknSvc.ScopeOpenID, toknSvc.ScopeProfile, toknSvc.ScopeEmail},
+			ResponseTypesSupported:            []string{"code"},
+			GrantTypesSupported:               []string{clntTable.GrantAuthorizationCode, clntTable.GrantRefreshToken, clntTable.GrantClientCredentials, clntTable.GrantDeviceCode, clntTable.GrantTokenExchange},
+			SubjectTypesSupported:             []string{"public"},
+			IDTokenSigningAlgValuesSupported:  []string{wkrs.toknHndlr.IDTokenAlg()},
+			TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+			CodeChallengeMethodsSupported:     []string{toknSvc.CodeChallengeS256},
+			ClaimsSupported: []string{
+				"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "at_hash",
+				"name", "given_name", "family_name", "email", "email_verified",
+			},
+		}
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		if err := wkrs.rndr.Render(w, config, http.StatusOK); err != nil {
+			sendISError(w, fmt.Sprintf("error marshalling openid configuration [%v]", err))
+		}
+	}
+}
+
+// issuerBaseURL locates the endpoints at the issuer, which must be an https
+// URL (OpenID Connect Discovery 1.0 section 3), http is accepted for a
+// loopback host in development. Endpoints are never located at the request
+// host, the Host header is controlled by the client.
+func issuerBaseURL(issuer string) string {
+	uri, err := url.Parse(issuer)
+	if err != nil || uri.Host == "" || uri.RawQuery != "" || uri.Fragment != "" {
+		log.Fatalf("issuer must be an URL of authsvc: [%s]", issuer)
+	}
+	if uri.Scheme != "https" && !(uri.Scheme == "http" && isLoopback(uri.Hostname())) {
+		log.Fatalf("issuer must be an https URL: [%s]", issuer)
+	}
+	return strings.TrimSuffix(issuer, "/")
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,71 @@
+package route
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// trustedProxies are the networks of the reverse proxies in front of authsvc.
+type trustedProxies []*net.IPNet
+
+// newTrustedProxies parses the IP addresses and CIDR ranges of trusted proxies.
+func newTrustedProxies(proxies []string) (trustedProxies, error) {
+	nets := make(trustedProxies, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: [%s]", proxy)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: [%s]", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func (tp trustedProxies) trusts(addr string) bool {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range tp {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP finds the client in the X-Forwarded-For chain, i.e. the right-most
+// address not being a trusted proxy. Entries left of it may be forged.
+func (tp trustedProxies) clientIP(forwardedFor []string) string {
+	var chain []string
+	for _, header := range forwardedFor {
+		for _, addr := range strings.Split(header, ",") {
+			chain = append(chain, strings.TrimSpace(addr))
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if net.ParseIP(chain[i]) == nil {
+			return ""
+		}
+		if !tp.trusts(chain[i]) {
+			return chain[i]
+		}
+	}
+	return ""
+}
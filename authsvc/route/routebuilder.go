@@ -14,15 +14,21 @@ import (
 // Builder holds all routes.
 type Builder struct {
 	allowCors  bool
+	proxies    trustedProxies
 	pr         resource.Protector
 	router     *mux.Router
 	serverName string
 	isLogDebug bool
 }
 
-// NewRouteBuilder creates a route builder.
-func NewRouteBuilder(allowCors bool, pr resource.Protector, serverName string, isLogDebug bool) *Builder {
-	return &Builder{allowCors, pr, mux.NewRouter().StrictSlash(true), serverName, isLogDebug}
+// NewRouteBuilder creates a route builder. Forwarding headers are honoured
+// for requests of the trusted proxies only.
+func NewRouteBuilder(allowCors bool, trustedProxies []string, pr resource.Protector, serverName string, isLogDebug bool) *Builder {
+	proxies, err := newTrustedProxies(trustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &Builder{allowCors, proxies, pr, mux.NewRouter().StrictSlash(true), serverName, isLogDebug}
 }
 
 // SubrouteBuilder creates a subroute builder.
@@ -65,10 +71,25 @@ func (rb *Builder) corsHandler(handler http.Handler) http.Handler {
 func (rb *Builder) generalHandler(inner http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Server", rb.serverName)
-		inner.ServeHTTP(w, r)
+		inner.ServeHTTP(w, rb.forwardedRequest(r))
 	})
 }
 
+// forwardedRequest takes the remote address of a request forwarded by a
+// trusted proxy from the X-Forwarded-For header. Forwarding headers of other
+// peers are dropped, anybody could forge them.
+func (rb *Builder) forwardedRequest(r *http.Request) *http.Request {
+	if !rb.proxies.trusts(r.RemoteAddr) {
+		r.Header.Del("X-Forwarded-For")
+		r.Header.Del("X-Forwarded-Proto")
+		return r
+	}
+	if ip := rb.proxies.clientIP(r.Header.Values("X-Forwarded-For")); ip != "" {
+		r.RemoteAddr = ip
+	}
+	return r
+}
+
 func (rb *Builder) performanceLogger(inner http.HandlerFunc, action resource.Action) http.Handler {
 	if rb.isLogDebug {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -81,5 +102,5 @@ func (rb *Builder) performanceLogger(inner http.HandlerFunc, action resource.Act
 }
 
 func (rb *Builder) partialClone(router *mux.Router) *Builder {
-	return &Builder{rb.allowCors, rb.pr, router, rb.serverName, rb.isLogDebug}
+	return &Builder{rb.allowCors, rb.proxies, rb.pr, router, rb.serverName, rb.isLogDebug}
 }
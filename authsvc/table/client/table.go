@@ -0,0 +1,109 @@
+package client
+
+import "errors"
+
+// Grant types a client may be registered for.
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+	GrantDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// Client is a registered client application of authsvc.
+type Client struct {
+	ID           string   `json:"client_id"`
+	Secret       string   `json:"-"`
+	Name         string   `json:"name" validate:"required,max=128"`
+	Public       bool     `json:"public"`
+	Grants       []string `json:"grants" validate:"dive,oneof=authorization_code refresh_token client_credentials urn:ietf:params:oauth:grant-type:device_code urn:ietf:params:oauth:grant-type:token-exchange"`
+	Scopes       []string `json:"scopes" validate:"dive,required,max=128,validPerm"`
+	RedirectURIs []string `json:"redirect_uris" validate:"dive,required,max=512,url"`
+	Audience     []string `json:"audience" validate:"dive,required,max=128"`
+}
+
+var (
+	ErrClientNotFound = errors.New("client is not found")
+	ErrClientExists   = errors.New("client already exists")
+	ErrClientInvalid  = errors.New("client registration is invalid")
+)
+
+// HasGrant checks whether the client is registered for the grant type.
+func (c *Client) HasGrant(grant string) bool {
+	return contains(c.Grants, grant)
+}
+
+// HasRedirectURI checks whether the redirect URI is registered, URIs must
+// match exactly.
+func (c *Client) HasRedirectURI(uri string) bool {
+	return contains(c.RedirectURIs, uri)
+}
+
+// HasScope checks whether the client may request the scope.
+func (c *Client) HasScope(scope string) bool {
+	return contains(c.Scopes, scope)
+}
+
+// HasAudience checks whether the client may request tokens for the audience.
+func (c *Client) HasAudience(audience string) bool {
+	return contains(c.Audience, audience)
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// Store defines the interface for Client storage.
+type Store interface {
+	ReadClients() ([]*Client, error)
+	ReadClient(string) (*Client, error)
+	InsertClient(*Client) error
+	UpdateClient(*Client) error
+	UpdateClientSecret(string, string) error
+	DeleteClient(string) error
+}
+
+// Table provides implementation of Client store
+type Table struct {
+	store Store
+}
+
+func NewTable(s Store) *Table {
+	return &Table{s}
+}
+
+// ReadClients fetches all clients.
+func (t *Table) ReadClients() ([]*Client, error) {
+	return t.store.ReadClients()
+}
+
+// ReadClient fetches a client by client ID, nil if it doesn't exist.
+func (t *Table) ReadClient(clientID string) (*Client, error) {
+	return t.store.ReadClient(clientID)
+}
+
+// InsertClient registers a client, its secret must be hashed already.
+func (t *Table) InsertClient(clnt *Client) error {
+	return t.store.InsertClient(clnt)
+}
+
+// UpdateClient changes the registration of a client except its secret.
+func (t *Table) UpdateClient(clnt *Client) error {
+	return t.store.UpdateClient(clnt)
+}
+
+// UpdateClientSecret replaces the hashed secret of a client.
+func (t *Table) UpdateClientSecret(clientID, secret string) error {
+	return t.store.UpdateClientSecret(clientID, secret)
+}
+
+// DeleteClient deletes a client.
+func (t *Table) DeleteClient(clientID string) error {
+	return t.store.DeleteClient(clientID)
+}
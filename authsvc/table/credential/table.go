@@ -0,0 +1,77 @@
+package credential
+
+import "errors"
+
+// Credential is a WebAuthn credential e.g. a passkey of a user.
+type Credential struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Login      string   `json:"-"`
+	UserGUID   string   `json:"-"`
+	PublicKey  []byte   `json:"-"`
+	SignCount  uint32   `json:"sign_count"`
+	AAGUID     string   `json:"aaguid"`
+	Format     string   `json:"attestation_format"`
+	Transports []string `json:"transports"`
+	Created    string   `json:"created"`
+	LastUsed   string   `json:"last_used,omitempty"`
+	Disabled   bool     `json:"disabled"`
+}
+
+var (
+	ErrCredentialNotFound = errors.New("credential is not found")
+	ErrCredentialExists   = errors.New("credential already exists")
+	ErrCredentialDisabled = errors.New("credential is disabled")
+)
+
+// Store defines the interface for Credential storage.
+type Store interface {
+	ReadUserCredentials(string) ([]*Credential, error)
+	ReadCredential(string) (*Credential, error)
+	InsertCredential(*Credential) error
+	UpdateCredentialSignCount(string, uint32) (bool, error)
+	DisableCredential(string) error
+	DeleteCredential(string, string) error
+}
+
+// Table provides implementation of Credential store
+type Table struct {
+	store Store
+}
+
+func NewTable(s Store) *Table {
+	return &Table{s}
+}
+
+// ReadUserCredentials fetches the credentials of user.
+func (t *Table) ReadUserCredentials(login string) ([]*Credential, error) {
+	return t.store.ReadUserCredentials(login)
+}
+
+// ReadCredential fetches a credential by its ID, nil if it doesn't exist.
+func (t *Table) ReadCredential(id string) (*Credential, error) {
+	return t.store.ReadCredential(id)
+}
+
+// InsertCredential registers a credential of its user.
+func (t *Table) InsertCredential(cred *Credential) error {
+	return t.store.InsertCredential(cred)
+}
+
+// UpdateCredentialSignCount records the sign count of an authentication. It
+// reports false if the stored sign count isn't lower, i.e. a concurrent
+// authentication has won.
+func (t *Table) UpdateCredentialSignCount(id string, signCount uint32) (bool, error) {
+	return t.store.UpdateCredentialSignCount(id, signCount)
+}
+
+// DisableCredential disables a credential, e.g. of a cloned authenticator. A
+// disabled credential doesn't sign in, its user may delete it.
+func (t *Table) DisableCredential(id string) error {
+	return t.store.DisableCredential(id)
+}
+
+// DeleteCredential deletes a credential of user.
+func (t *Table) DeleteCredential(login, id string) error {
+	return t.store.DeleteCredential(login, id)
+}
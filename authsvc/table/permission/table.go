@@ -1,13 +1,25 @@
 package permission
 
+import "errors"
+
 type Permission struct {
 	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name        string `json:"name" validate:"required,max=128,validPerm"`
+	Description string `json:"description" validate:"required,max=512"`
 }
 
+var (
+	ErrPermissionNotFound = errors.New("permission is not found")
+	ErrPermissionExists   = errors.New("permission already exists")
+)
+
 type Store interface {
 	ReadUserPermissions(string) ([]*Permission, error)
+	ReadPermissions() ([]*Permission, error)
+	ReadPermission(int) (*Permission, error)
+	InsertPermission(*Permission) (*Permission, error)
+	UpdatePermission(*Permission) error
+	DeletePermission(int) error
 }
 
 type Table struct {
@@ -21,3 +33,28 @@ func NewTable(s Store) *Table {
 func (t *Table) ReadUserPermissions(login string) ([]*Permission, error) {
 	return t.store.ReadUserPermissions(login)
 }
+
+// ReadPermissions fetches all permissions.
+func (t *Table) ReadPermissions() ([]*Permission, error) {
+	return t.store.ReadPermissions()
+}
+
+// ReadPermission fetches a permission by ID, nil if it doesn't exist.
+func (t *Table) ReadPermission(id int) (*Permission, error) {
+	return t.store.ReadPermission(id)
+}
+
+// InsertPermission creates a permission.
+func (t *Table) InsertPermission(perm *Permission) (*Permission, error) {
+	return t.store.InsertPermission(perm)
+}
+
+// UpdatePermission changes the name and description of a permission.
+func (t *Table) UpdatePermission(perm *Permission) error {
+	return t.store.UpdatePermission(perm)
+}
+
+// DeletePermission deletes a permission, it is detached from all roles.
+func (t *Table) DeletePermission(id int) error {
+	return t.store.DeletePermission(id)
+}
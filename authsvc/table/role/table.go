@@ -1,13 +1,47 @@
 package role
 
+import (
+	"errors"
+
+	"github.com/parthoshuvo/authsvc/table/permission"
+)
+
 type Role struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	ID          int                      `json:"id"`
+	Name        string                   `json:"name" validate:"required,max=64"`
+	Description string                   `json:"description" validate:"required,max=512"`
+	Permissions []*permission.Permission `json:"permissions,omitempty"`
+	Parents     []*Role                  `json:"parents,omitempty"`
 }
 
+var (
+	ErrRoleNotFound           = errors.New("role is not found")
+	ErrRoleExists             = errors.New("role already exists")
+	ErrRolePermissionNotFound = errors.New("role doesn't have the permission")
+	ErrRolePermissionExists   = errors.New("role already has the permission")
+	ErrUserRoleNotFound       = errors.New("user doesn't have the role")
+	ErrUserRoleExists         = errors.New("user already has the role")
+	ErrRoleParentNotFound     = errors.New("role doesn't have the parent role")
+	ErrRoleParentExists       = errors.New("role already has the parent role")
+	ErrRoleCycle              = errors.New("parent role would make the role inherit from itself")
+)
+
 type Store interface {
 	ReadUserRoles(string) ([]*Role, error)
+	ReadUserEffectiveRoles(string) ([]*Role, error)
+	ReadRoles() ([]*Role, error)
+	ReadRole(int) (*Role, error)
+	InsertRole(*Role) (*Role, error)
+	UpdateRole(*Role) error
+	DeleteRole(int) error
+	ReadRolePermissions(int) ([]*permission.Permission, error)
+	InsertRolePermission(int, int) error
+	DeleteRolePermission(int, int) error
+	ReadRoleParents(int) ([]*Role, error)
+	InsertRoleParent(int, int) error
+	DeleteRoleParent(int, int) error
+	InsertUserRole(int, int) error
+	DeleteUserRole(int, int) error
 }
 
 type Table struct {
@@ -21,3 +55,75 @@ func NewTable(s Store) *Table {
 func (t *Table) ReadUserRoles(login string) ([]*Role, error) {
 	return t.store.ReadUserRoles(login)
 }
+
+// ReadUserEffectiveRoles fetches the assigned roles of a user together with
+// the roles they inherit from their parent roles.
+func (t *Table) ReadUserEffectiveRoles(login string) ([]*Role, error) {
+	return t.store.ReadUserEffectiveRoles(login)
+}
+
+// ReadRoles fetches all roles.
+func (t *Table) ReadRoles() ([]*Role, error) {
+	return t.store.ReadRoles()
+}
+
+// ReadRole fetches a role by ID, nil if it doesn't exist.
+func (t *Table) ReadRole(id int) (*Role, error) {
+	return t.store.ReadRole(id)
+}
+
+// InsertRole creates a role.
+func (t *Table) InsertRole(role *Role) (*Role, error) {
+	return t.store.InsertRole(role)
+}
+
+// UpdateRole changes the name and description of a role.
+func (t *Table) UpdateRole(role *Role) error {
+	return t.store.UpdateRole(role)
+}
+
+// DeleteRole deletes a role, it is removed from all users.
+func (t *Table) DeleteRole(id int) error {
+	return t.store.DeleteRole(id)
+}
+
+// ReadRolePermissions fetches the permissions attached to a role.
+func (t *Table) ReadRolePermissions(roleID int) ([]*permission.Permission, error) {
+	return t.store.ReadRolePermissions(roleID)
+}
+
+// InsertRolePermission attaches a permission to a role.
+func (t *Table) InsertRolePermission(roleID, permID int) error {
+	return t.store.InsertRolePermission(roleID, permID)
+}
+
+// DeleteRolePermission detaches a permission from a role.
+func (t *Table) DeleteRolePermission(roleID, permID int) error {
+	return t.store.DeleteRolePermission(roleID, permID)
+}
+
+// ReadRoleParents fetches the parent roles of a role.
+func (t *Table) ReadRoleParents(roleID int) ([]*Role, error) {
+	return t.store.ReadRoleParents(roleID)
+}
+
+// InsertRoleParent makes a role inherit the permissions of a parent role. It
+// fails with ErrRoleCycle if the role is an ancestor of the parent role.
+func (t *Table) InsertRoleParent(roleID, parentID int) error {
+	return t.store.InsertRoleParent(roleID, parentID)
+}
+
+// DeleteRoleParent removes a parent role from a role.
+func (t *Table) DeleteRoleParent(roleID, parentID int) error {
+	return t.store.DeleteRoleParent(roleID, parentID)
+}
+
+// InsertUserRole assigns a role to a user.
+func (t *Table) InsertUserRole(userID, roleID int) error {
+	return t.store.InsertUserRole(userID, roleID)
+}
+
+// DeleteUserRole removes a role from a user.
+func (t *Table) DeleteUserRole(userID, roleID int) error {
+	return t.store.DeleteUserRole(userID, roleID)
+}
@@ -1,8 +1,7 @@
 package user
 
 import (
-	"crypto/md5"
-	"fmt"
+	"errors"
 	"strings"
 )
 
@@ -16,8 +15,22 @@ type User struct {
 	RowGUID          string   `json:"-"`
 	Verified         bool     `json:"-"`
 	VerificationCode string   `json:"-"`
+	Disabled         bool     `json:"-"`
+	TOTPEnabled      bool     `json:"-"`
 }
 
+// TOTP is the two-factor authentication state of a user. The secret is
+// encrypted, it is pending until a first code confirms it. Counter is the
+// time step of the last accepted code.
+type TOTP struct {
+	Secret  string
+	Enabled bool
+	Counter int64
+}
+
+// ErrUserNotFound is returned for operations on a user that doesn't exist.
+var ErrUserNotFound = errors.New("user is not found")
+
 type Email string
 
 func (e Email) String() string {
@@ -42,16 +55,25 @@ func (pw Password) Equals(other Password) bool {
 	return string(pw) == string(other)
 }
 
-func (pw Password) Hash() Password {
-	data := []byte(pw)
-	return Password(fmt.Sprintf("%x", md5.Sum(data)))
-}
-
 // Store defines the interface for User storage.
 type Store interface {
 	ReadUserByLogin(string) (*User, error)
 	InsertUser(*User) (*User, error)
 	AssignUserVerification(string, bool) error
+	AssignUserPassword(string, Password) error
+	ReadUserPasswordHistory(string, int) ([]Password, error)
+	ChangeUserPassword(string, Password, int) error
+	ReadUserByID(string) (*User, error)
+	ReadUsers(string, int, int) ([]*User, error)
+	CountUsers(string) (int, error)
+	AssignUserDisabled(string, bool) error
+	DeleteUser(string) error
+	ReadUserTOTP(string) (*TOTP, error)
+	AssignUserTOTP(string, string) error
+	EnableUserTOTP(string, int64) (bool, error)
+	AdvanceUserTOTPCounter(string, int64) (bool, error)
+	AssignUserRecoveryCodes(string, []string) error
+	ConsumeUserRecoveryCode(string, string) (bool, int, error)
 }
 
 // Table provides implementation of User store
@@ -77,3 +99,79 @@ func (t *Table) InsertUser(usr *User) (*User, error) {
 func (t *Table) AssignUserVerification(login string, isVerified bool) error {
 	return t.store.AssignUserVerification(login, isVerified)
 }
+
+// AssignUserPassword replaces the password hash of user
+func (t *Table) AssignUserPassword(login string, password Password) error {
+	return t.store.AssignUserPassword(login, password)
+}
+
+// ReadUserPasswordHistory fetches the most recent former password hashes of user
+func (t *Table) ReadUserPasswordHistory(login string, size int) ([]Password, error) {
+	return t.store.ReadUserPasswordHistory(login, size)
+}
+
+// ChangeUserPassword replaces the password hash of user, the former hash is kept
+// in the password history of historySize hashes
+func (t *Table) ChangeUserPassword(login string, password Password, historySize int) error {
+	return t.store.ChangeUserPassword(login, password, historySize)
+}
+
+// ReadUserByID fetches an user by its public ID.
+func (t *Table) ReadUserByID(id string) (*User, error) {
+	return t.store.ReadUserByID(id)
+}
+
+// ReadUsers fetches a page of the users whose login or name contains search.
+func (t *Table) ReadUsers(search string, offset, limit int) ([]*User, error) {
+	return t.store.ReadUsers(search, offset, limit)
+}
+
+// CountUsers counts the users whose login or name contains search.
+func (t *Table) CountUsers(search string) (int, error) {
+	return t.store.CountUsers(search)
+}
+
+// AssignUserDisabled disables or enables the account of user
+func (t *Table) AssignUserDisabled(login string, isDisabled bool) error {
+	return t.store.AssignUserDisabled(login, isDisabled)
+}
+
+// DeleteUser deletes a user
+func (t *Table) DeleteUser(login string) error {
+	return t.store.DeleteUser(login)
+}
+
+// ReadUserTOTP fetches the two-factor authentication state of user
+func (t *Table) ReadUserTOTP(login string) (*TOTP, error) {
+	return t.store.ReadUserTOTP(login)
+}
+
+// AssignUserTOTP replaces the TOTP secret of user with a pending one, an
+// empty secret disables two-factor authentication
+func (t *Table) AssignUserTOTP(login string, secret string) error {
+	return t.store.AssignUserTOTP(login, secret)
+}
+
+// EnableUserTOTP confirms the pending TOTP secret of user with the time step
+// of a first code. It reports false if the code is already used.
+func (t *Table) EnableUserTOTP(login string, counter int64) (bool, error) {
+	return t.store.EnableUserTOTP(login, counter)
+}
+
+// AdvanceUserTOTPCounter records the time step of an accepted code. It
+// reports false if the time step isn't after the last accepted one.
+func (t *Table) AdvanceUserTOTPCounter(login string, counter int64) (bool, error) {
+	return t.store.AdvanceUserTOTPCounter(login, counter)
+}
+
+// AssignUserRecoveryCodes replaces the recovery code hashes of user, no
+// hashes remove them.
+func (t *Table) AssignUserRecoveryCodes(login string, hashes []string) error {
+	return t.store.AssignUserRecoveryCodes(login, hashes)
+}
+
+// ConsumeUserRecoveryCode removes a recovery code hash of user. It reports
+// whether the hash was found and the number of remaining ones.
+func (t *Table) ConsumeUserRecoveryCode(login string, hash string) (bool, int, error) {
+	return t.store.ConsumeUserRecoveryCode(login, hash)
+}
@@ -0,0 +1,163 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/parthoshuvo/authsvc/table/user"
+)
+
+// Optional claims which can be embedded in a token type, see TokenDef.Claims.
+const (
+	ClaimRoles         = "roles"
+	ClaimPermissions   = "permissions"
+	ClaimScope         = "scope"
+	ClaimName          = "name"
+	ClaimEmailVerified = "email_verified"
+)
+
+var optionalClaims = map[string]bool{
+	ClaimRoles:         true,
+	ClaimPermissions:   true,
+	ClaimScope:         true,
+	ClaimName:          true,
+	ClaimEmailVerified: true,
+}
+
+// Grants are the effective roles and permissions of a user.
+type Grants struct {
+	Roles       []string
+	Permissions []string
+}
+
+// GrantsReader fetches the grants of a user, the grants are embedded in tokens
+// configured with the roles, permissions or scope claims.
+type GrantsReader func(usr *user.User) (*Grants, error)
+
+// validateClaims checks the optional claims of a token definition.
+func (td TokenDef) validateClaims() error {
+	for _, claim := range td.Claims {
+		if !optionalClaims[claim] {
+			return fmt.Errorf("unknown optional claim: [%s]", claim)
+		}
+	}
+	return nil
+}
+
+func (td TokenDef) hasClaim(claim string) bool {
+	for _, c := range td.Claims {
+		if c == claim {
+			return true
+		}
+	}
+	return false
+}
+
+func (td TokenDef) needsGrants() bool {
+	return td.hasClaim(ClaimRoles) || td.hasClaim(ClaimPermissions) || td.hasClaim(ClaimScope)
+}
+
+// addOptionalClaims embeds the optional claims configured for the token type.
+// The scope claim is the space separated list of the permissions. Roles,
+// permissions and scope of the user are left out of tokens issued to a
+// client, which are limited to the scope granted to the client.
+func (svc *Service) addOptionalClaims(claims *JWTCustomClaims, usr *user.User, tokenDef *TokenDef) error {
+	if tokenDef.hasClaim(ClaimName) {
+		claims.Name = strings.TrimSpace(usr.Firstname + " " + usr.Lastname)
+	}
+	if tokenDef.hasClaim(ClaimEmailVerified) {
+		verified := usr.Verified
+		claims.EmailVerified = &verified
+	}
+	if !tokenDef.needsGrants() || claims.ClientID != "" {
+		return nil
+	}
+	if svc.grantsReader == nil {
+		return fmt.Errorf("no grants reader is registered for the claims: %v", tokenDef.Claims)
+	}
+	grants, err := svc.grantsReader(usr)
+	if err != nil {
+		return err
+	}
+	if tokenDef.hasClaim(ClaimRoles) {
+		claims.Roles = grants.Roles
+	}
+	if tokenDef.hasClaim(ClaimPermissions) {
+		claims.Permissions = grants.Permissions
+	}
+	if tokenDef.hasClaim(ClaimScope) {
+		claims.Scope = strings.Join(grants.Permissions, " ")
+	}
+	return nil
+}
+
+// Audience is the aud claim, a single audience is encoded as string.
+type Audience []string
+
+func (aud Audience) MarshalJSON() ([]byte, error) {
+	if len(aud) == 1 {
+		return json.Marshal(aud[0])
+	}
+	return json.Marshal([]string(aud))
+}
+
+func (aud *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*aud = Audience{single}
+		return nil
+	}
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return err
+	}
+	*aud = multi
+	return nil
+}
+
+func (aud Audience) contains(audience string) bool {
+	for _, a := range aud {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// with adds the audiences which aren't contained yet.
+func (aud Audience) with(audiences ...string) Audience {
+	for _, a := range audiences {
+		if !aud.contains(a) {
+			aud = append(aud, a)
+		}
+	}
+	return aud
+}
+
+// validateClaims checks the registered claims of a token issued for any of the
+// audiences. Time claims are checked with the configured leeway.
+func (svc *Service) validateClaims(claims *JWTCustomClaims, audience Audience) error {
+	now := jwt.TimeFunc().Unix()
+	leeway := svc.jwtDef.leeway()
+	switch {
+	case claims.ExpiresAt == 0 || now > claims.ExpiresAt+leeway:
+		return errors.New("token is expired")
+	case now+leeway < claims.IssuedAt:
+		return errors.New("token is used before issued")
+	case now+leeway < claims.NotBefore:
+		return errors.New("token is not valid yet")
+	case claims.Issuer != svc.jwtDef.issuer():
+		return fmt.Errorf("token is issued by another issuer: [%s]", claims.Issuer)
+	case claims.TokenID() == "":
+		return errors.New("token has no jti claim")
+	}
+	for _, a := range audience {
+		if claims.Audience.contains(a) {
+			return nil
+		}
+	}
+	return fmt.Errorf("token is issued for another audience: %v", []string(claims.Audience))
+}
@@ -0,0 +1,232 @@
+package token
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// deviceCodeExp is the lifetime of device and user codes.
+	deviceCodeExp = 10 * time.Minute
+	// deviceGrantRetention is how long a device grant is kept, it outlives
+	// its codes to answer late polls with expired_token.
+	deviceGrantRetention = 2 * deviceCodeExp
+	// DevicePollInterval is the minimum time between polls of a device.
+	DevicePollInterval = 5 * time.Second
+	// userCodeChars are the characters of user codes, consonants only to
+	// avoid ambiguous characters and words (RFC 8628 section 6.1).
+	userCodeChars  = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength = 8
+	// userCodeAttempts is the number of attempts to generate an unused user code.
+	userCodeAttempts = 3
+	// maxUserCodeFailures is the number of wrong user codes of a client IP,
+	// its lookups are refused after until the failures of
+	// userCodeFailureWindow have expired (RFC 8628 section 5.1).
+	maxUserCodeFailures = 10
+	// userCodeFailureWindow is the time wrong user codes of a client IP are
+	// counted in since the first one.
+	userCodeFailureWindow = 15 * time.Minute
+)
+
+const (
+	deviceGrantPending  = "pending"
+	deviceGrantApproved = "approved"
+	deviceGrantDenied   = "denied"
+)
+
+// Errors of polling device codes (RFC 8628 section 3.5).
+var (
+	ErrAuthorizationPending = errors.New("the user hasn't completed the authorization yet")
+	ErrSlowDown             = errors.New("the device polls too frequently")
+	ErrAccessDenied         = errors.New("the user denied the authorization")
+	ErrExpiredToken         = errors.New("device code has expired")
+)
+
+// ErrUserCodeLocked is returned for a client IP with too many wrong user
+// codes, its lookups are refused until the failures have expired.
+var ErrUserCodeLocked = errors.New("too many wrong user codes, try again later")
+
+// DeviceGrant is the authorization request of a device, the user approves or
+// denies it with the user code on another device (RFC 8628).
+type DeviceGrant struct {
+	AuthorizationGrant
+	DeviceCodeHash string
+	UserCode       string
+	Status         string
+	Expires        int64
+}
+
+// ExpiresIn is the remaining lifetime of the device grant.
+func (dg *DeviceGrant) ExpiresIn() time.Duration {
+	return time.Unix(dg.Expires, 0).Sub(jwt.TimeFunc())
+}
+
+func (dg *DeviceGrant) isExpired() bool {
+	return jwt.TimeFunc().Unix() >= dg.Expires
+}
+
+// NewDeviceAuthorization issues the device code and the user code of a
+// device authorization request. Only the hash of the device code is stored.
+func (svc *Service) NewDeviceAuthorization(authz *Authorization) (string, *DeviceGrant, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", nil, err
+	}
+	deviceCode := b64(data)
+	grant := &DeviceGrant{
+		AuthorizationGrant: AuthorizationGrant{
+			ClientID: authz.ClientID,
+			Scope:    authz.Scope,
+			Audience: authz.Audience,
+		},
+		DeviceCodeHash: codeHash(deviceCode),
+		Status:         deviceGrantPending,
+		Expires:        jwt.TimeFunc().Add(deviceCodeExp).Unix(),
+	}
+	for i := 0; i < userCodeAttempts; i++ {
+		userCode, err := newUserCode()
+		if err != nil {
+			return "", nil, err
+		}
+		grant.UserCode = userCode
+		ok, err := svc.cache.SetDeviceGrant(grant, deviceGrantRetention)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok {
+			return deviceCode, grant, nil
+		}
+	}
+	return "", nil, fmt.Errorf("no unused user code is found in %d attempts", userCodeAttempts)
+}
+
+// PendingDeviceGrant fetches the grant of a user code the user hasn't
+// approved or denied yet, nil if the code is unknown or expired. Wrong codes
+// are counted per client IP, ErrUserCodeLocked is returned after
+// maxUserCodeFailures within userCodeFailureWindow.
+func (svc *Service) PendingDeviceGrant(userCode, ip string) (*DeviceGrant, error) {
+	failures, err := svc.cache.GetUserCodeFailures(ip)
+	if err != nil {
+		return nil, err
+	}
+	if failures >= maxUserCodeFailures {
+		return nil, ErrUserCodeLocked
+	}
+	grant, err := svc.pendingDeviceGrant(userCode)
+	if err != nil || grant != nil {
+		return grant, err
+	}
+	return nil, svc.cache.IncrUserCodeFailures(ip, userCodeFailureWindow)
+}
+
+func (svc *Service) pendingDeviceGrant(userCode string) (*DeviceGrant, error) {
+	userCode = NormalizeUserCode(userCode)
+	if len(userCode) != userCodeLength {
+		return nil, nil
+	}
+	grant, err := svc.cache.GetDeviceGrantByUserCode(userCode)
+	if err != nil || grant == nil {
+		return nil, err
+	}
+	if grant.Status != deviceGrantPending || grant.isExpired() {
+		return nil, nil
+	}
+	return grant, nil
+}
+
+// ApproveDeviceGrant records the consent of the user to the device grant, the
+// next poll of the device issues the tokens.
+func (svc *Service) ApproveDeviceGrant(grant *DeviceGrant, login string) error {
+	grant.Status = deviceGrantApproved
+	grant.Login = login
+	grant.AuthTime = jwt.TimeFunc().Unix()
+	return svc.cache.UpdateDeviceGrant(grant)
+}
+
+// DenyDeviceGrant records the refusal of the user, the next poll of the
+// device fails with access_denied.
+func (svc *Service) DenyDeviceGrant(grant *DeviceGrant) error {
+	grant.Status = deviceGrantDenied
+	return svc.cache.UpdateDeviceGrant(grant)
+}
+
+// PollDeviceGrant checks the device grant of a device code issued to the
+// client. An approved grant is consumed and returned, otherwise one of the
+// polling errors or ErrInvalidGrant is returned.
+func (svc *Service) PollDeviceGrant(deviceCode, clientID string) (*AuthorizationGrant, error) {
+	hash := codeHash(deviceCode)
+	grant, err := svc.cache.GetDeviceGrant(hash)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case grant == nil:
+		return nil, ErrInvalidGrant
+	case grant.ClientID != clientID:
+		return nil, fmt.Errorf("%w: device code is issued to another client", ErrInvalidGrant)
+	case grant.isExpired():
+		return nil, ErrExpiredToken
+	}
+	polled, err := svc.cache.PollDeviceGrant(hash, DevicePollInterval)
+	if err != nil {
+		return nil, err
+	}
+	if !polled {
+		return nil, ErrSlowDown
+	}
+	if grant.Status == deviceGrantPending {
+		return nil, ErrAuthorizationPending
+	}
+	consumed, err := svc.cache.ConsumeDeviceGrant(grant)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !consumed:
+		return nil, ErrInvalidGrant
+	case grant.Status == deviceGrantDenied:
+		return nil, ErrAccessDenied
+	}
+	return &grant.AuthorizationGrant, nil
+}
+
+// NormalizeUserCode removes the separators of a user code the user entered
+// and converts it to upper case.
+func NormalizeUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		if strings.ContainsRune(userCodeChars, r) {
+			return r
+		}
+		return -1
+	}, userCode)
+}
+
+// FormatUserCode splits a user code in two halves to ease typing, e.g. BDFH-JKLM.
+func FormatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+func newUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeChars)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeChars[n.Int64()]
+	}
+	return string(code), nil
+}
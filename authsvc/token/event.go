@@ -0,0 +1,39 @@
+package token
+
+import "time"
+
+// Security event types.
+const (
+	EventRefreshTokenReuse       = "refresh_token_reuse"
+	EventAuthorizationCodeMisuse = "authorization_code_misuse"
+	EventWebAuthnCloneDetected   = "webauthn_clone_detected"
+)
+
+// SecurityEvent describes a security relevant incident of a user's session,
+// grant or WebAuthn credential, the client is the one causing it if any.
+type SecurityEvent struct {
+	Type         string
+	UserID       string
+	Subject      string
+	SessionID    string
+	ClientID     string
+	CredentialID string
+	UserAgent    string
+	IP           string
+	Time         time.Time
+}
+
+// SecurityEventHandler receives security events.
+type SecurityEventHandler func(*SecurityEvent)
+
+func newSecurityEvent(eventType, subject string, sess *Session) *SecurityEvent {
+	return &SecurityEvent{
+		Type:      eventType,
+		UserID:    sess.UserID,
+		Subject:   subject,
+		SessionID: sess.ID,
+		UserAgent: sess.UserAgent,
+		IP:        sess.IP,
+		Time:      time.Now(),
+	}
+}
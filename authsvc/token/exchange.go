@@ -0,0 +1,56 @@
+package token
+
+// Token types of token exchange requests (RFC 8693 section 3).
+const (
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// Actor is the act claim of a delegated token (RFC 8693 section 4.1), the
+// party acting on behalf of the subject. Prior actors of the chain are nested.
+type Actor struct {
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id,omitempty"`
+	Actor    *Actor `json:"act,omitempty"`
+}
+
+// TokenExchange is the exchange of a subject token by a client, optionally
+// on behalf of the actor of an actor token (RFC 8693).
+type TokenExchange struct {
+	Subject  *JWTCustomClaims
+	Actor    *JWTCustomClaims
+	ClientID string
+	Scope    string
+	Audience Audience
+}
+
+// ExchangeToken creates an access token of the subject for the audience and
+// scope of the exchange, it doesn't outlive the subject token. The actor is
+// added in front of the act claim chain of the subject token, without actor
+// the chain is kept i.e. the client impersonates the subject. Roles and
+// permissions of the subject aren't carried over, the scope is. authsvc's own
+// audience is granted only if it is requested explicitly with the openid
+// scope, the token is a token of the client all the same.
+func (svc *Service) ExchangeToken(xchg *TokenExchange) (*AuthToken, error) {
+	subject := xchg.Subject
+	userInfo := xchg.Audience.contains(svc.jwtDef.issuer()) && HasScope(xchg.Scope, ScopeOpenID)
+	claims := svc.newClaims(subject.Subject(), svc.jwtDef.AccessToken, svc.jwtDef.clientAudience(xchg.Audience, userInfo))
+	claims.ID = subject.ID
+	claims.SessionID = subject.SessionID
+	claims.ClientID = xchg.ClientID
+	claims.Name = subject.Name
+	claims.EmailVerified = subject.EmailVerified
+	claims.Scope = xchg.Scope
+	claims.Actor = subject.Actor
+	if xchg.Actor != nil {
+		claims.Actor = &Actor{
+			Subject:  xchg.Actor.Subject(),
+			ClientID: xchg.Actor.ClientID,
+			Actor:    subject.Actor,
+		}
+	}
+	if subject.ExpiresAt < claims.ExpiresAt {
+		claims.ExpiresAt = subject.ExpiresAt
+	}
+	return svc.signToken(claims, svc.accessRing)
+}
@@ -0,0 +1,19 @@
+package token
+
+// JWK is a public JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty   string `json:"kty"`
+	KeyID string `json:"kid"`
+	Use   string `json:"use"`
+	Alg   string `json:"alg"`
+	N     string `json:"n,omitempty"`
+	E     string `json:"e,omitempty"`
+	Crv   string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKSet is a set of public JSON Web Keys.
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
@@ -0,0 +1,253 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const defaultAlg = "HS256"
+
+// signingKey is a key used for signing and verifying tokens.
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	created   time.Time
+}
+
+// newSigningKey creates a signing key from a token definition. HMAC algorithms
+// use the secret, asymmetric algorithms load the private key from a PEM file.
+func newSigningKey(td *TokenDef) (*signingKey, error) {
+	method := jwt.GetSigningMethod(td.alg())
+	if method == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm: [%s]", td.Alg)
+	}
+	var signKey, verifyKey interface{}
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if td.Secret == "" {
+			return nil, fmt.Errorf("secret is required for signing algorithm: [%s]", method.Alg())
+		}
+		signKey, verifyKey = []byte(td.Secret), []byte(td.Secret)
+	default:
+		privKey, err := loadPrivateKey(method, td.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signKey, verifyKey = privKey, privKey.Public()
+	}
+	return keyOf(td.KeyID, method, signKey, verifyKey, time.Now())
+}
+
+// generateSigningKey creates a fresh random key of the same algorithm and size as tmpl.
+func generateSigningKey(tmpl *signingKey) (*signingKey, error) {
+	var signKey, verifyKey interface{}
+	switch key := tmpl.signKey.(type) {
+	case []byte:
+		secret := make([]byte, tmpl.method.(*jwt.SigningMethodHMAC).Hash.Size())
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		signKey, verifyKey = secret, secret
+	case *rsa.PrivateKey:
+		privKey, err := rsa.GenerateKey(rand.Reader, key.N.BitLen())
+		if err != nil {
+			return nil, err
+		}
+		signKey, verifyKey = privKey, privKey.Public()
+	case *ecdsa.PrivateKey:
+		privKey, err := ecdsa.GenerateKey(key.Curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signKey, verifyKey = privKey, privKey.Public()
+	case ed25519.PrivateKey:
+		pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signKey, verifyKey = privKey, pubKey
+	default:
+		return nil, fmt.Errorf("unsupported signing key: [%T]", tmpl.signKey)
+	}
+	return keyOf("", tmpl.method, signKey, verifyKey, time.Now())
+}
+
+// keyOf creates a signing key. The kid defaults to the key thumbprint.
+func keyOf(kid string, method jwt.SigningMethod, signKey, verifyKey interface{}, created time.Time) (*signingKey, error) {
+	sk := &signingKey{kid, method, signKey, verifyKey, created}
+	if sk.kid == "" {
+		kid, err := sk.thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		sk.kid = kid
+	}
+	return sk, nil
+}
+
+func loadPrivateKey(method jwt.SigningMethod, keyFile string) (crypto.Signer, error) {
+	if keyFile == "" {
+		return nil, fmt.Errorf("key file is required for signing algorithm: [%s]", method.Alg())
+	}
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: [%v]", keyFile, err)
+	}
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPrivateKeyFromPEM(data)
+	case *jwt.SigningMethodECDSA:
+		key, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		if key.Curve.Params().BitSize != m.CurveBits {
+			return nil, fmt.Errorf("key file %s doesn't hold a %d bit curve required by %s", keyFile, m.CurveBits, m.Alg())
+		}
+		return key, nil
+	case *jwt.SigningMethodEd25519:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return key.(crypto.Signer), nil
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm: [%s]", method.Alg())
+}
+
+// marshal encodes the secret or the PKCS #8 private key.
+func (sk *signingKey) marshal() ([]byte, error) {
+	if secret, ok := sk.signKey.([]byte); ok {
+		return secret, nil
+	}
+	return x509.MarshalPKCS8PrivateKey(sk.signKey)
+}
+
+// unmarshalSigningKey decodes a key encoded by marshal.
+func unmarshalSigningKey(kid, alg string, data []byte, created time.Time) (*signingKey, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm: [%s]", alg)
+	}
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		return keyOf(kid, method, data, data, created)
+	}
+	privKey, err := x509.ParsePKCS8PrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := privKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key: [%T]", privKey)
+	}
+	return keyOf(kid, method, signer, signer.Public(), created)
+}
+
+// isPublic reports whether the verification key can be published.
+func (sk *signingKey) isPublic() bool {
+	_, ok := sk.method.(*jwt.SigningMethodHMAC)
+	return !ok
+}
+
+// sign signs the claims as a token of the typ header.
+func (sk *signingKey) sign(claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(sk.method, claims)
+	token.Header["kid"] = sk.kid
+	token.Header["typ"] = typ
+	return token.SignedString(sk.signKey)
+}
+
+// jwk renders the public part of the key as a JSON Web Key (RFC 7517).
+func (sk *signingKey) jwk() *JWK {
+	jwk := &JWK{KeyID: sk.kid, Use: "sig", Alg: sk.method.Alg()}
+	switch key := sk.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(key.N.Bytes())
+		jwk.E = b64(bigEndian(key.E))
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = b64(padded(key.X.Bytes(), size))
+		jwk.Y = b64(padded(key.Y.Bytes(), size))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(key)
+	}
+	return jwk
+}
+
+// thumbprint computes the JWK thumbprint (RFC 7638) of the key.
+func (sk *signingKey) thumbprint() (string, error) {
+	var members interface{}
+	if secret, ok := sk.verifyKey.([]byte); ok {
+		members = struct {
+			K   string `json:"k"`
+			Kty string `json:"kty"`
+		}{b64(secret), "oct"}
+	} else {
+		jwk := sk.jwk()
+		switch jwk.Kty {
+		case "RSA":
+			members = struct {
+				E   string `json:"e"`
+				Kty string `json:"kty"`
+				N   string `json:"n"`
+			}{jwk.E, jwk.Kty, jwk.N}
+		case "EC":
+			members = struct {
+				Crv string `json:"crv"`
+				Kty string `json:"kty"`
+				X   string `json:"x"`
+				Y   string `json:"y"`
+			}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+		default:
+			members = struct {
+				Crv string `json:"crv"`
+				Kty string `json:"kty"`
+				X   string `json:"x"`
+			}{jwk.Crv, jwk.Kty, jwk.X}
+		}
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return b64(sum[:]), nil
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func bigEndian(n int) []byte {
+	data := make([]byte, 0, 8)
+	for ; n > 0; n >>= 8 {
+		data = append([]byte{byte(n)}, data...)
+	}
+	return data
+}
+
+func padded(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+	return append(make([]byte, size-len(data)), data...)
+}
@@ -0,0 +1,240 @@
+package token
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// KeyringDef defines how signing keys are persisted and rotated.
+type KeyringDef struct {
+	Dir         string
+	RotateEvery ExpireTime
+}
+
+// KeyInfo describes a signing key of a keyring.
+type KeyInfo struct {
+	KeyID     string `json:"kid"`
+	Alg       string `json:"alg"`
+	TokenType string `json:"token_type"`
+	Status    string `json:"status"`
+	Created   int64  `json:"created"`
+	Expires   int64  `json:"expires,omitempty"`
+}
+
+const (
+	keyStatusActive  = "active"
+	keyStatusRetired = "retired"
+)
+
+type retiredKey struct {
+	*signingKey
+	expires time.Time
+}
+
+// keyring holds the active signing key of a token type and the retired keys
+// that still verify tokens issued before a rotation. A retired key is dropped
+// once the longest lived token it may have signed has expired, the leeway on
+// verifying the expiry included.
+type keyring struct {
+	mu         sync.RWMutex
+	name       string
+	typ        string
+	lifetime   time.Duration
+	file       string
+	configured string
+	active     *signingKey
+	retired    []*retiredKey
+}
+
+// keyringFile is the persisted form of a keyring.
+type keyringFile struct {
+	Configured string        `json:"configured"`
+	Keys       []*keyringKey `json:"keys"`
+}
+
+type keyringKey struct {
+	KeyID   string `json:"kid"`
+	Alg     string `json:"alg"`
+	Key     []byte `json:"key"`
+	Created int64  `json:"created"`
+	Expires int64  `json:"expires,omitempty"`
+}
+
+// newKeyring creates the keyring of a token type. A persisted keyring is
+// restored from dir; the configured key becomes active whenever it changes.
+func newKeyring(name string, td *TokenDef, leeway time.Duration, dir string) (*keyring, error) {
+	configured, err := newSigningKey(td)
+	if err != nil {
+		return nil, err
+	}
+	kr := &keyring{name: name, typ: tokenTypeHeaders[name], lifetime: td.Exp.duration() + leeway}
+	if dir != "" {
+		kr.file = filepath.Join(dir, name+".json")
+		if err := kr.load(); err != nil {
+			return nil, err
+		}
+	}
+	if kr.active == nil || kr.configured != configured.kid {
+		kr.configured = configured.kid
+		kr.activate(configured)
+		if err := kr.save(); err != nil {
+			return nil, err
+		}
+	}
+	return kr, nil
+}
+
+// signer provides the active signing key.
+func (kr *keyring) signer() *signingKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.active
+}
+
+// verifier finds the key by kid. Tokens without kid are verified by the active key.
+func (kr *keyring) verifier(kid string) *signingKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	if kid == "" || kid == kr.active.kid {
+		return kr.active
+	}
+	now := time.Now()
+	for _, rk := range kr.retired {
+		if rk.kid == kid && now.Before(rk.expires) {
+			return rk.signingKey
+		}
+	}
+	return nil
+}
+
+// rotate retires the active key and activates a freshly generated one.
+func (kr *keyring) rotate() (*signingKey, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	key, err := generateSigningKey(kr.active)
+	if err != nil {
+		return nil, err
+	}
+	kr.activate(key)
+	return key, kr.save()
+}
+
+// rotationDue reports whether the active key is older than interval.
+func (kr *keyring) rotationDue(interval time.Duration) bool {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return time.Since(kr.active.created) >= interval
+}
+
+func (kr *keyring) publicKeys() []*JWK {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	keys := make([]*JWK, 0, len(kr.retired)+1)
+	for _, key := range kr.keys() {
+		if key.isPublic() {
+			keys = append(keys, key.jwk())
+		}
+	}
+	return keys
+}
+
+func (kr *keyring) info() []*KeyInfo {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	info := []*KeyInfo{{kr.active.kid, kr.active.method.Alg(), kr.name, keyStatusActive, kr.active.created.Unix(), 0}}
+	for _, rk := range kr.retired {
+		info = append(info, &KeyInfo{rk.kid, rk.method.Alg(), kr.name, keyStatusRetired, rk.created.Unix(), rk.expires.Unix()})
+	}
+	return info
+}
+
+// activate makes key the signing key, the former one is retired.
+func (kr *keyring) activate(key *signingKey) {
+	if kr.active != nil && kr.active.kid != key.kid {
+		kr.retired = append(kr.retired, &retiredKey{kr.active, time.Now().Add(kr.lifetime)})
+	}
+	kr.active = key
+	kr.prune()
+}
+
+// prune drops the retired keys that can't verify an unexpired token anymore.
+func (kr *keyring) prune() {
+	now := time.Now()
+	retired := kr.retired[:0]
+	for _, rk := range kr.retired {
+		if now.Before(rk.expires) && rk.kid != kr.active.kid {
+			retired = append(retired, rk)
+		}
+	}
+	kr.retired = retired
+}
+
+func (kr *keyring) keys() []*signingKey {
+	keys := []*signingKey{kr.active}
+	for _, rk := range kr.retired {
+		keys = append(keys, rk.signingKey)
+	}
+	return keys
+}
+
+func (kr *keyring) load() error {
+	data, err := ioutil.ReadFile(kr.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var krf keyringFile
+	if err := json.Unmarshal(data, &krf); err != nil {
+		return err
+	}
+	kr.configured = krf.Configured
+	for _, k := range krf.Keys {
+		key, err := unmarshalSigningKey(k.KeyID, k.Alg, k.Key, time.Unix(k.Created, 0))
+		if err != nil {
+			return err
+		}
+		if k.Expires == 0 {
+			kr.active = key
+		} else {
+			kr.retired = append(kr.retired, &retiredKey{key, time.Unix(k.Expires, 0)})
+		}
+	}
+	if kr.active != nil {
+		kr.prune()
+	}
+	return nil
+}
+
+// save persists the keyring if it has a file. The file holds private keys,
+// hence it is only readable by the owner.
+func (kr *keyring) save() error {
+	if kr.file == "" {
+		return nil
+	}
+	krf := keyringFile{Configured: kr.configured}
+	for _, key := range kr.keys() {
+		data, err := key.marshal()
+		if err != nil {
+			return err
+		}
+		krf.Keys = append(krf.Keys, &keyringKey{key.kid, key.method.Alg(), data, key.created.Unix(), 0})
+	}
+	for i, rk := range kr.retired {
+		krf.Keys[i+1].Expires = rk.expires.Unix()
+	}
+	data, err := json.Marshal(krf)
+	if err != nil {
+		return err
+	}
+	tmp := kr.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, kr.file)
+}
@@ -0,0 +1,106 @@
+package token
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+)
+
+const (
+	// mfaChallengeExp is the lifetime of MFA challenge tokens.
+	mfaChallengeExp = 5 * time.Minute
+	// maxMFAAttempts is the number of wrong codes a challenge is revoked after.
+	maxMFAAttempts = 5
+	// maxMFAFailures is the number of wrong codes of a user, over all of its
+	// challenges and login pages, codes are refused after until the failures
+	// of mfaFailureWindow have expired.
+	maxMFAFailures = 10
+	// mfaFailureWindow is the time wrong codes of a user are counted in since
+	// the first one.
+	mfaFailureWindow = 15 * time.Minute
+)
+
+// ErrMFAChallengeInvalid is returned for an unknown, expired, used or revoked
+// MFA challenge token.
+var ErrMFAChallengeInvalid = errors.New("mfa challenge is invalid, expired or already used")
+
+// ErrMFALocked is returned for a user with too many wrong codes, its codes
+// aren't verified until the failures have expired.
+var ErrMFALocked = errors.New("too many wrong authentication codes, try again later")
+
+// MFAChallenge is the second step of the login of a user with two-factor
+// authentication, the password step is passed.
+type MFAChallenge struct {
+	Token   string        `json:"mfa_token"`
+	Expires time.Duration `json:"expires"`
+}
+
+// NewMFAChallenge issues a single-use MFA challenge token of the user with
+// the login. Only the hash of the token is stored.
+func (svc *Service) NewMFAChallenge(login string) (*MFAChallenge, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+	challenge := b64(data)
+	if err := svc.cache.SetMFAChallenge(codeHash(challenge), login, mfaChallengeExp); err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{challenge, time.Duration(mfaChallengeExp.Seconds())}, nil
+}
+
+// MFAChallengeLogin provides the login of the user of an MFA challenge token.
+func (svc *Service) MFAChallengeLogin(challenge string) (string, error) {
+	login, err := svc.cache.GetMFAChallenge(codeHash(challenge))
+	if err != nil {
+		return "", err
+	}
+	if login == "" {
+		return "", ErrMFAChallengeInvalid
+	}
+	return login, nil
+}
+
+// FailMFAChallenge records a wrong code of an MFA challenge token, the token
+// is revoked after maxMFAAttempts wrong codes.
+func (svc *Service) FailMFAChallenge(challenge string) error {
+	return svc.cache.FailMFAChallenge(codeHash(challenge), maxMFAAttempts)
+}
+
+// CompleteMFAChallenge consumes an MFA challenge token redeemed with a valid
+// code, only one of concurrent redemptions succeeds.
+func (svc *Service) CompleteMFAChallenge(challenge string) error {
+	ok, err := svc.cache.ConsumeMFAChallenge(codeHash(challenge))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMFAChallengeInvalid
+	}
+	return nil
+}
+
+// CheckMFALogin returns ErrMFALocked if the user with the login has entered
+// maxMFAFailures wrong codes within mfaFailureWindow.
+func (svc *Service) CheckMFALogin(login string) error {
+	failures, err := svc.cache.GetMFAFailures(login)
+	if err != nil {
+		return err
+	}
+	if failures >= maxMFAFailures {
+		return ErrMFALocked
+	}
+	return nil
+}
+
+// FailMFALogin records a wrong code of the user with the login, whichever
+// challenge or login page it is entered in.
+func (svc *Service) FailMFALogin(login string) error {
+	return svc.cache.IncrMFAFailures(login, mfaFailureWindow)
+}
+
+// ResetMFALogin clears the wrong codes of the user with the login after a
+// successful login.
+func (svc *Service) ResetMFALogin(login string) error {
+	return svc.cache.DelMFAFailures(login)
+}
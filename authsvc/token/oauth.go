@@ -0,0 +1,117 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/parthoshuvo/authsvc/table/user"
+)
+
+// CodeChallengeS256 is the only supported PKCE code challenge method (RFC 7636).
+const CodeChallengeS256 = "S256"
+
+// authorizationCodeExp is the lifetime of authorization codes.
+const authorizationCodeExp = time.Minute
+
+// ErrInvalidGrant is returned for an invalid, expired or already used
+// authorization code and for a code verifier or client mismatch.
+var ErrInvalidGrant = errors.New("authorization grant is invalid, expired or already used")
+
+// AuthorizationGrant is the consent of a user to a client, an authorization
+// code is issued for (RFC 6749 section 4.1).
+type AuthorizationGrant struct {
+	ClientID      string
+	RedirectURI   string
+	Scope         string
+	Audience      Audience
+	CodeChallenge string
+	Nonce         string
+	Login         string
+	AuthTime      int64
+}
+
+// Authorization is the access of a client to the resources of a user.
+type Authorization struct {
+	ClientID string
+	Scope    string
+	Audience Audience
+}
+
+// NewAuthorizationCode issues a single-use authorization code of a grant. Only
+// the hash of the code is stored.
+func (svc *Service) NewAuthorizationCode(grant *AuthorizationGrant) (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	code := b64(data)
+	if grant.AuthTime == 0 {
+		grant.AuthTime = jwt.TimeFunc().Unix()
+	}
+	if err := svc.cache.SetAuthorizationCode(codeHash(code), grant, authorizationCodeExp); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeAuthorizationCode consumes an authorization code issued to the
+// client for the redirect URI. The code verifier must match the S256 code
+// challenge of the authorization request. A code presented by another client
+// has leaked, it is consumed all the same and the misuse is reported.
+func (svc *Service) ExchangeAuthorizationCode(code, clientID, redirectURI, codeVerifier string) (*AuthorizationGrant, error) {
+	grant, err := svc.cache.ConsumeAuthorizationCode(codeHash(code))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case grant == nil:
+		return nil, ErrInvalidGrant
+	case grant.ClientID != clientID:
+		svc.emit(&SecurityEvent{Type: EventAuthorizationCodeMisuse, Subject: grant.Login, ClientID: clientID, Time: time.Now()})
+		return nil, fmt.Errorf("%w: code is issued to another client", ErrInvalidGrant)
+	case grant.RedirectURI != redirectURI:
+		return nil, fmt.Errorf("%w: redirect_uri mismatch", ErrInvalidGrant)
+	case !VerifyCodeChallenge(grant.CodeChallenge, codeVerifier):
+		return nil, fmt.Errorf("%w: code_verifier mismatch", ErrInvalidGrant)
+	}
+	return grant, nil
+}
+
+// NewClientTokenPair creates a token pair of a new session of the user
+// authorizing a client.
+func (svc *Service) NewClientTokenPair(usr *user.User, authz *Authorization, device *Device) (*AuthTokenPair, error) {
+	sess := newSession(usr.RowGUID, device)
+	sess.authorize(authz)
+	return svc.createAuthTokenPair(usr, sess)
+}
+
+// NewClientAccessToken creates an access token of a client acting on its own
+// behalf (RFC 6749 section 4.4). The subject of the token is the client, no
+// refresh token is issued. The token is never issued for authsvc itself, its
+// subject isn't a user.
+func (svc *Service) NewClientAccessToken(authz *Authorization) (*AuthToken, error) {
+	claims := svc.newClaims(authz.ClientID, svc.jwtDef.AccessToken, svc.jwtDef.clientAudience(authz.Audience, false))
+	claims.ClientID = authz.ClientID
+	claims.Scope = authz.Scope
+	return svc.signToken(claims, svc.accessRing)
+}
+
+// VerifyCodeChallenge checks a PKCE code verifier against a S256 code
+// challenge in constant time.
+func VerifyCodeChallenge(codeChallenge, codeVerifier string) bool {
+	if len(codeVerifier) < 43 || len(codeVerifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return subtle.ConstantTimeCompare([]byte(b64(sum[:])), []byte(codeChallenge)) == 1
+}
+
+func codeHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return b64(sum[:])
+}
@@ -0,0 +1,120 @@
+package token
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/parthoshuvo/authsvc/table/user"
+)
+
+// Scopes of OpenID Connect requests (OpenID Connect Core 1.0 section 5.4).
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// UserInfo holds the standard claims of a user released to a client.
+type UserInfo struct {
+	Name          string `json:"name,omitempty"`
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// NewUserInfo releases the claims of a user permitted by the scope, the name
+// claims by the profile scope and the email claims by the email scope.
+func NewUserInfo(firstname, lastname, email string, emailVerified bool, scope string) *UserInfo {
+	info := &UserInfo{}
+	if HasScope(scope, ScopeProfile) {
+		info.Name = strings.TrimSpace(firstname + " " + lastname)
+		info.GivenName = firstname
+		info.FamilyName = lastname
+	}
+	if HasScope(scope, ScopeEmail) {
+		info.Email = email
+		info.EmailVerified = &emailVerified
+	}
+	return info
+}
+
+// HasScope checks whether the space separated scope contains the scope s.
+func HasScope(scope, s string) bool {
+	for _, f := range strings.Fields(scope) {
+		if f == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IDTokenClaims are the claims of an ID token (OpenID Connect Core 1.0
+// section 2), its subject is the ID of the user.
+type IDTokenClaims struct {
+	Nonce           string `json:"nonce,omitempty"`
+	AuthTime        int64  `json:"auth_time,omitempty"`
+	AccessTokenHash string `json:"at_hash,omitempty"`
+	*UserInfo
+	jwt.StandardClaims
+}
+
+// NewIDToken creates the ID token of a user authorizing a client with the
+// openid scope. The at_hash claim binds it to the access token issued with it.
+func (svc *Service) NewIDToken(usr *user.User, grant *AuthorizationGrant, accessToken string) (string, error) {
+	key := svc.idRing.signer()
+	atHash, err := accessTokenHash(key.method, accessToken)
+	if err != nil {
+		return "", err
+	}
+	now := jwt.TimeFunc().Unix()
+	claims := &IDTokenClaims{
+		Nonce:           grant.Nonce,
+		AuthTime:        grant.AuthTime,
+		AccessTokenHash: atHash,
+		UserInfo:        NewUserInfo(usr.Firstname, usr.Lastname, usr.Email.String(), usr.Verified, grant.Scope),
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Issuer:    svc.jwtDef.issuer(),
+			Audience:  grant.ClientID,
+			IssuedAt:  now,
+			Subject:   usr.RowGUID,
+			ExpiresAt: svc.jwtDef.idTokenDef().ExpiresAt(),
+		},
+	}
+	return key.sign(claims, svc.idRing.typ)
+}
+
+// Issuer provides the iss claim of all tokens.
+func (svc *Service) Issuer() string {
+	return svc.jwtDef.issuer()
+}
+
+// IDTokenAlg provides the signing algorithm of ID tokens.
+func (svc *Service) IDTokenAlg() string {
+	return svc.idRing.signer().method.Alg()
+}
+
+// accessTokenHash is the left half of the hash of the access token, hashed
+// with the hash function of the signing algorithm of the ID token.
+func accessTokenHash(method jwt.SigningMethod, accessToken string) (string, error) {
+	var h hash.Hash
+	switch alg := method.Alg(); {
+	case strings.HasSuffix(alg, "256"):
+		h = sha256.New()
+	case strings.HasSuffix(alg, "384"):
+		h = sha512.New384()
+	case strings.HasSuffix(alg, "512"), alg == "EdDSA":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("no at_hash function for signing algorithm: [%s]", alg)
+	}
+	h.Write([]byte(accessToken))
+	sum := h.Sum(nil)
+	return b64(sum[:len(sum)/2]), nil
+}
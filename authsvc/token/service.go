@@ -3,15 +3,26 @@ package token
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
+	log "github.com/parthoshuvo/authsvc/log4u"
 	"github.com/parthoshuvo/authsvc/table/user"
 )
 
 type JWTCustomClaims struct {
-	ID  string `json:"id"`
-	UID string `json:"uid"`
+	ID            string   `json:"id"`
+	Audience      Audience `json:"aud,omitempty"`
+	SessionID     string   `json:"sid,omitempty"`
+	ClientID      string   `json:"client_id,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	EmailVerified *bool    `json:"email_verified,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	Permissions   []string `json:"permissions,omitempty"`
+	Scope         string   `json:"scope,omitempty"`
+	Actor         *Actor   `json:"act,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -19,31 +30,158 @@ func (claims *JWTCustomClaims) Subject() string {
 	return claims.StandardClaims.Subject
 }
 
+// TokenID provides the unique ID (jti) of the token.
+func (claims *JWTCustomClaims) TokenID() string {
+	return claims.StandardClaims.Id
+}
+
 type Cache interface {
-	SetRefreshToken(*AuthToken) error
-	GetRefreshToken(string) (string, error)
-	RevokeRefreshToken(string) error
+	SetSession(*Session, time.Duration) error
+	RotateSession(*Session, string, time.Duration) (bool, error)
+	GetSession(string) (*Session, error)
+	GetUserSessions(string) ([]*Session, error)
+	RevokeSession(*Session) error
+	DenyAccessToken(string, time.Duration) error
+	IsAccessTokenDenied(string) (bool, error)
+	SetPasswordResetToken(string, string, time.Duration) error
+	ConsumePasswordResetToken(string) (bool, error)
+	RevokePasswordResetTokens(string) error
+	SetAuthorizationCode(string, *AuthorizationGrant, time.Duration) error
+	ConsumeAuthorizationCode(string) (*AuthorizationGrant, error)
+	SetDeviceGrant(*DeviceGrant, time.Duration) (bool, error)
+	GetDeviceGrant(string) (*DeviceGrant, error)
+	GetDeviceGrantByUserCode(string) (*DeviceGrant, error)
+	UpdateDeviceGrant(*DeviceGrant) error
+	PollDeviceGrant(string, time.Duration) (bool, error)
+	ConsumeDeviceGrant(*DeviceGrant) (bool, error)
+	GetUserCodeFailures(string) (int, error)
+	IncrUserCodeFailures(string, time.Duration) error
+	SetMFAChallenge(string, string, time.Duration) error
+	GetMFAChallenge(string) (string, error)
+	FailMFAChallenge(string, int) error
+	ConsumeMFAChallenge(string) (bool, error)
+	GetMFAFailures(string) (int, error)
+	IncrMFAFailures(string, time.Duration) error
+	DelMFAFailures(string) error
+	SetWebAuthnChallenge(string, *WebAuthnChallenge, time.Duration) error
+	ConsumeWebAuthnChallenge(string) (*WebAuthnChallenge, error)
 }
 
+// ErrRefreshTokenReuse is returned for a refresh token that has already been
+// rotated. The session, i.e. the whole token family, is revoked in that case.
+var ErrRefreshTokenReuse = errors.New("refresh token reuse is detected")
+
+// errSessionChanged is returned if a session is rotated or revoked while its
+// refresh token is rotated.
+var errSessionChanged = errors.New("session is changed concurrently")
+
 type Service struct {
-	jwtDef *JWTDef
-	cache  Cache
+	jwtDef        *JWTDef
+	cache         Cache
+	accessRing    *keyring
+	refreshRing   *keyring
+	resetRing     *keyring
+	idRing        *keyring
+	eventHandlers []SecurityEventHandler
+	grantsReader  GrantsReader
 }
 
 func NewService(jwtDef *JWTDef, cache Cache) *Service {
-	return &Service{jwtDef, cache}
+	krDef := jwtDef.keyringDef()
+	for _, td := range []*TokenDef{jwtDef.AccessToken, jwtDef.RefreshToken, jwtDef.passwordResetTokenDef(), jwtDef.idTokenDef()} {
+		if err := td.validateClaims(); err != nil {
+			log.Fatalf("invalid token definition: [%v]", err)
+		}
+	}
+	leeway := time.Duration(jwtDef.leeway()) * time.Second
+	accessRing, err := newKeyring(accessTokenType, jwtDef.AccessToken, leeway, krDef.Dir)
+	if err != nil {
+		log.Fatalf("failed to load access token signing keys: [%v]", err)
+	}
+	refreshRing, err := newKeyring(refreshTokenType, jwtDef.RefreshToken, leeway, krDef.Dir)
+	if err != nil {
+		log.Fatalf("failed to load refresh token signing keys: [%v]", err)
+	}
+	resetRing, err := newKeyring(resetTokenType, jwtDef.passwordResetTokenDef(), leeway, krDef.Dir)
+	if err != nil {
+		log.Fatalf("failed to load password reset token signing keys: [%v]", err)
+	}
+	idRing, err := newKeyring(idTokenType, jwtDef.idTokenDef(), leeway, krDef.Dir)
+	if err != nil {
+		log.Fatalf("failed to load ID token signing keys: [%v]", err)
+	}
+	svc := &Service{jwtDef, cache, accessRing, refreshRing, resetRing, idRing, nil, nil}
+	if krDef.RotateEvery > 0 {
+		go svc.scheduleKeyRotation(krDef.RotateEvery.duration())
+	}
+	return svc
+}
+
+// OnSecurityEvent registers a handler of security events.
+func (svc *Service) OnSecurityEvent(handler SecurityEventHandler) {
+	svc.eventHandlers = append(svc.eventHandlers, handler)
+}
+
+// UseGrants registers the reader of the roles and permissions embedded in
+// tokens configured with the roles, permissions or scope claims.
+func (svc *Service) UseGrants(reader GrantsReader) {
+	svc.grantsReader = reader
+}
+
+// NewAuthTokenPair creates a token pair of a new session of the user.
+func (svc *Service) NewAuthTokenPair(usr *user.User, device *Device) (*AuthTokenPair, error) {
+	return svc.createAuthTokenPair(usr, newSession(usr.RowGUID, device))
+}
+
+// RenewAuthTokenPair creates a token pair of the session of a verified refresh
+// token. The refresh token is rotated, hence the presented one becomes invalid.
+// The rotation succeeds only if the presented token is still current in the
+// session, a concurrent rotation with the same token is a reuse.
+func (svc *Service) RenewAuthTokenPair(usr *user.User, claims *JWTCustomClaims, device *Device) (*AuthTokenPair, error) {
+	sess, err := svc.cache.GetSession(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, errors.New("session is expired")
+	}
+	sess.use(device, time.Now().Unix())
+	pair, err := svc.issueAuthTokenPair(usr, sess, claims.TokenID())
+	if err != errSessionChanged {
+		return pair, err
+	}
+	if sess, err = svc.cache.GetSession(claims.SessionID); err != nil || sess == nil {
+		return nil, errors.New("session is expired")
+	}
+	if err := svc.cache.RevokeSession(sess); err != nil {
+		return nil, err
+	}
+	svc.emit(newSecurityEvent(EventRefreshTokenReuse, claims.Subject(), sess))
+	return nil, ErrRefreshTokenReuse
+}
+
+func (svc *Service) createAuthTokenPair(usr *user.User, sess *Session) (*AuthTokenPair, error) {
+	return svc.issueAuthTokenPair(usr, sess, "")
 }
 
-func (svc *Service) NewAuthTokenPair(usr *user.User) (*AuthTokenPair, error) {
-	accessToken, err := svc.createAuthToken(usr, svc.jwtDef.AccessToken)
+// issueAuthTokenPair creates a token pair of the session and stores the
+// session. A rotated refresh token is replaced only if it is still current in
+// the stored session, errSessionChanged is returned otherwise.
+func (svc *Service) issueAuthTokenPair(usr *user.User, sess *Session, rotatedTokenID string) (*AuthTokenPair, error) {
+	audience := svc.jwtDef.accessAudience()
+	if sess.ClientID != "" {
+		audience = svc.jwtDef.clientAudience(sess.Audience, HasScope(sess.Scope, ScopeOpenID))
+	}
+	accessToken, err := svc.createAuthToken(usr, sess, svc.jwtDef.AccessToken, svc.accessRing, audience)
 	if err != nil {
 		return nil, err
 	}
-	refreshToken, err := svc.createAuthToken(usr, svc.jwtDef.RefreshToken)
+	refreshToken, err := svc.createAuthToken(usr, sess, svc.jwtDef.RefreshToken, svc.refreshRing, svc.jwtDef.ownAudience())
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.cache.SetRefreshToken(refreshToken); err != nil {
+	sess.TokenID = refreshToken.TokenID()
+	if err := svc.storeSession(sess, rotatedTokenID, refreshToken.Expires()); err != nil {
 		return nil, err
 	}
 	return &AuthTokenPair{
@@ -54,60 +192,428 @@ func (svc *Service) NewAuthTokenPair(usr *user.User) (*AuthTokenPair, error) {
 	}, nil
 }
 
+// storeSession stores a session until exp. The refresh token rotatedTokenID,
+// if any, is replaced atomically only if it is still current.
+func (svc *Service) storeSession(sess *Session, rotatedTokenID string, exp time.Duration) error {
+	if rotatedTokenID == "" {
+		return svc.cache.SetSession(sess, exp)
+	}
+	rotated, err := svc.cache.RotateSession(sess, rotatedTokenID, exp)
+	if err != nil {
+		return err
+	}
+	if !rotated {
+		return errSessionChanged
+	}
+	return nil
+}
+
+// VerifyAccessToken verifies an access token of authsvc's own login. Tokens
+// issued to a client are rejected, they never grant the rights of the user at
+// authsvc whatever their scope is.
 func (svc *Service) VerifyAccessToken(tokenStr string) (*JWTCustomClaims, error) {
-	return svc.parseToken(tokenStr, svc.jwtDef.AccessToken.Secret)
+	claims, err := svc.verifyAccessToken(tokenStr, svc.jwtDef.ownAudience())
+	if err != nil {
+		return nil, err
+	}
+	if claims.ClientID != "" {
+		return nil, fmt.Errorf("access token is issued to client: [%s]", claims.ClientID)
+	}
+	return claims, nil
 }
 
+// VerifyClientAccessToken verifies an access token issued for authsvc or any
+// of the audiences, tokens issued to clients included.
+func (svc *Service) VerifyClientAccessToken(tokenStr string, audience Audience) (*JWTCustomClaims, error) {
+	return svc.verifyAccessToken(tokenStr, svc.jwtDef.ownAudience().with(audience...))
+}
+
+// verifyAccessToken verifies an access token issued for any of the audiences.
+func (svc *Service) verifyAccessToken(tokenStr string, audience Audience) (*JWTCustomClaims, error) {
+	claims, err := svc.parseToken(tokenStr, svc.accessRing, audience)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := svc.cache.IsAccessTokenDenied(claims.TokenID())
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, errors.New("access token is revoked")
+	}
+	if claims.SessionID != "" {
+		sess, err := svc.cache.GetSession(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if sess == nil {
+			return nil, errors.New("session of access token is revoked")
+		}
+	}
+	return claims, nil
+}
+
+// VerifyRefreshToken verifies a refresh token presented to be rotated. A token
+// that has already been rotated revokes its session, i.e. the whole token
+// family.
 func (svc *Service) VerifyRefreshToken(tokenStr string) (*JWTCustomClaims, error) {
-	claims, err := svc.parseToken(tokenStr, svc.jwtDef.RefreshToken.Secret)
+	claims, sess, err := svc.refreshSession(tokenStr)
 	if err != nil {
 		return nil, err
 	}
-	tokenID, err := svc.cache.GetRefreshToken(claims.ID)
+	if sess.TokenID != claims.TokenID() {
+		if err := svc.cache.RevokeSession(sess); err != nil {
+			return nil, err
+		}
+		svc.emit(newSecurityEvent(EventRefreshTokenReuse, claims.Subject(), sess))
+		return nil, ErrRefreshTokenReuse
+	}
+	return claims, nil
+}
+
+// inspectRefreshToken verifies a refresh token without side effects, a token
+// that has already been rotated is just invalid.
+func (svc *Service) inspectRefreshToken(tokenStr string) (*JWTCustomClaims, error) {
+	claims, sess, err := svc.refreshSession(tokenStr)
 	if err != nil {
 		return nil, err
 	}
-	if tokenID == "" || tokenID != claims.UID {
-		return nil, errors.New("refresh token is invalid or expired")
+	if sess.TokenID != claims.TokenID() {
+		return nil, errors.New("refresh token is already rotated")
 	}
 	return claims, nil
 }
 
+// refreshSession verifies a refresh token and fetches its session.
+func (svc *Service) refreshSession(tokenStr string) (*JWTCustomClaims, *Session, error) {
+	claims, err := svc.parseToken(tokenStr, svc.refreshRing, svc.jwtDef.ownAudience())
+	if err != nil {
+		return nil, nil, err
+	}
+	sess, err := svc.cache.GetSession(claims.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sess == nil || sess.UserID != claims.ID {
+		return nil, nil, errors.New("refresh token is invalid or expired")
+	}
+	return claims, sess, nil
+}
+
+// RevokeRefreshToken ends the session of a valid refresh token, a token that
+// has already been rotated doesn't end the session it was rotated in.
 func (svc *Service) RevokeRefreshToken(tokenStr string) error {
-	claims, err := svc.parseToken(tokenStr, svc.jwtDef.RefreshToken.Secret)
+	claims, err := svc.inspectRefreshToken(tokenStr)
+	if err != nil {
+		return err
+	}
+	return svc.revokeSession(claims)
+}
+
+// RevokeAccessToken denies a valid access token until it expires.
+func (svc *Service) RevokeAccessToken(tokenStr string) error {
+	claims, err := svc.VerifyAccessToken(tokenStr)
 	if err != nil {
 		return err
 	}
-	return svc.cache.RevokeRefreshToken(claims.ID)
+	return svc.cache.DenyAccessToken(claims.TokenID(), time.Until(time.Unix(claims.ExpiresAt, 0)))
 }
 
-func (svc *Service) createAuthToken(usr *user.User, tokenDef *TokenDef) (*AuthToken, error) {
-	userID := usr.RowGUID
-	uid := uuid.NewString()
-	exp := tokenDef.ExpiresAt()
-	claims := &JWTCustomClaims{
-		ID:  userID,
-		UID: uid,
+// Revoke revokes an access or refresh token (RFC 7009) issued to the client
+// accepting the audience, authsvc's own audience if empty. The hinted token
+// type is tried first, invalid tokens and tokens issued to other clients are
+// ignored (RFC 7009 section 2.1). Only the session a refresh token is still
+// current in is ended, a rotated one is ignored without a reuse alarm.
+func (svc *Service) Revoke(tokenStr, hint, clientID string, audience Audience) error {
+	tokenType, claims := svc.identify(tokenStr, hint, audience)
+	if claims == nil || claims.ClientID != clientID {
+		return nil
+	}
+	switch tokenType {
+	case AccessTokenHint:
+		return svc.cache.DenyAccessToken(claims.TokenID(), time.Until(time.Unix(claims.ExpiresAt, 0)))
+	case RefreshTokenHint:
+		return svc.revokeSession(claims)
+	}
+	return nil
+}
+
+// NewPasswordResetToken creates a single-use password reset token of the user.
+func (svc *Service) NewPasswordResetToken(usr *user.User) (*AuthToken, error) {
+	resetToken, err := svc.createAuthToken(usr, &Session{}, svc.jwtDef.passwordResetTokenDef(), svc.resetRing, svc.jwtDef.ownAudience())
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.cache.SetPasswordResetToken(usr.RowGUID, resetToken.TokenID(), resetToken.Expires()); err != nil {
+		return nil, err
+	}
+	return resetToken, nil
+}
+
+// VerifyPasswordResetToken verifies and consumes a password reset token, hence
+// it can be verified only once.
+func (svc *Service) VerifyPasswordResetToken(tokenStr string) (*JWTCustomClaims, error) {
+	claims, err := svc.parseToken(tokenStr, svc.resetRing, svc.jwtDef.ownAudience())
+	if err != nil {
+		return nil, err
+	}
+	consumed, err := svc.cache.ConsumePasswordResetToken(claims.TokenID())
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errors.New("password reset token is already used")
+	}
+	return claims, nil
+}
+
+// RevokePasswordResetTokens invalidates the unused password reset tokens of a
+// user, e.g. once the password is changed.
+func (svc *Service) RevokePasswordResetTokens(userID string) error {
+	return svc.cache.RevokePasswordResetTokens(userID)
+}
+
+// RevokeUserSessions ends all sessions of a user, the access tokens of the
+// sessions are rejected as well.
+func (svc *Service) RevokeUserSessions(userID string) error {
+	sessions, err := svc.cache.GetUserSessions(userID)
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if err := svc.cache.RevokeSession(sess); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sessions lists the sessions of the user of an access token, the session
+// of the token is marked as current.
+func (svc *Service) Sessions(claims *JWTCustomClaims) ([]*Session, error) {
+	sessions, err := svc.cache.GetUserSessions(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sess := range sessions {
+		sess.Current = sess.ID == claims.SessionID
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsed > sessions[j].LastUsed
+	})
+	return sessions, nil
+}
+
+// RevokeSession ends a session of the user of an access token.
+func (svc *Service) RevokeSession(claims *JWTCustomClaims, sessionID string) error {
+	sess, err := svc.cache.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if sess == nil || sess.UserID != claims.ID {
+		return ErrSessionNotFound
+	}
+	return svc.cache.RevokeSession(sess)
+}
+
+// RevokeOtherSessions ends all sessions of the user of an access token
+// except the session of the token.
+func (svc *Service) RevokeOtherSessions(claims *JWTCustomClaims) error {
+	sessions, err := svc.cache.GetUserSessions(claims.ID)
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if sess.ID == claims.SessionID {
+			continue
+		}
+		if err := svc.cache.RevokeSession(sess); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *Service) emit(evt *SecurityEvent) {
+	for _, handler := range svc.eventHandlers {
+		handler(evt)
+	}
+}
+
+func (svc *Service) revokeSession(claims *JWTCustomClaims) error {
+	return svc.cache.RevokeSession(&Session{ID: claims.SessionID, UserID: claims.ID})
+}
+
+// Introspect describes the state of an access or refresh token for the client
+// accepting the audience, authsvc's own audience if empty. The hinted token
+// type is tried first, an unknown or expired token or a token issued for
+// another audience is inactive. A refresh token is active for the client it
+// is issued to only.
+func (svc *Service) Introspect(tokenStr, hint, clientID string, audience Audience) *Introspection {
+	tokenType, claims := svc.identify(tokenStr, hint, audience)
+	if claims == nil || (tokenType == RefreshTokenHint && claims.ClientID != clientID) {
+		return &Introspection{Active: false}
+	}
+	return &Introspection{
+		Active:    true,
+		Subject:   claims.Subject(),
+		Expires:   claims.ExpiresAt,
+		IssuedAt:  claims.IssuedAt,
+		NotBefore: claims.NotBefore,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		TokenID:   claims.TokenID(),
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: tokenType,
+		Actor:     claims.Actor,
+	}
+}
+
+// identify verifies a token of unknown type, trying the hinted type first.
+// It returns the token type hint and the claims of a valid token. Identifying
+// a rotated refresh token has no side effects, it is invalid only.
+func (svc *Service) identify(tokenStr, hint string, audience Audience) (string, *JWTCustomClaims) {
+	if len(audience) == 0 {
+		audience = svc.jwtDef.ownAudience()
+	}
+	verifiers := []struct {
+		hint   string
+		verify func(string) (*JWTCustomClaims, error)
+	}{
+		{AccessTokenHint, func(tokenStr string) (*JWTCustomClaims, error) {
+			return svc.verifyAccessToken(tokenStr, audience)
+		}},
+		{RefreshTokenHint, svc.inspectRefreshToken},
+	}
+	if hint == RefreshTokenHint {
+		verifiers[0], verifiers[1] = verifiers[1], verifiers[0]
+	}
+	for _, v := range verifiers {
+		if claims, err := v.verify(tokenStr); err == nil {
+			return v.hint, claims
+		}
+	}
+	return "", nil
+}
+
+// JWKS provides the public keys used to verify asymmetrically signed access
+// and ID tokens, including the retired keys of not yet expired tokens. Keys
+// of refresh and reset tokens are never published, they are verified by
+// authsvc only. A key shared by token types is published once.
+func (svc *Service) JWKS() *JWKSet {
+	keys := make([]*JWK, 0, 2)
+	published := make(map[string]bool, 2)
+	for _, kr := range []*keyring{svc.accessRing, svc.idRing} {
+		for _, key := range kr.publicKeys() {
+			if !published[key.KeyID] {
+				published[key.KeyID] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return &JWKSet{keys}
+}
+
+// SigningKeys describes the active and retired signing keys.
+func (svc *Service) SigningKeys() []*KeyInfo {
+	info := make([]*KeyInfo, 0, 4)
+	for _, kr := range svc.keyrings() {
+		info = append(info, kr.info()...)
+	}
+	return info
+}
+
+// RotateSigningKeys activates new signing keys. Retired keys keep verifying
+// outstanding tokens until they expire.
+func (svc *Service) RotateSigningKeys() ([]*KeyInfo, error) {
+	for _, kr := range svc.keyrings() {
+		key, err := kr.rotate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rotate %s token signing key: [%v]", kr.name, err)
+		}
+		log.Infof("%s token signing key is rotated, kid: [%s]", kr.name, key.kid)
+	}
+	return svc.SigningKeys(), nil
+}
+
+func (svc *Service) scheduleKeyRotation(interval time.Duration) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, kr := range svc.keyrings() {
+			if !kr.rotationDue(interval) {
+				continue
+			}
+			if key, err := kr.rotate(); err != nil {
+				log.Errorf("failed to rotate %s token signing key: [%v]", kr.name, err)
+			} else {
+				log.Infof("%s token signing key is rotated, kid: [%s]", kr.name, key.kid)
+			}
+		}
+	}
+}
+
+func (svc *Service) keyrings() []*keyring {
+	return []*keyring{svc.accessRing, svc.refreshRing, svc.resetRing, svc.idRing}
+}
+
+func (svc *Service) createAuthToken(usr *user.User, sess *Session, tokenDef *TokenDef, kr *keyring, audience Audience) (*AuthToken, error) {
+	claims := svc.newClaims(usr.Email.String(), tokenDef, audience)
+	claims.ID = usr.RowGUID
+	claims.SessionID = sess.ID
+	claims.ClientID = sess.ClientID
+	if err := svc.addOptionalClaims(claims, usr, tokenDef); err != nil {
+		return nil, err
+	}
+	if sess.ClientID != "" {
+		claims.Scope = sess.Scope
+	}
+	return svc.signToken(claims, kr)
+}
+
+// newClaims creates the registered claims of a new token of the subject.
+func (svc *Service) newClaims(subject string, tokenDef *TokenDef, audience Audience) *JWTCustomClaims {
+	now := jwt.TimeFunc().Unix()
+	return &JWTCustomClaims{
+		Audience: audience,
 		StandardClaims: jwt.StandardClaims{
-			IssuedAt:  jwt.TimeFunc().Unix(),
-			Subject:   usr.Email.String(),
-			ExpiresAt: exp,
+			Id:        uuid.NewString(),
+			Issuer:    svc.jwtDef.issuer(),
+			IssuedAt:  now,
+			NotBefore: now,
+			Subject:   subject,
+			ExpiresAt: tokenDef.ExpiresAt(),
 		},
 	}
-	tokenStr, err := svc.signToken(claims, tokenDef.Secret)
+}
+
+func (svc *Service) signToken(claims *JWTCustomClaims, kr *keyring) (*AuthToken, error) {
+	tokenStr, err := kr.signer().sign(claims, kr.typ)
 	if err != nil {
 		return nil, err
 	}
-	return &AuthToken{tokenStr, exp, userID, uid}, nil
+	return &AuthToken{tokenStr, claims.ExpiresAt, claims.ID, claims.TokenID()}, nil
 }
 
-func (svc *Service) parseToken(tokenStr, secret string) (*JWTCustomClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &JWTCustomClaims{},
+// parseToken verifies the type, the signature and the registered claims of a
+// token issued for any of the audiences.
+func (svc *Service) parseToken(tokenStr string, kr *keyring, audience Audience) (*JWTCustomClaims, error) {
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(tokenStr, &JWTCustomClaims{},
 		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			if typ, _ := token.Header["typ"].(string); typ != kr.typ {
+				return nil, fmt.Errorf("unexpected token type: [%v]", token.Header["typ"])
+			}
+			kid, _ := token.Header["kid"].(string)
+			key := kr.verifier(kid)
+			if key == nil {
+				return nil, fmt.Errorf("unknown signing key: [%v]", token.Header["kid"])
+			}
+			if token.Method.Alg() != key.method.Alg() {
 				return nil, fmt.Errorf("unexpected signing method: [%v]", token.Header["alg"])
 			}
-			return []byte(secret), nil
+			return key.verifyKey, nil
 		})
 
 	if err != nil {
@@ -116,13 +622,12 @@ func (svc *Service) parseToken(tokenStr, secret string) (*JWTCustomClaims, error
 	if !token.Valid {
 		return nil, errors.New("token is not valid")
 	}
-	if claims, ok := token.Claims.(*JWTCustomClaims); ok {
-		return claims, nil
+	claims, ok := token.Claims.(*JWTCustomClaims)
+	if !ok {
+		return nil, errors.New("token parsing error")
 	}
-	return nil, errors.New("token parsing error")
-}
-
-func (svc *Service) signToken(claims *JWTCustomClaims, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	if err := svc.validateClaims(claims, audience); err != nil {
+		return nil, err
+	}
+	return claims, nil
 }
@@ -0,0 +1,57 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Device identifies the user agent a session is used from.
+type Device struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is a login of a user. Every login creates a session, refreshing the
+// token pair rotates the refresh token of the session.
+type Session struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"-"`
+	TokenID   string   `json:"-"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Audience  Audience `json:"-"`
+	UserAgent string   `json:"user_agent"`
+	IP        string   `json:"ip"`
+	Created   int64    `json:"created"`
+	LastUsed  int64    `json:"last_used"`
+	Current   bool     `json:"current"`
+}
+
+// ErrSessionNotFound is returned for an unknown session or a session of another user.
+var ErrSessionNotFound = errors.New("session not found")
+
+func newSession(userID string, device *Device) *Session {
+	now := time.Now().Unix()
+	sess := &Session{ID: uuid.NewString(), UserID: userID, Created: now}
+	sess.use(device, now)
+	return sess
+}
+
+// authorize binds the session to the authorization of a client, the tokens of
+// the session are issued to the client.
+func (sess *Session) authorize(authz *Authorization) {
+	sess.ClientID = authz.ClientID
+	sess.Scope = authz.Scope
+	sess.Audience = authz.Audience
+}
+
+// use records the activity of the session from a device.
+func (sess *Session) use(device *Device, now int64) {
+	if device != nil {
+		sess.UserAgent = device.UserAgent
+		sess.IP = device.IP
+	}
+	sess.LastUsed = now
+}
@@ -4,7 +4,35 @@ import (
 	"time"
 )
 
-const tokenTypeBearer = "bearer"
+const (
+	tokenTypeBearer  = "bearer"
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+	resetTokenType   = "reset"
+	idTokenType      = "id"
+)
+
+// tokenTypeHeaders are the typ headers of the token types, a token of one
+// type never verifies as another even if their keys are shared (RFC 8725
+// section 3.11). Access tokens are typed as of RFC 9068.
+var tokenTypeHeaders = map[string]string{
+	accessTokenType:  "at+jwt",
+	refreshTokenType: "refresh+jwt",
+	resetTokenType:   "reset+jwt",
+	idTokenType:      "JWT",
+}
+
+// defaultResetTokenExp is the expire time of password reset tokens unless configured.
+const defaultResetTokenExp ExpireTime = 15
+
+// defaultIssuer is the iss claim of tokens unless configured.
+const defaultIssuer = "authsvc"
+
+// Token type hints of token introspection (RFC 7662).
+const (
+	AccessTokenHint  = "access_token"
+	RefreshTokenHint = "refresh_token"
+)
 
 type ExpireTime int
 
@@ -13,8 +41,19 @@ func (et ExpireTime) duration() time.Duration {
 }
 
 type TokenDef struct {
-	Secret string
-	Exp    ExpireTime
+	Alg     string
+	Secret  string
+	KeyFile string
+	KeyID   string
+	Exp     ExpireTime
+	Claims  []string
+}
+
+func (td TokenDef) alg() string {
+	if td.Alg == "" {
+		return defaultAlg
+	}
+	return td.Alg
 }
 
 func (td TokenDef) ExpiresAt() int64 {
@@ -22,23 +61,113 @@ func (td TokenDef) ExpiresAt() int64 {
 }
 
 type JWTDef struct {
-	AccessToken  *TokenDef
-	RefreshToken *TokenDef
+	Issuer             string
+	Audience           []string
+	Leeway             int
+	AccessToken        *TokenDef
+	RefreshToken       *TokenDef
+	PasswordResetToken *TokenDef
+	IDToken            *TokenDef
+	Keyring            *KeyringDef
+}
+
+// issuer provides the iss claim of all tokens, it is authsvc's own audience too.
+func (jd *JWTDef) issuer() string {
+	if jd.Issuer == "" {
+		return defaultIssuer
+	}
+	return jd.Issuer
+}
+
+// ownAudience is the audience of tokens consumed by authsvc only.
+func (jd *JWTDef) ownAudience() Audience {
+	return Audience{jd.issuer()}
+}
+
+// accessAudience is the audience of access tokens of authsvc's own login,
+// authsvc and the configured resource servers.
+func (jd *JWTDef) accessAudience() Audience {
+	return jd.ownAudience().with(jd.Audience...)
+}
+
+// clientAudience is the audience of access tokens issued to a client, the
+// audience of the client or else the configured resource servers. authsvc's
+// own audience is added only if the token may be used at authsvc, i.e. at the
+// userinfo endpoint. Routes of authsvc itself reject tokens of clients anyway.
+func (jd *JWTDef) clientAudience(clientAudience Audience, userInfo bool) Audience {
+	aud := Audience{}
+	if userInfo {
+		aud = jd.ownAudience()
+	}
+	if len(clientAudience) == 0 {
+		clientAudience = jd.Audience
+	}
+	for _, a := range clientAudience {
+		if a != jd.issuer() {
+			aud = aud.with(a)
+		}
+	}
+	return aud
+}
+
+// leeway is the tolerated clock skew in seconds on verifying time claims.
+func (jd *JWTDef) leeway() int64 {
+	if jd.Leeway < 0 {
+		return 0
+	}
+	return int64(jd.Leeway)
+}
+
+// passwordResetTokenDef falls back to the refresh token key. A reset token is
+// still no refresh token, both are bound to their own records in the cache.
+func (jd *JWTDef) passwordResetTokenDef() *TokenDef {
+	td := *jd.RefreshToken
+	td.Exp = 0
+	td.Claims = nil
+	if jd.PasswordResetToken != nil {
+		td = *jd.PasswordResetToken
+	}
+	if td.Exp <= 0 {
+		td.Exp = defaultResetTokenExp
+	}
+	return &td
+}
+
+// idTokenDef falls back to the access token key and expire time. Relying
+// parties verify ID tokens with the published keys.
+func (jd *JWTDef) idTokenDef() *TokenDef {
+	td := *jd.AccessToken
+	td.Claims = nil
+	if jd.IDToken != nil {
+		td = *jd.IDToken
+	}
+	if td.Exp <= 0 {
+		td.Exp = jd.AccessToken.Exp
+	}
+	return &td
+}
+
+func (jd *JWTDef) keyringDef() *KeyringDef {
+	if jd.Keyring == nil {
+		return &KeyringDef{}
+	}
+	return jd.Keyring
 }
 
 type AuthToken struct {
 	tokenStr string
 	exp      int64
 	id       string
-	uid      string
+	jti      string
 }
 
 func (t *AuthToken) UserID() string {
 	return t.id
 }
 
-func (t *AuthToken) UUID() string {
-	return t.uid
+// TokenID provides the unique ID (jti) of the token.
+func (t *AuthToken) TokenID() string {
+	return t.jti
 }
 
 func (t *AuthToken) String() string {
@@ -60,3 +189,19 @@ type AuthTokenPair struct {
 	TokenType    string        `json:"token_type"`
 	Expires      time.Duration `json:"expires"`
 }
+
+// Introspection is the token introspection response (RFC 7662).
+type Introspection struct {
+	Active    bool     `json:"active"`
+	Subject   string   `json:"sub,omitempty"`
+	Expires   int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	TokenID   string   `json:"jti,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Actor     *Actor   `json:"act,omitempty"`
+}
@@ -0,0 +1,62 @@
+package token
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+)
+
+// webAuthnChallengeExp is the lifetime of WebAuthn ceremony challenges.
+const webAuthnChallengeExp = 5 * time.Minute
+
+// ErrWebAuthnChallengeInvalid is returned for an unknown, expired or already
+// used WebAuthn challenge and for a challenge of another ceremony.
+var ErrWebAuthnChallengeInvalid = errors.New("webauthn challenge is invalid, expired or already used")
+
+// WebAuthnChallenge is a pending WebAuthn ceremony, the login is the user of
+// a registration or of an authentication with allowed credentials.
+type WebAuthnChallenge struct {
+	Ceremony string
+	Login    string
+}
+
+// NewWebAuthnChallenge issues a single-use challenge of a WebAuthn ceremony.
+// Only the hash of the challenge is stored.
+func (svc *Service) NewWebAuthnChallenge(ceremony, login string) (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	challenge := b64(data)
+	if err := svc.cache.SetWebAuthnChallenge(codeHash(challenge), &WebAuthnChallenge{ceremony, login}, webAuthnChallengeExp); err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// ConsumeWebAuthnChallenge redeems the challenge of a WebAuthn ceremony and
+// provides its login.
+func (svc *Service) ConsumeWebAuthnChallenge(ceremony, challenge string) (string, error) {
+	wc, err := svc.cache.ConsumeWebAuthnChallenge(codeHash(challenge))
+	if err != nil {
+		return "", err
+	}
+	if wc == nil || wc.Ceremony != ceremony {
+		return "", ErrWebAuthnChallengeInvalid
+	}
+	return wc.Login, nil
+}
+
+// ReportWebAuthnClone emits EventWebAuthnCloneDetected for a credential of the
+// user whose sign count didn't increase, its authenticator may be cloned.
+func (svc *Service) ReportWebAuthnClone(userID, login, credentialID string, device *Device) {
+	svc.emit(&SecurityEvent{
+		Type:         EventWebAuthnCloneDetected,
+		UserID:       userID,
+		Subject:      login,
+		CredentialID: credentialID,
+		UserAgent:    device.UserAgent,
+		IP:           device.IP,
+		Time:         time.Now(),
+	})
+}
@@ -0,0 +1,84 @@
+package token
+
+import (
+	"testing"
+	"time"
+)
+
+// webAuthnCache stores WebAuthn challenges in memory, the other methods of
+// Cache aren't used.
+type webAuthnCache struct {
+	Cache
+	challenges map[string]*WebAuthnChallenge
+}
+
+func (c *webAuthnCache) SetWebAuthnChallenge(challengeHash string, wc *WebAuthnChallenge, exp time.Duration) error {
+	c.challenges[challengeHash] = wc
+	return nil
+}
+
+func (c *webAuthnCache) ConsumeWebAuthnChallenge(challengeHash string) (*WebAuthnChallenge, error) {
+	wc := c.challenges[challengeHash]
+	delete(c.challenges, challengeHash)
+	return wc, nil
+}
+
+func newWebAuthnService() (*Service, *webAuthnCache) {
+	cache := &webAuthnCache{challenges: map[string]*WebAuthnChallenge{}}
+	return &Service{cache: cache}, cache
+}
+
+func TestConsumeWebAuthnChallenge(t *testing.T) {
+	svc, cache := newWebAuthnService()
+	challenge, err := svc.NewWebAuthnChallenge("webauthn.get", "a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.challenges[challenge]; ok {
+		t.Error("challenge is stored in plain")
+	}
+	login, err := svc.ConsumeWebAuthnChallenge("webauthn.get", challenge)
+	if err != nil || login != "a@example.com" {
+		t.Fatalf("login = %q, %v, want a@example.com", login, err)
+	}
+	if _, err := svc.ConsumeWebAuthnChallenge("webauthn.get", challenge); err != ErrWebAuthnChallengeInvalid {
+		t.Errorf("reused challenge: error = %v, want %v", err, ErrWebAuthnChallengeInvalid)
+	}
+}
+
+func TestConsumeWebAuthnChallengeOfAnotherCeremony(t *testing.T) {
+	svc, _ := newWebAuthnService()
+	challenge, err := svc.NewWebAuthnChallenge("webauthn.create", "a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ConsumeWebAuthnChallenge("webauthn.get", challenge); err != ErrWebAuthnChallengeInvalid {
+		t.Errorf("error = %v, want %v", err, ErrWebAuthnChallengeInvalid)
+	}
+	// A challenge presented to the wrong ceremony is spent.
+	if _, err := svc.ConsumeWebAuthnChallenge("webauthn.create", challenge); err != ErrWebAuthnChallengeInvalid {
+		t.Errorf("error = %v, want %v", err, ErrWebAuthnChallengeInvalid)
+	}
+}
+
+func TestConsumeUnknownWebAuthnChallenge(t *testing.T) {
+	svc, _ := newWebAuthnService()
+	if _, err := svc.ConsumeWebAuthnChallenge("webauthn.get", "dW5rbm93bg"); err != ErrWebAuthnChallengeInvalid {
+		t.Errorf("error = %v, want %v", err, ErrWebAuthnChallengeInvalid)
+	}
+}
+
+func TestReportWebAuthnClone(t *testing.T) {
+	svc, _ := newWebAuthnService()
+	var events []*SecurityEvent
+	svc.OnSecurityEvent(func(evt *SecurityEvent) { events = append(events, evt) })
+	svc.ReportWebAuthnClone("guid", "a@example.com", "Y3JlZA", &Device{UserAgent: "agent", IP: "192.0.2.1"})
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1", len(events))
+	}
+	evt := events[0]
+	if evt.Type != EventWebAuthnCloneDetected || evt.UserID != "guid" || evt.Subject != "a@example.com" ||
+		evt.CredentialID != "Y3JlZA" || evt.UserAgent != "agent" || evt.IP != "192.0.2.1" {
+		t.Errorf("event = %+v", evt)
+	}
+}
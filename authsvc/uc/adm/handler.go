@@ -13,11 +13,32 @@ import (
 )
 
 type UserDetails struct {
-	Firstname   string         `json:"firstname"`
-	Lastname    string         `json:"lastname"`
-	Email       usrTable.Email `json:"email"`
-	Roles       []string       `json:"roles,omitempty"`
-	Permissions []string       `json:"permissions,omitempty"`
+	Firstname     string         `json:"firstname"`
+	Lastname      string         `json:"lastname"`
+	Email         usrTable.Email `json:"email"`
+	EmailVerified bool           `json:"email_verified"`
+	Roles         []string       `json:"roles,omitempty"`
+	Permissions   []string       `json:"permissions,omitempty"`
+}
+
+// UserAccount is the administrative view of a user.
+type UserAccount struct {
+	ID          string                  `json:"id"`
+	Firstname   string                  `json:"firstname"`
+	Lastname    string                  `json:"lastname"`
+	Email       usrTable.Email          `json:"email"`
+	Verified    bool                    `json:"verified"`
+	Disabled    bool                    `json:"disabled"`
+	Roles       []*roleTable.Role       `json:"roles,omitempty"`
+	Permissions []*permTable.Permission `json:"permissions,omitempty"`
+}
+
+// UserPage is a page of user accounts.
+type UserPage struct {
+	Users  []*UserAccount `json:"users"`
+	Total  int            `json:"total"`
+	Offset int            `json:"offset"`
+	Limit  int            `json:"limit"`
 }
 
 // Handler implements admin use-cases.
@@ -36,19 +57,35 @@ func (hndlr *Handler) UserDetailsByJWTClaims(claims *token.JWTCustomClaims) (*Us
 	if err != nil {
 		return nil, err
 	}
-	roles, err := hndlr.roleHndlr.ReadUserRoles(claims.Subject())
-	if err != nil {
-		return nil, err
+	if usr == nil {
+		return nil, usrTable.ErrUserNotFound
 	}
-	perms, err := hndlr.permHndlr.ReadUserPermissions(claims.Subject())
+	grants, err := hndlr.UserGrants(usr)
 	if err != nil {
 		return nil, err
 	}
 
 	return &UserDetails{
-		Firstname: usr.Firstname,
-		Lastname:  usr.Lastname,
-		Email:     usr.Email,
+		Firstname:     usr.Firstname,
+		Lastname:      usr.Lastname,
+		Email:         usr.Email,
+		EmailVerified: usr.Verified,
+		Roles:         grants.Roles,
+		Permissions:   grants.Permissions,
+	}, nil
+}
+
+// UserGrants fetches the names of the effective roles and permissions of a user.
+func (hndlr *Handler) UserGrants(usr *usrTable.User) (*token.Grants, error) {
+	roles, err := hndlr.roleHndlr.ReadUserEffectiveRoles(usr.Email.String())
+	if err != nil {
+		return nil, err
+	}
+	perms, err := hndlr.permHndlr.ReadUserPermissions(usr.Email.String())
+	if err != nil {
+		return nil, err
+	}
+	return &token.Grants{
 		Roles: hndlr.toStrings(roles, func(v interface{}) string {
 			role, _ := v.(*roleTable.Role)
 			return role.Name
@@ -68,3 +105,100 @@ func (hndlr *Handler) toStrings(items interface{}, exec func(interface{}) string
 	}
 	return res
 }
+
+// UserAccounts fetches a page of the users whose login or name contains search.
+func (hndlr *Handler) UserAccounts(search string, offset, limit int) (*UserPage, error) {
+	total, err := hndlr.usrHndlr.CountUsers(search)
+	if err != nil {
+		return nil, err
+	}
+	users, err := hndlr.usrHndlr.ReadUsers(search, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &UserPage{make([]*UserAccount, 0, len(users)), total, offset, limit}
+	for _, usr := range users {
+		page.Users = append(page.Users, toUserAccount(usr))
+	}
+	return page, nil
+}
+
+// UserAccount fetches a user with the roles and permissions, nil if the user doesn't exist.
+func (hndlr *Handler) UserAccount(id string) (*UserAccount, error) {
+	usr, err := hndlr.usrHndlr.ReadUserByID(id)
+	if err != nil || usr == nil {
+		return nil, err
+	}
+	account := toUserAccount(usr)
+	if account.Roles, err = hndlr.roleHndlr.ReadUserRoles(usr.Email.String()); err != nil {
+		return nil, err
+	}
+	if account.Permissions, err = hndlr.permHndlr.ReadUserPermissions(usr.Email.String()); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (hndlr *Handler) AssignUserRole(id string, roleID int) error {
+	usr, err := hndlr.user(id)
+	if err != nil {
+		return err
+	}
+	return hndlr.roleHndlr.AssignUserRole(usr.ID, roleID)
+}
+
+func (hndlr *Handler) RemoveUserRole(id string, roleID int) error {
+	usr, err := hndlr.user(id)
+	if err != nil {
+		return err
+	}
+	return hndlr.roleHndlr.RemoveUserRole(usr.ID, roleID)
+}
+
+// VerifyUser marks the email of a user as verified.
+func (hndlr *Handler) VerifyUser(id string) error {
+	usr, err := hndlr.user(id)
+	if err != nil {
+		return err
+	}
+	return hndlr.usrHndlr.AssignUserVerification(usr.Email.String(), true)
+}
+
+// DisableUser disables or enables the account of a user.
+func (hndlr *Handler) DisableUser(id string, isDisabled bool) error {
+	usr, err := hndlr.user(id)
+	if err != nil {
+		return err
+	}
+	return hndlr.usrHndlr.AssignUserDisabled(usr.Email.String(), isDisabled)
+}
+
+func (hndlr *Handler) DeleteUser(id string) error {
+	usr, err := hndlr.user(id)
+	if err != nil {
+		return err
+	}
+	return hndlr.usrHndlr.DeleteUser(usr.Email.String())
+}
+
+func (hndlr *Handler) user(id string) (*usrTable.User, error) {
+	usr, err := hndlr.usrHndlr.ReadUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if usr == nil {
+		return nil, usrTable.ErrUserNotFound
+	}
+	return usr, nil
+}
+
+func toUserAccount(usr *usrTable.User) *UserAccount {
+	return &UserAccount{
+		ID:        usr.RowGUID,
+		Firstname: usr.Firstname,
+		Lastname:  usr.Lastname,
+		Email:     usr.Email,
+		Verified:  usr.Verified,
+		Disabled:  usr.Disabled,
+	}
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/google/uuid"
+	log "github.com/parthoshuvo/authsvc/log4u"
+	"github.com/parthoshuvo/authsvc/passwd"
+	"github.com/parthoshuvo/authsvc/table/client"
+)
+
+// secretSize is the number of random bytes of a generated client secret.
+const secretSize = 32
+
+// confidentialGrants are the grant types of confidential clients only.
+var confidentialGrants = []string{client.GrantClientCredentials, client.GrantTokenExchange}
+
+// Handler implements client use-cases.
+type Handler struct {
+	table  *client.Table
+	hasher *passwd.PasswordHasher
+}
+
+func NewHandler(t *client.Table, hasher *passwd.PasswordHasher) *Handler {
+	return &Handler{t, hasher}
+}
+
+// ReadClients fetches all clients.
+func (h *Handler) ReadClients() ([]*client.Client, error) {
+	return h.table.ReadClients()
+}
+
+// ReadClient fetches a client by client ID, nil if it doesn't exist.
+func (h *Handler) ReadClient(clientID string) (*client.Client, error) {
+	return h.table.ReadClient(clientID)
+}
+
+// CreateClient registers a client with a generated client ID. The generated
+// secret of a confidential client is returned, only its hash is stored.
+func (h *Handler) CreateClient(clnt *client.Client) (*client.Client, string, error) {
+	if err := validate(clnt); err != nil {
+		return nil, "", err
+	}
+	clnt.ID = uuid.NewString()
+	var secret string
+	if !clnt.Public {
+		var err error
+		if secret, clnt.Secret, err = h.newSecret(); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := h.table.InsertClient(clnt); err != nil {
+		return nil, "", err
+	}
+	return clnt, secret, nil
+}
+
+// UpdateClient changes the registration of a client, a client stays public
+// or confidential.
+func (h *Handler) UpdateClient(clnt *client.Client) error {
+	current, err := h.table.ReadClient(clnt.ID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return client.ErrClientNotFound
+	}
+	clnt.Public = current.Public
+	if err := validate(clnt); err != nil {
+		return err
+	}
+	return h.table.UpdateClient(clnt)
+}
+
+// RotateClientSecret replaces the secret of a confidential client with a
+// generated one, the former secret becomes invalid at once.
+func (h *Handler) RotateClientSecret(clientID string) (string, error) {
+	clnt, err := h.table.ReadClient(clientID)
+	if err != nil {
+		return "", err
+	}
+	if clnt == nil {
+		return "", client.ErrClientNotFound
+	}
+	if clnt.Public {
+		return "", fmt.Errorf("%w: a public client has no secret", client.ErrClientInvalid)
+	}
+	secret, hash, err := h.newSecret()
+	if err != nil {
+		return "", err
+	}
+	return secret, h.table.UpdateClientSecret(clientID, hash)
+}
+
+// DeleteClient deletes a client.
+func (h *Handler) DeleteClient(clientID string) error {
+	return h.table.DeleteClient(clientID)
+}
+
+// Authenticate returns the client if the secret matches, otherwise nil.
+// Public clients have no secret, hence they never authenticate. Secrets of
+// outdated hashes are rehashed.
+func (h *Handler) Authenticate(clientID, secret string) (*client.Client, error) {
+	clnt, err := h.table.ReadClient(clientID)
+	if err != nil || clnt == nil || clnt.Public {
+		return nil, err
+	}
+	ok, rehash, err := h.hasher.Verify(secret, clnt.Secret)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if rehash {
+		h.rehashSecret(clnt, secret)
+	}
+	return clnt, nil
+}
+
+func (h *Handler) rehashSecret(clnt *client.Client, secret string) {
+	hash, err := h.hasher.Hash(secret)
+	if err == nil {
+		err = h.table.UpdateClientSecret(clnt.ID, hash)
+	}
+	if err != nil {
+		log.Errorf("failed to rehash secret of client %s: [%v]", clnt.ID, err)
+	}
+}
+
+// newSecret generates a client secret and its hash.
+func (h *Handler) newSecret() (string, string, error) {
+	data := make([]byte, secretSize)
+	if _, err := rand.Read(data); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(data)
+	hash, err := h.hasher.Hash(secret)
+	return secret, hash, err
+}
+
+// validate checks the grants of a client against its type and redirect URIs.
+func validate(clnt *client.Client) error {
+	for _, grant := range confidentialGrants {
+		if clnt.Public && clnt.HasGrant(grant) {
+			return fmt.Errorf("%w: a public client can't use the %s grant", client.ErrClientInvalid, grant)
+		}
+	}
+	if clnt.HasGrant(client.GrantAuthorizationCode) && len(clnt.RedirectURIs) == 0 {
+		return fmt.Errorf("%w: the %s grant requires redirect_uris", client.ErrClientInvalid, client.GrantAuthorizationCode)
+	}
+	return nil
+}
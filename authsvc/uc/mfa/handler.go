@@ -0,0 +1,178 @@
+package mfa
+
+import (
+	"time"
+
+	"github.com/parthoshuvo/authsvc/mfa"
+	"github.com/parthoshuvo/authsvc/table/user"
+)
+
+// RecoveryCodeHandler is notified of the recovery code of user consumed and
+// the number of remaining ones.
+type RecoveryCodeHandler func(usr *user.User, remaining int)
+
+// Handler implements two-factor authentication use-cases.
+type Handler struct {
+	table              *user.Table
+	totp               *mfa.TOTP
+	onRecoveryCodeUsed RecoveryCodeHandler
+}
+
+func NewHandler(t *user.Table, totp *mfa.TOTP) *Handler {
+	return &Handler{t, totp, func(*user.User, int) {}}
+}
+
+// OnRecoveryCodeUsed registers the handler of consumed recovery codes.
+func (h *Handler) OnRecoveryCodeUsed(handler RecoveryCodeHandler) {
+	h.onRecoveryCodeUsed = handler
+}
+
+// EnrollTOTP generates a pending TOTP secret of user and returns it with its
+// key URI. A pending secret is replaced, an enabled one must be disabled
+// first.
+func (h *Handler) EnrollTOTP(usr *user.User) (string, string, error) {
+	if usr.TOTPEnabled {
+		return "", "", mfa.ErrTOTPEnabled
+	}
+	secret, encrypted, err := h.totp.NewSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := h.table.AssignUserTOTP(usr.Email.String(), encrypted); err != nil {
+		return "", "", err
+	}
+	return secret, h.totp.URI(usr.Email.String(), secret), nil
+}
+
+// PendingTOTPURI provides the key URI of the pending TOTP secret of user. The
+// secret of enabled two-factor authentication isn't revealed again.
+func (h *Handler) PendingTOTPURI(usr *user.User) (string, error) {
+	secret, totp, err := h.secret(usr)
+	if err != nil {
+		return "", err
+	}
+	if totp.Enabled {
+		return "", mfa.ErrTOTPEnabled
+	}
+	return h.totp.URI(usr.Email.String(), secret), nil
+}
+
+// ConfirmTOTP enables two-factor authentication of user with a first code of
+// the pending secret and returns a new set of recovery codes.
+func (h *Handler) ConfirmTOTP(usr *user.User, code string) ([]string, error) {
+	secret, totp, err := h.secret(usr)
+	if err != nil {
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, mfa.ErrTOTPEnabled
+	}
+	counter, err := h.totp.Verify(secret, code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if counter == 0 {
+		return nil, mfa.ErrInvalidCode
+	}
+	ok, err := h.table.EnableUserTOTP(usr.Email.String(), counter)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, mfa.ErrInvalidCode
+	}
+	return h.newRecoveryCodes(usr)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of user, authorized by
+// a code of the authenticator app. The former codes become invalid.
+func (h *Handler) RegenerateRecoveryCodes(usr *user.User, code string) ([]string, error) {
+	if err := h.VerifyTOTP(usr, code); err != nil {
+		return nil, err
+	}
+	return h.newRecoveryCodes(usr)
+}
+
+// VerifyCode checks a code of the authenticator app or else a recovery code
+// of user, a recovery code is consumed.
+func (h *Handler) VerifyCode(usr *user.User, code string) error {
+	if mfa.IsTOTPCode(code) {
+		return h.VerifyTOTP(usr, code)
+	}
+	if !usr.TOTPEnabled {
+		return mfa.ErrTOTPNotEnabled
+	}
+	ok, remaining, err := h.table.ConsumeUserRecoveryCode(usr.Email.String(), mfa.HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return mfa.ErrInvalidCode
+	}
+	h.onRecoveryCodeUsed(usr, remaining)
+	return nil
+}
+
+// VerifyTOTP checks a code of the enabled secret of user, each code is
+// accepted once.
+func (h *Handler) VerifyTOTP(usr *user.User, code string) error {
+	secret, totp, err := h.secret(usr)
+	if err == mfa.ErrTOTPNotEnrolled || err == nil && !totp.Enabled {
+		return mfa.ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	counter, err := h.totp.Verify(secret, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if counter <= totp.Counter {
+		return mfa.ErrInvalidCode
+	}
+	ok, err := h.table.AdvanceUserTOTPCounter(usr.Email.String(), counter)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return mfa.ErrInvalidCode
+	}
+	return nil
+}
+
+// DisableTOTP disables two-factor authentication of user with a valid code or
+// recovery code and removes the secret and the recovery codes.
+func (h *Handler) DisableTOTP(usr *user.User, code string) error {
+	if err := h.VerifyCode(usr, code); err != nil {
+		return err
+	}
+	if err := h.table.AssignUserRecoveryCodes(usr.Email.String(), nil); err != nil {
+		return err
+	}
+	return h.table.AssignUserTOTP(usr.Email.String(), "")
+}
+
+// newRecoveryCodes replaces the recovery codes of user with a new set.
+func (h *Handler) newRecoveryCodes(usr *user.User) ([]string, error) {
+	codes, hashes, err := mfa.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.table.AssignUserRecoveryCodes(usr.Email.String(), hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// secret reads and decrypts the TOTP secret of user.
+func (h *Handler) secret(usr *user.User) (string, *user.TOTP, error) {
+	totp, err := h.table.ReadUserTOTP(usr.Email.String())
+	if err != nil {
+		return "", nil, err
+	}
+	if totp.Secret == "" {
+		return "", nil, mfa.ErrTOTPNotEnrolled
+	}
+	secret, err := h.totp.Decrypt(totp.Secret)
+	return secret, totp, err
+}
@@ -14,3 +14,23 @@ func NewHandler(t *permission.Table) *Handler {
 func (hndlr *Handler) ReadUserPermissions(login string) ([]*permission.Permission, error) {
 	return hndlr.table.ReadUserPermissions(login)
 }
+
+func (hndlr *Handler) ReadPermissions() ([]*permission.Permission, error) {
+	return hndlr.table.ReadPermissions()
+}
+
+func (hndlr *Handler) ReadPermission(id int) (*permission.Permission, error) {
+	return hndlr.table.ReadPermission(id)
+}
+
+func (hndlr *Handler) CreatePermission(perm *permission.Permission) (*permission.Permission, error) {
+	return hndlr.table.InsertPermission(perm)
+}
+
+func (hndlr *Handler) UpdatePermission(perm *permission.Permission) error {
+	return hndlr.table.UpdatePermission(perm)
+}
+
+func (hndlr *Handler) DeletePermission(id int) error {
+	return hndlr.table.DeletePermission(id)
+}
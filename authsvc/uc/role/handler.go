@@ -14,3 +14,62 @@ func NewHandler(t *role.Table) *Handler {
 func (hndlr *Handler) ReadUserRoles(login string) ([]*role.Role, error) {
 	return hndlr.table.ReadUserRoles(login)
 }
+
+// ReadUserEffectiveRoles fetches the assigned and inherited roles of a user.
+func (hndlr *Handler) ReadUserEffectiveRoles(login string) ([]*role.Role, error) {
+	return hndlr.table.ReadUserEffectiveRoles(login)
+}
+
+func (hndlr *Handler) ReadRoles() ([]*role.Role, error) {
+	return hndlr.table.ReadRoles()
+}
+
+// ReadRole fetches a role with its own permissions and parent roles, nil if
+// it doesn't exist.
+func (hndlr *Handler) ReadRole(id int) (*role.Role, error) {
+	rl, err := hndlr.table.ReadRole(id)
+	if err != nil || rl == nil {
+		return rl, err
+	}
+	if rl.Permissions, err = hndlr.table.ReadRolePermissions(id); err != nil {
+		return nil, err
+	}
+	rl.Parents, err = hndlr.table.ReadRoleParents(id)
+	return rl, err
+}
+
+func (hndlr *Handler) CreateRole(rl *role.Role) (*role.Role, error) {
+	return hndlr.table.InsertRole(rl)
+}
+
+func (hndlr *Handler) UpdateRole(rl *role.Role) error {
+	return hndlr.table.UpdateRole(rl)
+}
+
+func (hndlr *Handler) DeleteRole(id int) error {
+	return hndlr.table.DeleteRole(id)
+}
+
+func (hndlr *Handler) AttachPermission(roleID, permID int) error {
+	return hndlr.table.InsertRolePermission(roleID, permID)
+}
+
+func (hndlr *Handler) DetachPermission(roleID, permID int) error {
+	return hndlr.table.DeleteRolePermission(roleID, permID)
+}
+
+func (hndlr *Handler) AddParent(roleID, parentID int) error {
+	return hndlr.table.InsertRoleParent(roleID, parentID)
+}
+
+func (hndlr *Handler) RemoveParent(roleID, parentID int) error {
+	return hndlr.table.DeleteRoleParent(roleID, parentID)
+}
+
+func (hndlr *Handler) AssignUserRole(userID, roleID int) error {
+	return hndlr.table.InsertUserRole(userID, roleID)
+}
+
+func (hndlr *Handler) RemoveUserRole(userID, roleID int) error {
+	return hndlr.table.DeleteUserRole(userID, roleID)
+}
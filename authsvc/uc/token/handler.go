@@ -13,14 +13,70 @@ func NewHandler(tokenSvc *token.Service) *Handler {
 	return &Handler{tokenSvc}
 }
 
-func (h *Handler) NewAuthTokenPair(usr *user.User) (*token.AuthTokenPair, error) {
-	return h.tokenSvc.NewAuthTokenPair(usr)
+func (h *Handler) OnSecurityEvent(handler token.SecurityEventHandler) {
+	h.tokenSvc.OnSecurityEvent(handler)
+}
+
+func (h *Handler) UseGrants(reader token.GrantsReader) {
+	h.tokenSvc.UseGrants(reader)
+}
+
+func (h *Handler) NewAuthTokenPair(usr *user.User, device *token.Device) (*token.AuthTokenPair, error) {
+	return h.tokenSvc.NewAuthTokenPair(usr, device)
+}
+
+func (h *Handler) NewMFAChallenge(login string) (*token.MFAChallenge, error) {
+	return h.tokenSvc.NewMFAChallenge(login)
+}
+
+func (h *Handler) MFAChallengeLogin(challenge string) (string, error) {
+	return h.tokenSvc.MFAChallengeLogin(challenge)
+}
+
+func (h *Handler) FailMFAChallenge(challenge string) error {
+	return h.tokenSvc.FailMFAChallenge(challenge)
+}
+
+func (h *Handler) CompleteMFAChallenge(challenge string) error {
+	return h.tokenSvc.CompleteMFAChallenge(challenge)
+}
+
+func (h *Handler) CheckMFALogin(login string) error {
+	return h.tokenSvc.CheckMFALogin(login)
+}
+
+func (h *Handler) FailMFALogin(login string) error {
+	return h.tokenSvc.FailMFALogin(login)
+}
+
+func (h *Handler) ResetMFALogin(login string) error {
+	return h.tokenSvc.ResetMFALogin(login)
+}
+
+func (h *Handler) NewWebAuthnChallenge(ceremony, login string) (string, error) {
+	return h.tokenSvc.NewWebAuthnChallenge(ceremony, login)
+}
+
+func (h *Handler) ConsumeWebAuthnChallenge(ceremony, challenge string) (string, error) {
+	return h.tokenSvc.ConsumeWebAuthnChallenge(ceremony, challenge)
+}
+
+func (h *Handler) ReportWebAuthnClone(userID, login, credentialID string, device *token.Device) {
+	h.tokenSvc.ReportWebAuthnClone(userID, login, credentialID, device)
+}
+
+func (h *Handler) RenewAuthTokenPair(usr *user.User, claims *token.JWTCustomClaims, device *token.Device) (*token.AuthTokenPair, error) {
+	return h.tokenSvc.RenewAuthTokenPair(usr, claims, device)
 }
 
 func (h *Handler) VerifyAccessToken(tokenStr string) (*token.JWTCustomClaims, error) {
 	return h.tokenSvc.VerifyAccessToken(tokenStr)
 }
 
+func (h *Handler) VerifyClientAccessToken(tokenStr string, audience []string) (*token.JWTCustomClaims, error) {
+	return h.tokenSvc.VerifyClientAccessToken(tokenStr, audience)
+}
+
 func (h *Handler) VerifyRefreshToken(tokenStr string) (*token.JWTCustomClaims, error) {
 	return h.tokenSvc.VerifyRefreshToken(tokenStr)
 }
@@ -28,3 +84,107 @@ func (h *Handler) VerifyRefreshToken(tokenStr string) (*token.JWTCustomClaims, e
 func (h *Handler) RevokeRefreshToken(tokenStr string) error {
 	return h.tokenSvc.RevokeRefreshToken(tokenStr)
 }
+
+func (h *Handler) RevokeAccessToken(tokenStr string) error {
+	return h.tokenSvc.RevokeAccessToken(tokenStr)
+}
+
+func (h *Handler) Revoke(tokenStr, hint, clientID string, audience []string) error {
+	return h.tokenSvc.Revoke(tokenStr, hint, clientID, audience)
+}
+
+func (h *Handler) JWKS() *token.JWKSet {
+	return h.tokenSvc.JWKS()
+}
+
+func (h *Handler) SigningKeys() []*token.KeyInfo {
+	return h.tokenSvc.SigningKeys()
+}
+
+func (h *Handler) RotateSigningKeys() ([]*token.KeyInfo, error) {
+	return h.tokenSvc.RotateSigningKeys()
+}
+
+func (h *Handler) Introspect(tokenStr, hint, clientID string, audience []string) *token.Introspection {
+	return h.tokenSvc.Introspect(tokenStr, hint, clientID, audience)
+}
+
+func (h *Handler) Sessions(claims *token.JWTCustomClaims) ([]*token.Session, error) {
+	return h.tokenSvc.Sessions(claims)
+}
+
+func (h *Handler) RevokeSession(claims *token.JWTCustomClaims, sessionID string) error {
+	return h.tokenSvc.RevokeSession(claims, sessionID)
+}
+
+func (h *Handler) RevokeOtherSessions(claims *token.JWTCustomClaims) error {
+	return h.tokenSvc.RevokeOtherSessions(claims)
+}
+
+func (h *Handler) NewPasswordResetToken(usr *user.User) (*token.AuthToken, error) {
+	return h.tokenSvc.NewPasswordResetToken(usr)
+}
+
+func (h *Handler) RevokePasswordResetTokens(userID string) error {
+	return h.tokenSvc.RevokePasswordResetTokens(userID)
+}
+
+func (h *Handler) VerifyPasswordResetToken(tokenStr string) (*token.JWTCustomClaims, error) {
+	return h.tokenSvc.VerifyPasswordResetToken(tokenStr)
+}
+
+func (h *Handler) RevokeUserSessions(userID string) error {
+	return h.tokenSvc.RevokeUserSessions(userID)
+}
+
+func (h *Handler) NewAuthorizationCode(grant *token.AuthorizationGrant) (string, error) {
+	return h.tokenSvc.NewAuthorizationCode(grant)
+}
+
+func (h *Handler) ExchangeAuthorizationCode(code, clientID, redirectURI, codeVerifier string) (*token.AuthorizationGrant, error) {
+	return h.tokenSvc.ExchangeAuthorizationCode(code, clientID, redirectURI, codeVerifier)
+}
+
+func (h *Handler) NewDeviceAuthorization(authz *token.Authorization) (string, *token.DeviceGrant, error) {
+	return h.tokenSvc.NewDeviceAuthorization(authz)
+}
+
+func (h *Handler) PendingDeviceGrant(userCode, ip string) (*token.DeviceGrant, error) {
+	return h.tokenSvc.PendingDeviceGrant(userCode, ip)
+}
+
+func (h *Handler) ApproveDeviceGrant(grant *token.DeviceGrant, login string) error {
+	return h.tokenSvc.ApproveDeviceGrant(grant, login)
+}
+
+func (h *Handler) DenyDeviceGrant(grant *token.DeviceGrant) error {
+	return h.tokenSvc.DenyDeviceGrant(grant)
+}
+
+func (h *Handler) PollDeviceGrant(deviceCode, clientID string) (*token.AuthorizationGrant, error) {
+	return h.tokenSvc.PollDeviceGrant(deviceCode, clientID)
+}
+
+func (h *Handler) NewClientTokenPair(usr *user.User, authz *token.Authorization, device *token.Device) (*token.AuthTokenPair, error) {
+	return h.tokenSvc.NewClientTokenPair(usr, authz, device)
+}
+
+func (h *Handler) NewClientAccessToken(authz *token.Authorization) (*token.AuthToken, error) {
+	return h.tokenSvc.NewClientAccessToken(authz)
+}
+
+func (h *Handler) ExchangeToken(xchg *token.TokenExchange) (*token.AuthToken, error) {
+	return h.tokenSvc.ExchangeToken(xchg)
+}
+
+func (h *Handler) NewIDToken(usr *user.User, grant *token.AuthorizationGrant, accessToken string) (string, error) {
+	return h.tokenSvc.NewIDToken(usr, grant, accessToken)
+}
+
+func (h *Handler) Issuer() string {
+	return h.tokenSvc.Issuer()
+}
+
+func (h *Handler) IDTokenAlg() string {
+	return h.tokenSvc.IDTokenAlg()
+}
@@ -31,3 +31,49 @@ func (h *Handler) InsertUser(usr *user.User) (*user.User, error) {
 func (h *Handler) AssignUserVerification(login string, isVerified bool) error {
 	return h.table.AssignUserVerification(login, isVerified)
 }
+
+func (h *Handler) AssignUserPassword(login string, password user.Password) error {
+	return h.table.AssignUserPassword(login, password)
+}
+
+// PasswordHistory fetches the current and the former password hashes of user,
+// at most size hashes.
+func (h *Handler) PasswordHistory(usr *user.User, size int) ([]user.Password, error) {
+	if size <= 1 {
+		return []user.Password{usr.Password}, nil
+	}
+	history, err := h.table.ReadUserPasswordHistory(usr.Email.String(), size-1)
+	if err != nil {
+		return nil, err
+	}
+	return append([]user.Password{usr.Password}, history...), nil
+}
+
+// ChangeUserPassword replaces the password hash of user. The password history
+// keeps historySize hashes including the new one.
+func (h *Handler) ChangeUserPassword(login string, password user.Password, historySize int) error {
+	if historySize < 1 {
+		historySize = 1
+	}
+	return h.table.ChangeUserPassword(login, password, historySize-1)
+}
+
+func (h *Handler) ReadUserByID(id string) (*user.User, error) {
+	return h.table.ReadUserByID(id)
+}
+
+func (h *Handler) ReadUsers(search string, offset, limit int) ([]*user.User, error) {
+	return h.table.ReadUsers(search, offset, limit)
+}
+
+func (h *Handler) CountUsers(search string) (int, error) {
+	return h.table.CountUsers(search)
+}
+
+func (h *Handler) AssignUserDisabled(login string, isDisabled bool) error {
+	return h.table.AssignUserDisabled(login, isDisabled)
+}
+
+func (h *Handler) DeleteUser(login string) error {
+	return h.table.DeleteUser(login)
+}
@@ -0,0 +1,186 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/parthoshuvo/authsvc/table/credential"
+	"github.com/parthoshuvo/authsvc/table/user"
+	toknSvc "github.com/parthoshuvo/authsvc/token"
+	"github.com/parthoshuvo/authsvc/uc/token"
+	"github.com/parthoshuvo/authsvc/webauthn"
+)
+
+// defaultCredentialName names credentials registered without a name.
+const defaultCredentialName = "Passkey"
+
+// Handler implements WebAuthn credential use-cases, the challenges of the
+// ceremonies are held by the token cache.
+type Handler struct {
+	table     *credential.Table
+	rp        *webauthn.RelyingParty
+	toknHndlr *token.Handler
+}
+
+func NewHandler(t *credential.Table, rp *webauthn.RelyingParty, toknHndlr *token.Handler) *Handler {
+	return &Handler{t, rp, toknHndlr}
+}
+
+// BeginRegistration starts the registration of a credential of user, the
+// credentials of user are excluded.
+func (h *Handler) BeginRegistration(usr *user.User) (*webauthn.CreationOptions, error) {
+	creds, err := h.table.ReadUserCredentials(usr.Email.String())
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := h.toknHndlr.NewWebAuthnChallenge(webauthn.CeremonyCreate, usr.Email.String())
+	if err != nil {
+		return nil, err
+	}
+	entity := webauthn.UserEntity{
+		ID:          webauthn.Bytes(usr.RowGUID),
+		Name:        usr.Email.String(),
+		DisplayName: strings.TrimSpace(usr.Firstname + " " + usr.Lastname),
+	}
+	return h.rp.CreationOptions(challenge, entity, descriptors(creds)), nil
+}
+
+// FinishRegistration verifies the registration response of user and stores
+// the new credential.
+func (h *Handler) FinishRegistration(usr *user.User, resp *webauthn.AttestationResponse, name string) (*credential.Credential, error) {
+	challenge, err := webauthn.Challenge(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	login, err := h.toknHndlr.ConsumeWebAuthnChallenge(webauthn.CeremonyCreate, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if login != usr.Email.String() {
+		return nil, toknSvc.ErrWebAuthnChallengeInvalid
+	}
+	wc, err := h.rp.VerifyRegistration(challenge, resp)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = defaultCredentialName
+	}
+	aaguid, err := uuid.FromBytes(wc.AAGUID)
+	if err != nil {
+		return nil, err
+	}
+	cred := &credential.Credential{
+		ID:         b64(wc.ID),
+		Name:       name,
+		Login:      login,
+		PublicKey:  wc.PublicKey,
+		SignCount:  wc.SignCount,
+		AAGUID:     aaguid.String(),
+		Format:     wc.AttestationFormat,
+		Transports: wc.Transports,
+	}
+	if err := h.table.InsertCredential(cred); err != nil {
+		return nil, err
+	}
+	return h.table.ReadCredential(cred.ID)
+}
+
+// BeginLogin starts an authentication. Without login any discoverable
+// credential i.e. passkey is accepted, else the credentials of the login.
+func (h *Handler) BeginLogin(login string) (*webauthn.RequestOptions, error) {
+	var creds []*credential.Credential
+	if login != "" {
+		var err error
+		if creds, err = h.table.ReadUserCredentials(login); err != nil {
+			return nil, err
+		}
+	}
+	challenge, err := h.toknHndlr.NewWebAuthnChallenge(webauthn.CeremonyGet, login)
+	if err != nil {
+		return nil, err
+	}
+	return h.rp.RequestOptions(challenge, descriptors(creds)), nil
+}
+
+// FinishLogin verifies the authentication response from the device and
+// provides the credential of the user to sign in. A sign count which doesn't
+// increase reports a security event, disables the credential and fails with
+// webauthn.ErrCloneDetected.
+func (h *Handler) FinishLogin(resp *webauthn.AssertionResponse, device *toknSvc.Device) (*credential.Credential, error) {
+	challenge, err := webauthn.Challenge(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	login, err := h.toknHndlr.ConsumeWebAuthnChallenge(webauthn.CeremonyGet, challenge)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := h.table.ReadCredential(b64(resp.RawID))
+	if err != nil {
+		return nil, err
+	}
+	if cred == nil || login != "" && cred.Login != login {
+		return nil, credential.ErrCredentialNotFound
+	}
+	if len(resp.Response.UserHandle) > 0 && string(resp.Response.UserHandle) != cred.UserGUID {
+		return nil, webauthn.ErrVerification
+	}
+	if cred.Disabled {
+		return nil, credential.ErrCredentialDisabled
+	}
+	signCount, err := h.rp.VerifyAssertion(challenge, resp, cred.PublicKey, cred.SignCount)
+	if err == webauthn.ErrCloneDetected {
+		return nil, h.disableClone(cred, device)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := h.table.UpdateCredentialSignCount(cred.ID, signCount)
+	if err != nil {
+		return nil, err
+	}
+	if !ok && signCount > 0 {
+		return nil, h.disableClone(cred, device)
+	}
+	cred.SignCount = signCount
+	return cred, nil
+}
+
+// disableClone reports the credential of a possibly cloned authenticator and
+// disables it, it returns webauthn.ErrCloneDetected unless disabling fails.
+func (h *Handler) disableClone(cred *credential.Credential, device *toknSvc.Device) error {
+	h.toknHndlr.ReportWebAuthnClone(cred.UserGUID, cred.Login, cred.ID, device)
+	if err := h.table.DisableCredential(cred.ID); err != nil {
+		return err
+	}
+	return webauthn.ErrCloneDetected
+}
+
+// Credentials lists the credentials of user.
+func (h *Handler) Credentials(usr *user.User) ([]*credential.Credential, error) {
+	return h.table.ReadUserCredentials(usr.Email.String())
+}
+
+// DeleteCredential deletes a credential of user.
+func (h *Handler) DeleteCredential(usr *user.User, id string) error {
+	return h.table.DeleteCredential(usr.Email.String(), id)
+}
+
+// descriptors identifies credentials to authenticators.
+func descriptors(creds []*credential.Credential) []webauthn.CredentialDescriptor {
+	descs := make([]webauthn.CredentialDescriptor, 0, len(creds))
+	for _, cred := range creds {
+		id, err := base64.RawURLEncoding.DecodeString(cred.ID)
+		if err != nil {
+			continue
+		}
+		descs = append(descs, webauthn.CredentialDescriptor{Type: webauthn.CredentialType, ID: id, Transports: cred.Transports})
+	}
+	return descs
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
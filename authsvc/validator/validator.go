@@ -7,10 +7,12 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/parthoshuvo/authsvc/authz"
 )
 
 const (
 	validPasswdTag = "validPwd"
+	validPermTag   = "validPerm"
 )
 
 // PasswordValidator checks whether a password complies with minimal requirements.
@@ -64,8 +66,14 @@ func New() *validator.Validate {
 
 func registerCustomValidators(validate *validator.Validate) {
 	validate.RegisterValidation(validPasswdTag, passwordValidator)
+	validate.RegisterValidation(validPermTag, permissionValidator)
 }
 
 func passwordValidator(fl validator.FieldLevel) bool {
 	return NewPasswordValidator().Validate(fl.Field().String()) == nil
 }
+
+func permissionValidator(fl validator.FieldLevel) bool {
+	_, err := authz.Parse(fl.Field().String())
+	return err == nil
+}
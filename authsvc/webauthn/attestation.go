@@ -0,0 +1,104 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Supported attestation statement formats (WebAuthn section 8).
+const (
+	FormatNone   = "none"
+	FormatPacked = "packed"
+)
+
+// oidFIDOAAGUID is the certificate extension of the AAGUID of an
+// authenticator model (WebAuthn section 8.2.1).
+var oidFIDOAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// attestationObject is the attestation of a new credential.
+type attestationObject struct {
+	Format   string          `cbor:"fmt"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+// packedStatement is the attestation statement of the packed format.
+type packedStatement struct {
+	Alg int      `cbor:"alg"`
+	Sig []byte   `cbor:"sig"`
+	X5C [][]byte `cbor:"x5c"`
+}
+
+func parseAttestationObject(raw []byte) (*attestationObject, error) {
+	var att attestationObject
+	if err := cbor.Unmarshal(raw, &att); err != nil {
+		return nil, fmt.Errorf("%w: attestation object: %v", ErrInvalidEncoding, err)
+	}
+	return &att, nil
+}
+
+// verify verifies the attestation statement of the format.
+func (att *attestationObject) verify(authData *authenticatorData, clientDataHash []byte, key *publicKey) error {
+	switch att.Format {
+	case FormatNone:
+		var stmt map[string]interface{}
+		if err := cbor.Unmarshal(att.AttStmt, &stmt); err != nil || len(stmt) > 0 {
+			return fmt.Errorf("%w: none attestation statement isn't empty", ErrVerification)
+		}
+		return nil
+	case FormatPacked:
+		var stmt packedStatement
+		if err := cbor.Unmarshal(att.AttStmt, &stmt); err != nil {
+			return fmt.Errorf("%w: packed attestation statement: %v", ErrInvalidEncoding, err)
+		}
+		signed := append(append([]byte{}, att.AuthData...), clientDataHash...)
+		if len(stmt.X5C) == 0 {
+			return verifySelfAttestation(&stmt, signed, key)
+		}
+		return verifyPackedCertificate(&stmt, signed, authData.AAGUID)
+	}
+	return fmt.Errorf("%w: attestation format %s", ErrUnsupported, att.Format)
+}
+
+// verifySelfAttestation verifies a packed attestation signed with the
+// credential private key.
+func verifySelfAttestation(stmt *packedStatement, signed []byte, key *publicKey) error {
+	if stmt.Alg != key.alg {
+		return fmt.Errorf("%w: self attestation algorithm mismatch", ErrVerification)
+	}
+	return key.verify(signed, stmt.Sig)
+}
+
+// verifyPackedCertificate verifies a packed attestation signed with the key of
+// an attestation certificate (WebAuthn section 8.2.1). The certificate chain
+// isn't validated against trust anchors, i.e. the attestation isn't trusted
+// but verified.
+func verifyPackedCertificate(stmt *packedStatement, signed []byte, aaguid []byte) error {
+	cert, err := x509.ParseCertificate(stmt.X5C[0])
+	if err != nil {
+		return fmt.Errorf("%w: attestation certificate: %v", ErrInvalidEncoding, err)
+	}
+	if err := verifySignature(stmt.Alg, cert.PublicKey, signed, stmt.Sig); err != nil {
+		return err
+	}
+	if cert.Version != 3 || cert.IsCA {
+		return fmt.Errorf("%w: attestation certificate must be a version 3 end-entity certificate", ErrVerification)
+	}
+	if len(cert.Subject.OrganizationalUnit) != 1 || cert.Subject.OrganizationalUnit[0] != "Authenticator Attestation" {
+		return fmt.Errorf("%w: attestation certificate subject isn't an authenticator attestation", ErrVerification)
+	}
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidFIDOAAGUID) {
+			continue
+		}
+		var certAAGUID []byte
+		if _, err := asn1.Unmarshal(ext.Value, &certAAGUID); err != nil || ext.Critical || !bytes.Equal(certAAGUID, aaguid) {
+			return fmt.Errorf("%w: attestation certificate AAGUID mismatch", ErrVerification)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE algorithms of supported credentials (RFC 8152, RFC 8812).
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// COSE key types and curves.
+const (
+	ktyOKP     = 1
+	ktyEC2     = 2
+	ktyRSA     = 3
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+// supportedParameters lists the supported algorithms in order of preference.
+func supportedParameters() []CredentialParameter {
+	return []CredentialParameter{
+		{CredentialType, AlgES256},
+		{CredentialType, AlgEdDSA},
+		{CredentialType, AlgRS256},
+	}
+}
+
+// coseKey is a COSE_Key of a credential public key. The parameter -1 is the
+// curve of EC2 and OKP keys and the modulus of RSA keys, -2 is the x
+// coordinate or the exponent.
+type coseKey struct {
+	Kty int             `cbor:"1,keyasint"`
+	Alg int             `cbor:"3,keyasint"`
+	P1  cbor.RawMessage `cbor:"-1,keyasint"`
+	P2  []byte          `cbor:"-2,keyasint"`
+	P3  []byte          `cbor:"-3,keyasint"`
+}
+
+// publicKey is a credential public key with its algorithm.
+type publicKey struct {
+	alg int
+	key crypto.PublicKey
+}
+
+func parseCOSEKey(raw []byte) (*publicKey, error) {
+	var ck coseKey
+	if err := cbor.Unmarshal(raw, &ck); err != nil {
+		return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidEncoding, err)
+	}
+	switch {
+	case ck.Kty == ktyEC2 && ck.Alg == AlgES256:
+		var crv int
+		if err := cbor.Unmarshal(ck.P1, &crv); err != nil || crv != crvP256 || len(ck.P2) != 32 || len(ck.P3) != 32 {
+			return nil, fmt.Errorf("%w: ES256 key isn't a P-256 key", ErrUnsupported)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(ck.P2), Y: new(big.Int).SetBytes(ck.P3)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: ES256 key isn't on the curve", ErrInvalidEncoding)
+		}
+		return &publicKey{ck.Alg, key}, nil
+	case ck.Kty == ktyOKP && ck.Alg == AlgEdDSA:
+		var crv int
+		if err := cbor.Unmarshal(ck.P1, &crv); err != nil || crv != crvEd25519 || len(ck.P2) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: EdDSA key isn't an Ed25519 key", ErrUnsupported)
+		}
+		return &publicKey{ck.Alg, ed25519.PublicKey(ck.P2)}, nil
+	case ck.Kty == ktyRSA && ck.Alg == AlgRS256:
+		var n []byte
+		if err := cbor.Unmarshal(ck.P1, &n); err != nil || len(ck.P2) == 0 || len(ck.P2) > 4 {
+			return nil, fmt.Errorf("%w: RS256 key is malformed", ErrInvalidEncoding)
+		}
+		e := new(big.Int).SetBytes(ck.P2)
+		return &publicKey{ck.Alg, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(e.Int64())}}, nil
+	}
+	return nil, fmt.Errorf("%w: key type %d with algorithm %d", ErrUnsupported, ck.Kty, ck.Alg)
+}
+
+func (pk *publicKey) verify(data, sig []byte) error {
+	return verifySignature(pk.alg, pk.key, data, sig)
+}
+
+// verifySignature verifies a signature of the COSE algorithm, ES256
+// signatures are ASN.1 DER encoded.
+func verifySignature(alg int, key crypto.PublicKey, data, sig []byte) error {
+	ok := false
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if alg == AlgES256 {
+			sum := sha256.Sum256(data)
+			ok = ecdsa.VerifyASN1(k, sum[:], sig)
+		}
+	case ed25519.PublicKey:
+		if alg == AlgEdDSA {
+			ok = ed25519.Verify(k, data, sig)
+		}
+	case *rsa.PublicKey:
+		if alg == AlgRS256 {
+			sum := sha256.Sum256(data)
+			ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+		}
+	default:
+		return fmt.Errorf("%w: public key type %T", ErrUnsupported, key)
+	}
+	if !ok {
+		return fmt.Errorf("%w: signature mismatch", ErrVerification)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Flags of authenticator data (WebAuthn section 6.1).
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+	flagExtensions   = 0x80
+)
+
+// authenticator data sizes of the fixed length parts.
+const (
+	rpIDHashSize  = 32
+	aaguidSize    = 16
+	authDataSize  = rpIDHashSize + 1 + 4
+	credIDLenSize = 2
+)
+
+// clientData is the CollectedClientData of a ceremony (WebAuthn section 5.8.1).
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func parseClientData(raw []byte) (*clientData, error) {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, fmt.Errorf("%w: client data: %v", ErrInvalidEncoding, err)
+	}
+	return &cd, nil
+}
+
+// authenticatorData is the data of an authenticator signed by attestations
+// and assertions, credential ID and public key are present in registrations.
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func (ad *authenticatorData) has(flag byte) bool {
+	return ad.Flags&flag == flag
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < authDataSize {
+		return nil, fmt.Errorf("%w: authenticator data is too short", ErrInvalidEncoding)
+	}
+	ad := &authenticatorData{
+		RPIDHash:  raw[:rpIDHashSize],
+		Flags:     raw[rpIDHashSize],
+		SignCount: binary.BigEndian.Uint32(raw[rpIDHashSize+1 : authDataSize]),
+	}
+	rest := raw[authDataSize:]
+	if ad.has(flagAttestedData) {
+		if len(rest) < aaguidSize+credIDLenSize {
+			return nil, fmt.Errorf("%w: attested credential data is too short", ErrInvalidEncoding)
+		}
+		ad.AAGUID = rest[:aaguidSize]
+		n := int(binary.BigEndian.Uint16(rest[aaguidSize : aaguidSize+credIDLenSize]))
+		rest = rest[aaguidSize+credIDLenSize:]
+		if len(rest) < n {
+			return nil, fmt.Errorf("%w: credential ID is too short", ErrInvalidEncoding)
+		}
+		ad.CredentialID, rest = rest[:n], rest[n:]
+		dec := cbor.NewDecoder(bytes.NewReader(rest))
+		var key cbor.RawMessage
+		if err := dec.Decode(&key); err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidEncoding, err)
+		}
+		ad.PublicKey, rest = key, rest[dec.NumBytesRead():]
+	}
+	if !ad.has(flagExtensions) && len(rest) > 0 {
+		return nil, fmt.Errorf("%w: authenticator data has trailing bytes", ErrInvalidEncoding)
+	}
+	return ad, nil
+}
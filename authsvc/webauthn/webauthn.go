@@ -0,0 +1,341 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/parthoshuvo/authsvc/log4u"
+)
+
+// Ceremony types of the client data (WebAuthn section 5.8.1).
+const (
+	CeremonyCreate = "webauthn.create"
+	CeremonyGet    = "webauthn.get"
+)
+
+// CredentialType is the type of WebAuthn credentials.
+const CredentialType = "public-key"
+
+// ceremonyTimeout is the time a user has to complete a ceremony.
+const ceremonyTimeout = 5 * time.Minute
+
+var (
+	ErrVerification     = errors.New("webauthn verification failed")
+	ErrUnsupported      = errors.New("webauthn credential isn't supported")
+	ErrCloneDetected    = errors.New("webauthn sign count didn't increase, the authenticator may be cloned")
+	ErrUserNotVerified  = errors.New("webauthn user verification is required")
+	ErrInvalidEncoding  = errors.New("webauthn data is malformed")
+	ErrNoChallengeFound = errors.New("webauthn client data has no challenge")
+)
+
+// Bytes is binary data encoded base64url without padding in JSON, the
+// encoding of the WebAuthn JSON serialization.
+type Bytes []byte
+
+// MarshalJSON encodes the bytes base64url without padding.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes base64url with or without padding.
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// RelyingPartyDef defines the WebAuthn relying party i.e. the site passkeys
+// are registered for.
+type RelyingPartyDef struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// RelyingParty runs the registration and authentication ceremonies of
+// WebAuthn credentials (Web Authentication Level 2) with none and packed
+// attestation.
+type RelyingParty struct {
+	id      string
+	name    string
+	origins []string
+	idHash  []byte
+}
+
+func NewRelyingParty(def *RelyingPartyDef) *RelyingParty {
+	if def == nil || def.ID == "" {
+		log.Fatal("WebAuthn relying party ID is missing")
+	}
+	name, origins := def.Name, def.Origins
+	if name == "" {
+		name = def.ID
+	}
+	if len(origins) == 0 {
+		origins = []string{"https://" + def.ID}
+	}
+	sum := sha256.Sum256([]byte(def.ID))
+	return &RelyingParty{def.ID, name, origins, sum[:]}
+}
+
+// RelyingPartyEntity describes the relying party to authenticators.
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity describes the user account of a credential, the ID is the user
+// handle returned by discoverable credentials.
+type UserEntity struct {
+	ID          Bytes  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameter is a supported credential type and algorithm.
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CredentialDescriptor identifies a registered credential.
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         Bytes    `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection are the requirements of authenticators.
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions are the options of navigator.credentials.create().
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the options of navigator.credentials.get().
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the PublicKeyCredential of a registration.
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes    `json:"clientDataJSON"`
+		AttestationObject Bytes    `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential of an authentication.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AuthenticatorData Bytes `json:"authenticatorData"`
+		Signature         Bytes `json:"signature"`
+		UserHandle        Bytes `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is a verified new credential.
+type Credential struct {
+	ID                []byte
+	PublicKey         []byte
+	SignCount         uint32
+	AAGUID            []byte
+	AttestationFormat string
+	Transports        []string
+}
+
+// CreationOptions are the options of the registration of a credential of
+// user, registered credentials are excluded. Discoverable credentials i.e.
+// passkeys are preferred.
+func (rp *RelyingParty) CreationOptions(challenge string, user UserEntity, exclude []CredentialDescriptor) *CreationOptions {
+	return &CreationOptions{
+		RP:                 RelyingPartyEntity{rp.id, rp.name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   supportedParameters(),
+		Timeout:            ceremonyTimeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "direct",
+	}
+}
+
+// RequestOptions are the options of an authentication, without allowed
+// credentials a discoverable credential is chosen by the user. The user must
+// be verified, a credential is a second factor by itself then.
+func (rp *RelyingParty) RequestOptions(challenge string, allow []CredentialDescriptor) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          ceremonyTimeout.Milliseconds(),
+		RPID:             rp.id,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// VerifyRegistration verifies the response of a registration of the
+// challenge and provides the new credential (WebAuthn section 7.1).
+func (rp *RelyingParty) VerifyRegistration(challenge string, resp *AttestationResponse) (*Credential, error) {
+	if resp.Type != CredentialType {
+		return nil, fmt.Errorf("%w: credential type %s", ErrUnsupported, resp.Type)
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, CeremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+	att, err := parseAttestationObject(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	authData, err := parseAuthenticatorData(att.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if !authData.has(flagAttestedData) {
+		return nil, fmt.Errorf("%w: attested credential data is missing", ErrVerification)
+	}
+	if !bytes.Equal(authData.CredentialID, resp.RawID) {
+		return nil, fmt.Errorf("%w: credential ID mismatch", ErrVerification)
+	}
+	key, err := parseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	if err := att.verify(authData, clientDataHash[:], key); err != nil {
+		return nil, err
+	}
+	return &Credential{
+		ID:                authData.CredentialID,
+		PublicKey:         authData.PublicKey,
+		SignCount:         authData.SignCount,
+		AAGUID:            authData.AAGUID,
+		AttestationFormat: att.Format,
+		Transports:        resp.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion verifies the response of an authentication of the
+// challenge with the public key and sign count of the registered credential,
+// the new sign count is provided (WebAuthn section 7.2). A sign count which
+// doesn't increase indicates a cloned authenticator.
+func (rp *RelyingParty) VerifyAssertion(challenge string, resp *AssertionResponse, publicKey []byte, signCount uint32) (uint32, error) {
+	if resp.Type != CredentialType {
+		return 0, fmt.Errorf("%w: credential type %s", ErrUnsupported, resp.Type)
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, CeremonyGet, challenge); err != nil {
+		return 0, err
+	}
+	authData, err := parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+	if !authData.has(flagUserVerified) {
+		return 0, ErrUserNotVerified
+	}
+	key, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte{}, resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := key.verify(signed, resp.Response.Signature); err != nil {
+		return 0, err
+	}
+	if (authData.SignCount != 0 || signCount != 0) && authData.SignCount <= signCount {
+		return 0, ErrCloneDetected
+	}
+	return authData.SignCount, nil
+}
+
+// verifyAuthenticatorData checks the relying party and the user presence.
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData) error {
+	if !bytes.Equal(authData.RPIDHash, rp.idHash) {
+		return fmt.Errorf("%w: relying party ID mismatch", ErrVerification)
+	}
+	if !authData.has(flagUserPresent) {
+		return fmt.Errorf("%w: user isn't present", ErrVerification)
+	}
+	return nil
+}
+
+// verifyClientData checks the ceremony type, challenge and origin of the
+// client data.
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony, challenge string) error {
+	cd, err := parseClientData(raw)
+	if err != nil {
+		return err
+	}
+	if cd.Type != ceremony {
+		return fmt.Errorf("%w: ceremony type %s", ErrVerification, cd.Type)
+	}
+	if cd.Challenge != challenge {
+		return fmt.Errorf("%w: challenge mismatch", ErrVerification)
+	}
+	if cd.CrossOrigin {
+		return fmt.Errorf("%w: cross origin ceremonies aren't allowed", ErrVerification)
+	}
+	for _, origin := range rp.origins {
+		if cd.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: origin %s isn't allowed", ErrVerification, cd.Origin)
+}
+
+// Challenge provides the challenge of the client data of a ceremony response,
+// the challenge identifies the ceremony.
+func Challenge(clientDataJSON []byte) (string, error) {
+	cd, err := parseClientData(clientDataJSON)
+	if err != nil {
+		return "", err
+	}
+	if cd.Challenge == "" {
+		return "", ErrNoChallengeFound
+	}
+	return cd.Challenge, nil
+}
@@ -0,0 +1,632 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	testRPID      = "example.com"
+	testOrigin    = "https://example.com"
+	testChallenge = "dGVzdC1jaGFsbGVuZ2U"
+)
+
+var testAAGUID = bytes.Repeat([]byte{0x42}, aaguidSize)
+
+// softAuthenticator is a software authenticator of a single ES256 or EdDSA
+// credential.
+type softAuthenticator struct {
+	credID    []byte
+	ecKey     *ecdsa.PrivateKey
+	edKey     ed25519.PrivateKey
+	signCount uint32
+}
+
+func newES256Authenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{credID: []byte("es256-credential"), ecKey: key}
+}
+
+func newEdDSAAuthenticator(t *testing.T) *softAuthenticator {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{credID: []byte("eddsa-credential"), edKey: key}
+}
+
+func (a *softAuthenticator) alg() int {
+	if a.edKey != nil {
+		return AlgEdDSA
+	}
+	return AlgES256
+}
+
+func (a *softAuthenticator) publicKey(t *testing.T) []byte {
+	var key map[int]interface{}
+	if a.edKey != nil {
+		key = map[int]interface{}{1: ktyOKP, 3: AlgEdDSA, -1: crvEd25519, -2: []byte(a.edKey.Public().(ed25519.PublicKey))}
+	} else {
+		x, y := make([]byte, 32), make([]byte, 32)
+		a.ecKey.X.FillBytes(x)
+		a.ecKey.Y.FillBytes(y)
+		key = map[int]interface{}{1: ktyEC2, 3: AlgES256, -1: crvP256, -2: x, -3: y}
+	}
+	return mustCBOR(t, key)
+}
+
+func (a *softAuthenticator) sign(t *testing.T, data []byte) []byte {
+	if a.edKey != nil {
+		return ed25519.Sign(a.edKey, data)
+	}
+	return signES256(t, a.ecKey, data)
+}
+
+// authData builds authenticator data of the relying party, the attested
+// credential data is appended if present.
+func (a *softAuthenticator) authData(rpID string, flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = append(data, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(data[rpIDHashSize+1:], a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) attestedData(t *testing.T) []byte {
+	data := append([]byte{}, testAAGUID...)
+	data = append(data, make([]byte, credIDLenSize)...)
+	binary.BigEndian.PutUint16(data[aaguidSize:], uint16(len(a.credID)))
+	data = append(data, a.credID...)
+	return append(data, a.publicKey(t)...)
+}
+
+// ceremony describes a ceremony response, tests alter it to produce failures.
+type ceremony struct {
+	typ         string
+	challenge   string
+	origin      string
+	crossOrigin bool
+	rpID        string
+	flags       byte
+}
+
+func newCeremony(typ string, flags byte) *ceremony {
+	return &ceremony{typ, testChallenge, testOrigin, false, testRPID, flags}
+}
+
+func (c *ceremony) clientData(t *testing.T) []byte {
+	data, err := json.Marshal(&clientData{c.typ, c.challenge, c.origin, c.crossOrigin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// attestation is the attestation statement of a registration.
+type attestation struct {
+	format  string
+	certKey *ecdsa.PrivateKey
+	cert    []byte
+}
+
+// register answers a registration ceremony with the attestation.
+func (a *softAuthenticator) register(t *testing.T, c *ceremony, att *attestation) *AttestationResponse {
+	clientDataJSON := c.clientData(t)
+	authData := a.authData(c.rpID, c.flags|flagAttestedData, a.attestedData(t))
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	stmt := map[string]interface{}{}
+	switch {
+	case att.format == FormatPacked && att.cert != nil:
+		stmt = map[string]interface{}{"alg": AlgES256, "sig": signES256(t, att.certKey, signed), "x5c": [][]byte{att.cert}}
+	case att.format == FormatPacked:
+		stmt = map[string]interface{}{"alg": a.alg(), "sig": a.sign(t, signed)}
+	}
+	resp := &AttestationResponse{ID: string(a.credID), RawID: a.credID, Type: CredentialType}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AttestationObject = mustCBOR(t, map[string]interface{}{"fmt": att.format, "attStmt": stmt, "authData": authData})
+	return resp
+}
+
+// assert answers an authentication ceremony, the sign count is increased
+// beforehand.
+func (a *softAuthenticator) assert(t *testing.T, c *ceremony) *AssertionResponse {
+	a.signCount++
+	clientDataJSON := c.clientData(t)
+	authData := a.authData(c.rpID, c.flags, nil)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	resp := &AssertionResponse{ID: string(a.credID), RawID: a.credID, Type: CredentialType}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AuthenticatorData = authData
+	resp.Response.Signature = a.sign(t, append(append([]byte{}, authData...), clientDataHash[:]...))
+	return resp
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	sum := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+// mustCBOR encodes canonical CBOR, the encoding of a key map is the same
+// every time.
+func mustCBOR(t *testing.T, v interface{}) []byte {
+	em, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := em.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// attestationCertificate creates a self-signed packed attestation
+// certificate of the AAGUID.
+func attestationCertificate(t *testing.T, aaguid []byte) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext, err := asn1.Marshal(aaguid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"US"},
+			Organization:       []string{"Soft Authenticator"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "Soft Authenticator Attestation",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: oidFIDOAAGUID, Value: ext}},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+func newTestRelyingParty() *RelyingParty {
+	return NewRelyingParty(&RelyingPartyDef{ID: testRPID, Origins: []string{testOrigin}})
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	certKey, cert := attestationCertificate(t, testAAGUID)
+	otherKey, otherCert := attestationCertificate(t, bytes.Repeat([]byte{0x24}, aaguidSize))
+	tests := []struct {
+		name      string
+		auth      func(*testing.T) *softAuthenticator
+		att       *attestation
+		alter     func(*ceremony)
+		alterResp func(*AttestationResponse)
+		err       error
+	}{
+		{name: "none ES256", auth: newES256Authenticator, att: &attestation{format: FormatNone}},
+		{name: "none EdDSA", auth: newEdDSAAuthenticator, att: &attestation{format: FormatNone}},
+		{name: "packed self ES256", auth: newES256Authenticator, att: &attestation{format: FormatPacked}},
+		{name: "packed self EdDSA", auth: newEdDSAAuthenticator, att: &attestation{format: FormatPacked}},
+		{name: "packed x5c", auth: newES256Authenticator, att: &attestation{FormatPacked, certKey, cert}},
+		{
+			name: "packed x5c of another key",
+			auth: newES256Authenticator,
+			att:  &attestation{FormatPacked, certKey, otherCert},
+			err:  ErrVerification,
+		},
+		{
+			name: "packed x5c of another AAGUID",
+			auth: newES256Authenticator,
+			att:  &attestation{FormatPacked, otherKey, otherCert},
+			err:  ErrVerification,
+		},
+		{
+			name: "unsupported format",
+			auth: newES256Authenticator,
+			att:  &attestation{format: "tpm"},
+			err:  ErrUnsupported,
+		},
+		{
+			name:  "wrong ceremony type",
+			auth:  newES256Authenticator,
+			att:   &attestation{format: FormatNone},
+			alter: func(c *ceremony) { c.typ = CeremonyGet },
+			err:   ErrVerification,
+		},
+		{
+			name:  "wrong challenge",
+			auth:  newES256Authenticator,
+			att:   &attestation{format: FormatNone},
+			alter: func(c *ceremony) { c.challenge = "b3RoZXI" },
+			err:   ErrVerification,
+		},
+		{
+			name:  "wrong origin",
+			auth:  newES256Authenticator,
+			att:   &attestation{format: FormatNone},
+			alter: func(c *ceremony) { c.origin = "https://evil.example.com" },
+			err:   ErrVerification,
+		},
+		{
+			name:  "wrong relying party ID",
+			auth:  newES256Authenticator,
+			att:   &attestation{format: FormatNone},
+			alter: func(c *ceremony) { c.rpID = "evil.example.com" },
+			err:   ErrVerification,
+		},
+		{
+			name:  "user not present",
+			auth:  newES256Authenticator,
+			att:   &attestation{format: FormatNone},
+			alter: func(c *ceremony) { c.flags = flagUserVerified },
+			err:   ErrVerification,
+		},
+		{
+			name:      "credential ID mismatch",
+			auth:      newES256Authenticator,
+			att:       &attestation{format: FormatNone},
+			alterResp: func(resp *AttestationResponse) { resp.RawID = []byte("other-credential") },
+			err:       ErrVerification,
+		},
+		{
+			name:      "malformed attestation object",
+			auth:      newES256Authenticator,
+			att:       &attestation{format: FormatNone},
+			alterResp: func(resp *AttestationResponse) { resp.Response.AttestationObject = []byte{0xff} },
+			err:       ErrInvalidEncoding,
+		},
+		{
+			name:      "unsupported credential type",
+			auth:      newES256Authenticator,
+			att:       &attestation{format: FormatNone},
+			alterResp: func(resp *AttestationResponse) { resp.Type = "password" },
+			err:       ErrUnsupported,
+		},
+	}
+	rp := newTestRelyingParty()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := tt.auth(t)
+			c := newCeremony(CeremonyCreate, flagUserPresent|flagUserVerified)
+			if tt.alter != nil {
+				tt.alter(c)
+			}
+			resp := auth.register(t, c, tt.att)
+			if tt.alterResp != nil {
+				tt.alterResp(resp)
+			}
+			cred, err := rp.VerifyRegistration(testChallenge, resp)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(cred.ID, auth.credID) || !bytes.Equal(cred.AAGUID, testAAGUID) || cred.AttestationFormat != tt.att.format {
+				t.Errorf("credential = %+v", cred)
+			}
+			if !bytes.Equal(cred.PublicKey, auth.publicKey(t)) {
+				t.Error("public key of the credential differs from the authenticator's")
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationNoneWithStatement(t *testing.T) {
+	auth := newES256Authenticator(t)
+	resp := auth.register(t, newCeremony(CeremonyCreate, flagUserPresent), &attestation{format: FormatPacked})
+	var att attestationObject
+	if err := cbor.Unmarshal(resp.Response.AttestationObject, &att); err != nil {
+		t.Fatal(err)
+	}
+	att.Format = FormatNone
+	resp.Response.AttestationObject = mustCBOR(t, &att)
+	if _, err := newTestRelyingParty().VerifyRegistration(testChallenge, resp); !errors.Is(err, ErrVerification) {
+		t.Fatalf("error = %v, want %v", err, ErrVerification)
+	}
+}
+
+// registered registers the credential of the authenticator with none
+// attestation.
+func registered(t *testing.T, rp *RelyingParty, auth *softAuthenticator) *Credential {
+	resp := auth.register(t, newCeremony(CeremonyCreate, flagUserPresent|flagUserVerified), &attestation{format: FormatNone})
+	cred, err := rp.VerifyRegistration(testChallenge, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cred
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	tests := []struct {
+		name      string
+		auth      func(*testing.T) *softAuthenticator
+		alter     func(*ceremony)
+		alterResp func(*AssertionResponse)
+		err       error
+	}{
+		{name: "ES256", auth: newES256Authenticator},
+		{name: "EdDSA", auth: newEdDSAAuthenticator},
+		{
+			name:  "user not verified",
+			auth:  newES256Authenticator,
+			alter: func(c *ceremony) { c.flags = flagUserPresent },
+			err:   ErrUserNotVerified,
+		},
+		{
+			name:  "user not present",
+			auth:  newES256Authenticator,
+			alter: func(c *ceremony) { c.flags = flagUserVerified },
+			err:   ErrVerification,
+		},
+		{
+			name:  "wrong ceremony type",
+			auth:  newES256Authenticator,
+			alter: func(c *ceremony) { c.typ = CeremonyCreate },
+			err:   ErrVerification,
+		},
+		{
+			name:  "wrong challenge",
+			auth:  newES256Authenticator,
+			alter: func(c *ceremony) { c.challenge = "b3RoZXI" },
+			err:   ErrVerification,
+		},
+		{
+			name:  "wrong origin",
+			auth:  newEdDSAAuthenticator,
+			alter: func(c *ceremony) { c.origin = "https://example.com.evil" },
+			err:   ErrVerification,
+		},
+		{
+			name:  "cross origin",
+			auth:  newES256Authenticator,
+			alter: func(c *ceremony) { c.crossOrigin = true },
+			err:   ErrVerification,
+		},
+		{
+			name:  "wrong relying party ID",
+			auth:  newES256Authenticator,
+			alter: func(c *ceremony) { c.rpID = "evil.example.com" },
+			err:   ErrVerification,
+		},
+		{
+			name: "signature of other data",
+			auth: newEdDSAAuthenticator,
+			alterResp: func(resp *AssertionResponse) {
+				resp.Response.ClientDataJSON = append(resp.Response.ClientDataJSON, ' ')
+			},
+			err: ErrVerification,
+		},
+		{
+			name: "malformed authenticator data",
+			auth: newES256Authenticator,
+			alterResp: func(resp *AssertionResponse) {
+				resp.Response.AuthenticatorData = resp.Response.AuthenticatorData[:rpIDHashSize]
+			},
+			err: ErrInvalidEncoding,
+		},
+	}
+	rp := newTestRelyingParty()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := tt.auth(t)
+			cred := registered(t, rp, auth)
+			c := newCeremony(CeremonyGet, flagUserPresent|flagUserVerified)
+			if tt.alter != nil {
+				tt.alter(c)
+			}
+			resp := auth.assert(t, c)
+			if tt.alterResp != nil {
+				tt.alterResp(resp)
+			}
+			signCount, err := rp.VerifyAssertion(testChallenge, resp, cred.PublicKey, cred.SignCount)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if signCount != auth.signCount {
+				t.Errorf("sign count = %d, want %d", signCount, auth.signCount)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionSignCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    uint32
+		asserted  uint32
+		wantCount uint32
+		wantErr   error
+	}{
+		{name: "increased", stored: 4, asserted: 5, wantCount: 5},
+		{name: "unsupported by the authenticator", stored: 0, asserted: 0, wantCount: 0},
+		{name: "first use", stored: 0, asserted: 1, wantCount: 1},
+		{name: "replayed", stored: 5, asserted: 5, wantErr: ErrCloneDetected},
+		{name: "decreased", stored: 5, asserted: 3, wantErr: ErrCloneDetected},
+		{name: "reset to zero", stored: 5, asserted: 0, wantErr: ErrCloneDetected},
+	}
+	rp := newTestRelyingParty()
+	auth := newES256Authenticator(t)
+	cred := registered(t, rp, auth)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// assert increases the sign count before signing.
+			auth.signCount = tt.asserted - 1
+			resp := auth.assert(t, newCeremony(CeremonyGet, flagUserPresent|flagUserVerified))
+			signCount, err := rp.VerifyAssertion(testChallenge, resp, cred.PublicKey, tt.stored)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if signCount != tt.wantCount {
+				t.Errorf("sign count = %d, want %d", signCount, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionOfOtherCredential(t *testing.T) {
+	rp := newTestRelyingParty()
+	auth := newES256Authenticator(t)
+	registered(t, rp, auth)
+	other := registered(t, rp, newES256Authenticator(t))
+	resp := auth.assert(t, newCeremony(CeremonyGet, flagUserPresent|flagUserVerified))
+	if _, err := rp.VerifyAssertion(testChallenge, resp, other.PublicKey, 0); !errors.Is(err, ErrVerification) {
+		t.Fatalf("error = %v, want %v", err, ErrVerification)
+	}
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	auth := newES256Authenticator(t)
+	attested := auth.attestedData(t)
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "assertion", data: auth.authData(testRPID, flagUserPresent, nil)},
+		{name: "attested credential", data: auth.authData(testRPID, flagUserPresent|flagAttestedData, attested)},
+		{name: "extensions", data: auth.authData(testRPID, flagUserPresent|flagExtensions, mustCBOR(t, map[string]bool{"credProps": true}))},
+		{name: "too short", data: make([]byte, authDataSize-1), err: ErrInvalidEncoding},
+		{name: "trailing bytes", data: auth.authData(testRPID, flagUserPresent, []byte{0}), err: ErrInvalidEncoding},
+		{
+			name: "attested credential data too short",
+			data: auth.authData(testRPID, flagUserPresent|flagAttestedData, attested[:aaguidSize]),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "credential ID too short",
+			data: auth.authData(testRPID, flagUserPresent|flagAttestedData, attested[:aaguidSize+credIDLenSize+len(auth.credID)-1]),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "public key missing",
+			data: auth.authData(testRPID, flagUserPresent|flagAttestedData, attested[:aaguidSize+credIDLenSize+len(auth.credID)]),
+			err:  ErrInvalidEncoding,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad, err := parseAuthenticatorData(tt.data)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ad.has(flagAttestedData) && (!bytes.Equal(ad.CredentialID, auth.credID) || !bytes.Equal(ad.PublicKey, auth.publicKey(t))) {
+				t.Errorf("attested credential data = %+v", ad)
+			}
+		})
+	}
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	ec := newES256Authenticator(t)
+	x, y := make([]byte, 32), make([]byte, 32)
+	ec.ecKey.X.FillBytes(x)
+	ec.ecKey.Y.FillBytes(y)
+	rsaModulus := bytes.Repeat([]byte{0xc5}, 256)
+	tests := []struct {
+		name string
+		key  map[int]interface{}
+		alg  int
+		err  error
+	}{
+		{name: "ES256", key: map[int]interface{}{1: ktyEC2, 3: AlgES256, -1: crvP256, -2: x, -3: y}, alg: AlgES256},
+		{name: "EdDSA", key: map[int]interface{}{1: ktyOKP, 3: AlgEdDSA, -1: crvEd25519, -2: make([]byte, ed25519.PublicKeySize)}, alg: AlgEdDSA},
+		{name: "RS256", key: map[int]interface{}{1: ktyRSA, 3: AlgRS256, -1: rsaModulus, -2: []byte{1, 0, 1}}, alg: AlgRS256},
+		{name: "ES256 of another curve", key: map[int]interface{}{1: ktyEC2, 3: AlgES256, -1: 2, -2: x, -3: y}, err: ErrUnsupported},
+		{name: "ES256 off the curve", key: map[int]interface{}{1: ktyEC2, 3: AlgES256, -1: crvP256, -2: x, -3: x}, err: ErrInvalidEncoding},
+		{name: "EdDSA of another curve", key: map[int]interface{}{1: ktyOKP, 3: AlgEdDSA, -1: 7, -2: make([]byte, 56)}, err: ErrUnsupported},
+		{name: "RS256 without exponent", key: map[int]interface{}{1: ktyRSA, 3: AlgRS256, -1: rsaModulus}, err: ErrInvalidEncoding},
+		{name: "key type of another algorithm", key: map[int]interface{}{1: ktyOKP, 3: AlgES256, -1: crvP256, -2: x, -3: y}, err: ErrUnsupported},
+		{name: "unsupported algorithm", key: map[int]interface{}{1: ktyEC2, 3: -35, -1: 2, -2: x, -3: y}, err: ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseCOSEKey(mustCBOR(t, tt.key))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key.alg != tt.alg {
+				t.Errorf("algorithm = %d, want %d", key.alg, tt.alg)
+			}
+		})
+	}
+	if _, err := parseCOSEKey([]byte{0xa1}); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("truncated key: error = %v, want %v", err, ErrInvalidEncoding)
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	c := newCeremony(CeremonyGet, flagUserPresent)
+	challenge, err := Challenge(c.clientData(t))
+	if err != nil || challenge != testChallenge {
+		t.Errorf("challenge = %q, %v, want %q", challenge, err, testChallenge)
+	}
+	c.challenge = ""
+	if _, err := Challenge(c.clientData(t)); err != ErrNoChallengeFound {
+		t.Errorf("error = %v, want %v", err, ErrNoChallengeFound)
+	}
+	if _, err := Challenge([]byte("{")); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidEncoding)
+	}
+}
+
+func TestBytesJSON(t *testing.T) {
+	data, err := json.Marshal(Bytes{0xfb, 0xff})
+	if err != nil || string(data) != `"-_8"` {
+		t.Errorf("marshalled = %s, %v", data, err)
+	}
+	for _, s := range []string{`"-_8"`, `"-_8="`} {
+		var b Bytes
+		if err := json.Unmarshal([]byte(s), &b); err != nil || !bytes.Equal(b, []byte{0xfb, 0xff}) {
+			t.Errorf("unmarshalled %s = %x, %v", s, []byte(b), err)
+		}
+	}
+}